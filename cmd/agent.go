@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/internal/agent"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Runs an agent that keeps unwrapped keys in memory, so repeated commands don't prompt for passwords or call remote services again.",
+
+		"The agent listens on a unix socket only accessible to the current user, at `$XDG_RUNTIME_DIR/gcy-agent.sock` or `$TMPDIR/gcy-$UID/gcy-agent.sock` by default, and forgets keys after `--ttl`. Set `GCY_AGENT_SOCK` to use a different socket, or to an empty string to stop commands from using the agent.",
+
+		"Password, gpg and Azure Key Vault files share one data key, which is what the agent keeps. AWS KMS decrypts every value separately, so for `kms` files the agent keeps each decrypted value instead, looked up by its ciphertext. Any process running as the current user can ask the agent for the keys and values it holds, without a password or AWS credentials, until they expire; run `gcy agent lock` when you're done, or don't run the agent on shared machines.",
+
+		"`gcy agent lock` makes a running agent forget all of its keys.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "agent",
+		Usage:       "Cache unwrapped keys between commands",
+		Description: description,
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "ttl",
+				Value: agentDefaultTTL,
+				Usage: "How long to keep keys for",
+			},
+			&cli.StringFlag{
+				Name:    "socket",
+				Usage:   "The path to listen at",
+				EnvVars: []string{agent.SocketEnvVar},
+			},
+		},
+		Action: agentAction,
+		Subcommands: []*cli.Command{
+			{
+				Name:   "lock",
+				Usage:  "Forget all keys in a running agent",
+				Action: agentLock,
+			},
+		},
+	})
+}
+
+const agentDefaultTTL = 15 * time.Minute
+
+func agentAction(ctx *cli.Context) error {
+	path := ctx.String("socket")
+	if path == "" {
+		path = agent.SocketPath()
+	}
+
+	if path == "" {
+		return Exit(fmt.Sprintf("%s is empty, refusing to start", agent.SocketEnvVar), ExitCodeInputError)
+	}
+
+	listener, err := agent.Listen(path)
+	if err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	// so shells can eval our output, like ssh-agent's
+	fmt.Printf("%s=%s; export %s;\n", agent.SocketEnvVar, path, agent.SocketEnvVar)
+	log.Infof("Agent listening at %s, keeping keys for %s", path, ctx.Duration("ttl"))
+
+	_ = agent.New(ctx.Duration("ttl")).Serve(listener)
+	log.Info("Agent stopped")
+	return nil
+}
+
+func agentLock(ctx *cli.Context) error {
+	if err := agent.Lock(); err != nil {
+		return Exit(fmt.Sprintf("Could not lock agent: %s", err), ExitCodeToolError)
+	}
+
+	log.Info("Agent locked")
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Lists secrets in `CONFIG_FILE` that expired or expire soon, that were set too long ago, or that have no owner, according to the metadata stored by `gcy set`. `CONFIG_FILE` may also be a directory or glob, and many can be passed.",
+
+		"Each problem is output as `FILE: KEYPATH problem`, and `gcy audit` fails with exit code 1 if there are any, so it can run in CI. Secrets don't need to be decrypted, so no keys or passwords are needed. Secrets set before `gcy` recorded when are never considered stale.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "audit",
+		Usage:       "List secrets that are expiring, stale or unowned",
+		ArgsUsage:   "CONFIG_FILE...",
+		Description: description,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "expiring-within",
+				Value: 30,
+				Usage: "Report secrets that expire within this many days",
+			},
+			&cli.IntFlag{
+				Name:  "stale-after",
+				Value: 90,
+				Usage: "Report secrets set more than this many days ago, 0 to skip this check",
+			},
+			&cli.BoolFlag{
+				Name:  "ignore-unowned",
+				Usage: "Don't report secrets without an owner",
+			},
+		},
+		Action: audit,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Audit the metadata of secrets in config files
+func audit(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return showUsage(ctx, "Missing CONFIG_FILE")
+	}
+
+	paths := []string{}
+	for _, target := range ctx.Args().Slice() {
+		if !util.IsBulkTarget(target) {
+			paths = append(paths, target)
+			continue
+		}
+		expanded, err := util.ExpandTarget(target)
+		if err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+		paths = append(paths, expanded...)
+	}
+
+	now := time.Now()
+	expiringBy := now.AddDate(0, 0, ctx.Int("expiring-within"))
+	var staleBefore time.Time
+	if days := ctx.Int("stale-after"); days > 0 {
+		staleBefore = now.AddDate(0, 0, -days)
+	}
+
+	problems := 0
+	failed := 0
+	for _, path := range paths {
+		config, err := file.Load(path)
+		if err != nil {
+			log.Errorf("Could not load %s: %s", path, err)
+			failed++
+			continue
+		}
+
+		secrets := config.ListSecrets()
+		sort.Strings(secrets)
+		for _, keyPath := range secrets {
+			metadata, err := config.GetMetadata(keyPath)
+			if err != nil {
+				log.Errorf("Could not read %s in %s: %s", keyPath, path, err)
+				failed++
+				continue
+			}
+
+			found := []string{}
+			switch {
+			case metadata.Expires.IsZero():
+			case metadata.Expires.Before(now):
+				found = append(found, fmt.Sprintf("expired on %s", metadata.Expires.Format("2006-01-02")))
+			case metadata.Expires.Before(expiringBy):
+				found = append(found, fmt.Sprintf("expires in %d day(s), on %s", daysBetween(now, metadata.Expires), metadata.Expires.Format("2006-01-02")))
+			}
+			if !staleBefore.IsZero() && !metadata.Created.IsZero() && metadata.Created.Before(staleBefore) {
+				found = append(found, fmt.Sprintf("was set %d day(s) ago, on %s", daysBetween(metadata.Created, now), metadata.Created.Format("2006-01-02")))
+			}
+			if metadata.Owner == "" && !ctx.Bool("ignore-unowned") {
+				found = append(found, "has no owner")
+			}
+
+			for _, problem := range found {
+				fmt.Printf("%s: %s %s\n", path, keyPath, problem)
+			}
+			problems += len(found)
+		}
+	}
+
+	if failed > 0 {
+		return Exit(fmt.Sprintf("Could not audit %d secret(s) or file(s)", failed), ExitCodeInputError)
+	}
+	if problems > 0 {
+		return Exit(fmt.Sprintf("Found %d problem(s) with secrets", problems), ExitCodeFindings)
+	}
+
+	log.Infof("Audited %d file(s), no problems found", len(paths))
+	return nil
+}
+
+// daysBetween counts days from `start` to `end`, rounded to the nearest one
+func daysBetween(start time.Time, end time.Time) int {
+	return int(math.Round(end.Sub(start).Hours() / 24))
+}
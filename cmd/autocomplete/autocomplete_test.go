@@ -46,8 +46,8 @@ func TestListProviderFlags(t *testing.T) {
 		{"single-dash", []string{"-"}, allFlags},
 		{"complete-ver", []string{"--ver"}, []string{"--verbose", "--version"}},
 		{"expect-empty", []string{"--var"}, []string{}},
-		{"provider", []string{"--provider"}, []string{"gpg", "kms", "password"}},
-		{"provider-query", []string{"--provider", "g"}, []string{"gpg"}},
+		{"provider", []string{"--provider"}, []string{"gpg", "azurekv", "gcpkms", "vault", "password", "kms"}},
+		{"provider-query", []string{"--provider", "g"}, []string{"gpg", "gcpkms"}},
 		{"post-provider", []string{"--provider", "gpg", "-"}, []string{"--verbose", "--version"}},
 	}
 
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
 	"github.com/blinkhealth/go-config-yourself/pkg/file"
 
 	log "github.com/sirupsen/logrus"
@@ -12,12 +13,27 @@ var configFile *file.ConfigFile
 
 // sets the config file and keypath for commands
 func beforeCommand(ctx *cli.Context) (err error) {
+	return loadBeforeCommand(ctx)
+}
+
+// sets the config file and keypath for commands that write to the config file, locking it until the process exits
+func beforeWriteCommand(ctx *cli.Context) (err error) {
+	return loadBeforeCommand(ctx, file.WithLock())
+}
+
+func loadBeforeCommand(ctx *cli.Context, extraOptions ...file.Option) (err error) {
 	log.Debugf("running beforeCommand with %d args", ctx.NArg())
 	if ctx.NArg() < 2 {
 		return Exit("Missing arguments", ExitCodeInputError)
 	}
 
-	configFile, err = file.Load(ctx.Args().Get(0))
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	options = append(options, extraOptions...)
+
+	configFile, err = file.Load(ctx.Args().Get(0), options...)
 	if err != nil {
 		return Exit(err, ExitCodeInputError)
 	}
@@ -31,6 +47,20 @@ func beforeCommand(ctx *cli.Context) (err error) {
 	return nil
 }
 
+// sets the keypath for commands that accept a directory or glob, and the config file otherwise
+func beforeBulkCommand(ctx *cli.Context) (err error) {
+	if ctx.NArg() < 2 || !util.IsBulkTarget(ctx.Args().Get(0)) {
+		return beforeCommand(ctx)
+	}
+
+	configFile = nil
+	if err := ctx.Set("keypath", ctx.Args().Get(1)); err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	return nil
+}
+
 func showUsage(ctx *cli.Context, message string) error {
 	_ = cli.ShowCommandHelp(ctx, ctx.Command.Name)
 	return Exit(message, ExitCodeInputError)
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// bulkConcurrency caps how many files are processed at once when a command operates on a directory or glob
+const bulkConcurrency = 8
+
+// continueOnErrorFlag lets commands operating on many files keep the results for those that succeeded
+var continueOnErrorFlag = &cli.BoolFlag{
+	Name:  "continue-on-error",
+	Usage: "When CONFIG_FILE is a directory or glob, keep the results for files that succeed even if others fail",
+}
+
+// loadAll loads every config file named by `target`, one at a time
+//
+// Each file with secrets decrypts its first one, so prompts for passwords or gpg passphrases happen here, in order,
+// and not later while files are processed concurrently
+func loadAll(target string, options []file.Option) (configs map[string]*file.ConfigFile, paths []string, failures map[string]error, err error) {
+	paths, err = util.ExpandTarget(target)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	configs = map[string]*file.ConfigFile{}
+	failures = map[string]error{}
+	for _, path := range paths {
+		config, err := file.Load(path, options...)
+		if err == nil && config.HasCrypto() {
+			if secrets := config.ListSecrets(); len(secrets) > 0 {
+				_, err = config.Get(secrets[0])
+			}
+		}
+
+		if err != nil {
+			if config != nil {
+				config.Close()
+			}
+			log.Errorf("Could not load %s: %s", path, err)
+			failures[path] = err
+			continue
+		}
+		configs[path] = config
+	}
+
+	return configs, paths, failures, nil
+}
+
+// forEachFile runs `operation` on every one of `paths` concurrently, logging each outcome as it completes, and
+// returns the errors by path
+func forEachFile(paths []string, operation func(path string) error) map[string]error {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	failures := map[string]error{}
+	queue := make(chan string)
+	done := 0
+
+	for worker := 0; worker < bulkConcurrency && worker < len(paths); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range queue {
+				err := operation(path)
+
+				lock.Lock()
+				done++
+				if err != nil {
+					failures[path] = err
+					log.Errorf("[%d/%d] %s: %s", done, len(paths), path, err)
+				} else {
+					log.Infof("[%d/%d] %s", done, len(paths), path)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		queue <- path
+	}
+	close(queue)
+	wg.Wait()
+
+	return failures
+}
+
+// bulkSummary describes how an operation on `total` files went, listing the `failures` sorted by path
+func bulkSummary(verb string, total int, failures map[string]error) string {
+	if len(failures) == 0 {
+		return fmt.Sprintf("%s %d file(s)", verb, total)
+	}
+
+	failed := []string{}
+	for path, err := range failures {
+		failed = append(failed, fmt.Sprintf("%s: %s", path, err))
+	}
+	sort.Strings(failed)
+
+	return fmt.Sprintf("%s %d of %d file(s), %d failed: %s", verb, total-len(failures), total, len(failures), strings.Join(failed, "; "))
+}
@@ -8,6 +8,8 @@ type ExitCode int
 const (
 	// ExitCodeToolError means we did something silly
 	ExitCodeToolError ExitCode = 2
+	// ExitCodeFindings means a check found problems for the user to fix
+	ExitCodeFindings ExitCode = 1
 	// ExitCodeInputError means the user did something silly
 	ExitCodeInputError ExitCode = 99
 )
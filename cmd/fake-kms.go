@@ -0,0 +1,48 @@
+// +build test
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/blinkhealth/go-config-yourself/internal/fakekms"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Test builds ship a stand-in for AWS KMS, so integration tests can exercise the real AWS SDK
+func init() {
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:   "fake-kms",
+		Usage:  "Serve a stand-in for AWS KMS, for tests",
+		Hidden: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "listen",
+				Value: "127.0.0.1:0",
+				Usage: "The address to listen at",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			listener, err := net.Listen("tcp", ctx.String("listen"))
+			if err != nil {
+				return Exit(err, ExitCodeToolError)
+			}
+
+			server := &http.Server{Handler: fakekms.Handler()}
+			go func() {
+				// the context is canceled on SIGINT and SIGTERM
+				<-ctx.Context.Done()
+				server.Close()
+			}()
+
+			// tests read the endpoint from the first line of output
+			fmt.Printf("http://%s\n", listener.Addr())
+			if err = server.Serve(listener); err != http.ErrServerClosed {
+				return Exit(err, ExitCodeToolError)
+			}
+			return nil
+		},
+	})
+}
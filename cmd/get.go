@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sync"
 
 	"github.com/blinkhealth/go-config-yourself/cmd/autocomplete"
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
 
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
@@ -19,23 +21,26 @@ func init() {
 		"`KEYPATH` refers to a dot-delimited path to values, see `gcy help keypath` for examples.",
 
 		"If the value at `KEYPATH` is a dictionary or a list, it will be encoded as JSON, with all of the encrypted values within decrypted. If no value `KEYPATH` exists, `gcy get` will fail with exit code 2.",
+
+		"`CONFIG_FILE` may also be a directory, searched recursively for `.yml` and `.yaml` files, or a glob like `config/**/*.yml`. Files are decrypted concurrently and the output is a JSON object mapping each file's path to its value at `KEYPATH`. Nothing is output unless every file has a value, pass `--continue-on-error` to output the ones that do.",
 	)
 
 	App.Commands = append(App.Commands, &cli.Command{
 		Name:        "get",
-		Before:      beforeCommand,
+		Before:      beforeBulkCommand,
 		Aliases:     []string{"show"},
 		Usage:       "Output a value from a file",
 		ArgsUsage:   "CONFIG_FILE KEYPATH",
 		Description: description,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:   "keypath",
 				Value:  "",
 				Usage:  "Used internally by the app",
 				Hidden: true,
 			},
-		},
+			continueOnErrorFlag,
+		}, util.LoaderFlags()...),
 		Action: get,
 		BashComplete: func(ctx *cli.Context) {
 			if ctx.NArg() == 0 {
@@ -51,6 +56,10 @@ func init() {
 
 // Get a value from a config file
 func get(ctx *cli.Context) error {
+	if configFile == nil {
+		return getAll(ctx, ctx.Args().Get(0))
+	}
+
 	value, err := configFile.Get(ctx.String("keypath"))
 
 	if err != nil {
@@ -73,3 +82,57 @@ func get(ctx *cli.Context) error {
 
 	return nil
 }
+
+// Get a value from every config file in a directory or glob
+func getAll(ctx *cli.Context, target string) error {
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	configs, paths, failures, err := loadAll(target, options)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	loaded := []string{}
+	for _, path := range paths {
+		if _, ok := configs[path]; ok {
+			loaded = append(loaded, path)
+		}
+	}
+
+	var lock sync.Mutex
+	values := map[string]interface{}{}
+	keyPath := ctx.String("keypath")
+	for path, err := range forEachFile(loaded, func(path string) error {
+		value, err := configs[path].Get(keyPath)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		values[path] = value
+		lock.Unlock()
+		return nil
+	}) {
+		failures[path] = err
+	}
+
+	message := bulkSummary("Read", len(paths), failures)
+	if len(failures) > 0 && !ctx.Bool("continue-on-error") {
+		return Exit(message, ExitCodeInputError)
+	}
+
+	jsonBytes, err := json.Marshal(values)
+	if err != nil {
+		return Exit(fmt.Sprintf("Could not encode as json: %s", err), ExitCodeToolError)
+	}
+	fmt.Println(string(jsonBytes))
+
+	if len(failures) > 0 {
+		return Exit(message, ExitCodeInputError)
+	}
+	log.Info(message)
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// the name of the diff and merge drivers in git's config and .gitattributes
+const gitDriverName = "gcy"
+
+func init() {
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:      "git-textconv",
+		Usage:     "Output a file with its secrets decrypted, for git diff",
+		ArgsUsage: "CONFIG_FILE",
+		Description: multiLineDescription(
+			"Outputs `CONFIG_FILE` with the ciphertext of every secret replaced by its plaintext, followed by an `# encrypted` comment, so `git diff` shows what changed instead of base64 churn.",
+
+			"If any secret can't be decrypted, or `--hashes` is passed, secrets show the hash of their plaintext instead. Files that can't be loaded are output as they are. `gcy git-setup` configures git to use this command.",
+		),
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "hashes",
+				Usage: "Show the hash of each secret's plaintext instead of decrypting it",
+			},
+		}, util.LoaderFlags()...),
+		Action: gitTextconv,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:      "git-merge",
+		Usage:     "Merge changes to a file by their decrypted values, for git merge",
+		ArgsUsage: "BASE OURS THEIRS",
+		Description: multiLineDescription(
+			"Does a three-way merge of the decrypted values in `OURS` and `THEIRS`, changed from their common ancestor `BASE`, and writes the result to `OURS`, re-encrypted. Git calls it as `gcy git-merge %O %A %B`.",
+
+			"The result is encrypted with our crypto settings, or theirs if only they changed them. Values changed to something different on both sides are conflicts: our values are kept, they are listed, and `gcy git-merge` fails with exit code 2 so git marks the file as conflicted. `gcy git-setup` configures git to use this command.",
+		),
+		Flags:  util.LoaderFlags(),
+		Action: gitMerge,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:      "git-setup",
+		Usage:     "Configure git to diff and merge config files with gcy",
+		ArgsUsage: "[PATTERN...]",
+		Description: multiLineDescription(
+			"Adds `PATTERN diff=gcy merge=gcy` entries to the `.gitattributes` at the root of the current git repository, and configures git to diff them with `gcy git-textconv` and merge them with `gcy git-merge`.",
+
+			"Without any `PATTERN`, the paths of the creation rules in the `.gcy.yaml` at the root of the repository are used. Pass `--global` to configure the drivers for every repository of the current user, entries in `.gitattributes` are always added to the current repository.",
+		),
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "global",
+				Usage: "Configure the diff and merge drivers in the global git config, instead of the repository's",
+			},
+		},
+		Action: gitSetup,
+	})
+}
+
+// Output a decrypted view of a config file
+func gitTextconv(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		return showUsage(ctx, "Missing arguments")
+	}
+	target := ctx.Args().Get(0)
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	var revealed []byte
+	config, err := file.Load(target, options...)
+	if err == nil {
+		revealed, err = config.Reveal(!ctx.Bool("hashes"))
+	}
+	if err != nil {
+		// diffs should still work for files gcy can't read
+		log.Warnf("Could not load %s, showing it as is: %s", target, err)
+		if revealed, err = ioutil.ReadFile(target); err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+	}
+
+	fmt.Print(string(revealed))
+	return nil
+}
+
+// Merge a config file by its decrypted values
+func gitMerge(ctx *cli.Context) error {
+	if ctx.NArg() != 3 {
+		return showUsage(ctx, "Expected BASE, OURS and THEIRS")
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	versions := []*file.ConfigFile{}
+	for _, path := range ctx.Args().Slice() {
+		config, err := file.Load(path, options...)
+		if err != nil {
+			return Exit(fmt.Sprintf("Could not load %s: %s", path, err), ExitCodeToolError)
+		}
+		versions = append(versions, config)
+	}
+
+	merged, conflicts, err := file.Merge(versions[0], versions[1], versions[2])
+	if err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	if err := util.SerializeAndWrite(ctx.Args().Get(1), merged); err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	if len(conflicts) > 0 {
+		message := fmt.Sprintf("Conflicting changes at %s, kept ours. Set the right values with gcy set or gcy rekey, then git add the file", strings.Join(conflicts, ", "))
+		return Exit(message, ExitCodeToolError)
+	}
+
+	log.Info("Merged decrypted values without conflicts")
+	return nil
+}
+
+// Configure git's diff and merge drivers
+func gitSetup(ctx *cli.Context) error {
+	revParse := exec.Command("git", "rev-parse", "--show-toplevel")
+	revParse.Env = input.SanitizedEnv()
+	output, err := revParse.Output()
+	if err != nil {
+		return Exit("Could not find a git repository, run gcy git-setup inside one", ExitCodeInputError)
+	}
+	root := strings.TrimSpace(string(output))
+
+	patterns := ctx.Args().Slice()
+	if len(patterns) == 0 {
+		source := filepath.Join(root, util.ProjectConfigName)
+		if _, err := os.Stat(source); err != nil {
+			return showUsage(ctx, fmt.Sprintf("Missing patterns, and no %s at the root of the repository to take them from", util.ProjectConfigName))
+		}
+		if patterns, err = util.CreationRulePaths(source); err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+	}
+
+	settings := [][]string{
+		{fmt.Sprintf("diff.%s.textconv", gitDriverName), "gcy git-textconv"},
+		{fmt.Sprintf("merge.%s.name", gitDriverName), "gcy decrypted merge"},
+		{fmt.Sprintf("merge.%s.driver", gitDriverName), "gcy git-merge %O %A %B"},
+	}
+	for _, setting := range settings {
+		args := []string{"config"}
+		if ctx.Bool("global") {
+			args = append(args, "--global")
+		}
+		command := exec.Command("git", append(args, setting...)...)
+		command.Dir = root
+		command.Env = input.SanitizedEnv()
+		if output, err := command.CombinedOutput(); err != nil {
+			return Exit(fmt.Sprintf("Could not set %s: %s", setting[0], strings.TrimSpace(string(output))), ExitCodeToolError)
+		}
+		log.Debugf("Set %s to %s", setting[0], setting[1])
+	}
+
+	attributesPath := filepath.Join(root, ".gitattributes")
+	attributes, err := ioutil.ReadFile(attributesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	existing := map[string]bool{}
+	for _, line := range strings.Split(string(attributes), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+
+	contents := string(attributes)
+	for _, pattern := range patterns {
+		entry := fmt.Sprintf("%s diff=%s merge=%s", pattern, gitDriverName, gitDriverName)
+		if existing[entry] {
+			continue
+		}
+		if contents != "" && !strings.HasSuffix(contents, "\n") {
+			contents += "\n"
+		}
+		contents += entry + "\n"
+	}
+
+	if err := ioutil.WriteFile(attributesPath, []byte(contents), 0644); err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	log.Infof("Configured git to diff and merge %s with gcy", strings.Join(patterns, ", "))
+	return nil
+}
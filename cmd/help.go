@@ -108,6 +108,18 @@ secretColor:
 
 `
 
+var exampleProjectConfig = `creation_rules:
+  - path: config/production.yml
+    provider: kms
+    key: arn:aws:kms:us-east-1:an-account:alias/production
+    kms-context:
+      - env=production
+  - path: config/**/*.yml
+    provider: kms
+    key: arn:aws:kms:us-east-1:an-account:alias/development
+
+`
+
 func multiLineDescription(lines ...string) string {
 	return strings.Join(lines, "\n\n")
 }
@@ -153,7 +165,11 @@ func init() {
 			    | - production.yml
 
 ` +
-			"In the above scenario, you may store defaults or placeholders in `defaults.yml` with no encryption, while storing only the necessary secrets to override these placeholders in separate files. `staging.yml` and `production.yml` will only contain overrides to be applied on top of `defaults.yml`. `gcy set` automatically adds placeholder values to `defaults.yml` after storing secrets in environment-specific files.",
+			"In the above scenario, you may store defaults or placeholders in `defaults.yml` with no encryption, while storing only the necessary secrets to override these placeholders in separate files. `staging.yml` and `production.yml` will only contain overrides to be applied on top of `defaults.yml`. `gcy set` automatically adds placeholder values to `defaults.yml` after storing secrets in environment-specific files.\n\n" +
+			"To have `gcy init` pick the right provider and key for each environment, add creation rules to a `.gcy.yaml` file in your repository. `gcy` uses the closest `.gcy.yaml` above `CONFIG_FILE`, and the first rule whose `path` glob matches `CONFIG_FILE` relative to it. `**` matches any number of directories, and every other property of a rule is an argument for its provider, named like its flag:\n\n" +
+			exampleProjectConfig +
+			"Flags passed to `gcy init` and `gcy rekey` take precedence over creation rules. Rules can't set how passwords are read, so `password`, `password-file`, `password-fd` and `password-command` must be passed on the command line. `gcy rekey --apply-rules` re-encrypts files that have drifted from their rule with its provider and arguments.\n\n" +
+			"A rule's `schema` is the path to a JSON Schema, relative to `.gcy.yaml`, that matching files follow. Files may also point to their own with a `$schema` property, relative to them. See `gcy help validate`.",
 	}
 	App.Commands = append(App.Commands, keypathHelp, configfileHelp)
 }
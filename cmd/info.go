@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Outputs the crypto provider settings and secrets in `CONFIG_FILE`.",
+
+		"If `CONFIG_FILE` has secrets, `gcy info` decrypts the first one to check the provider can still do so, and shows where it was decrypted, like the region that served a `kms` request. It fails with exit code 2 if decryption fails.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "info",
+		Usage:       "Describe a file's crypto settings and secrets",
+		ArgsUsage:   "CONFIG_FILE",
+		Description: description,
+		Flags:       util.LoaderFlags(),
+		Action:      info,
+		BashComplete: func(ctx *cli.Context) {
+			if ctx.NArg() < 1 {
+				// offer file searching
+				os.Exit(1)
+			}
+		},
+	})
+}
+
+// Describe a config file
+func info(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		return showUsage(ctx, "Missing arguments")
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	config, err := file.Load(ctx.Args().Get(0), options...)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	details, err := config.Info()
+
+	if details.Provider == "" {
+		fmt.Println("provider: none")
+	} else {
+		fmt.Printf("provider: %s\n", details.Provider)
+	}
+
+	settings := map[string]string{}
+	for name, value := range details.Crypto {
+		if name == "provider" {
+			continue
+		}
+		if text, isString := value.(string); isString {
+			settings[name] = text
+		} else {
+			encoded, _ := json.Marshal(value)
+			settings[name] = string(encoded)
+		}
+	}
+	printSection("crypto", settings)
+
+	fmt.Printf("secrets: %d\n", len(details.Secrets))
+	for _, keyPath := range details.Secrets {
+		fmt.Printf("  - %s\n", keyPath)
+	}
+
+	printSection("served by", details.Served)
+
+	if err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+	return nil
+}
+
+// printSection prints `values` sorted by name under `title`, unless there's none
+func printSection(title string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%s:\n", title)
+	for _, name := range names {
+		fmt.Printf("  %s: %s\n", name, values[name])
+	}
+}
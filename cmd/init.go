@@ -18,7 +18,9 @@ func init() {
 
 		"If needed, `gcy init` will query your provider for a list of keys to choose from when using the `aws` or `gpg` providers, and a password will be prompted for when using the `password` provider. `gcy init` will select the `aws` provider by default, and you can override it with the `--provider` flag.",
 
-		"See `gcy help config-file` for more information about `CONFIG_FILE`.",
+		"The provider and arguments missing from the command line are taken from the creation rule matching `CONFIG_FILE` in the closest `.gcy.yaml`, if any.",
+
+		"See `gcy help config-file` for more information about `CONFIG_FILE` and creation rules.",
 	)
 
 	App.Commands = append(App.Commands, &cli.Command{
@@ -57,11 +59,16 @@ func initAction(ctx *cli.Context) error {
 	}
 
 	log.Infof("Creating config at %s", target)
+	args, err := util.GetKeyArguments(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
 	if !ctx.IsSet("provider") {
 		_ = ctx.Set("provider", "kms")
 	}
 
-	configData, err := file.Create(ctx.String("provider"), util.GetKeyArguments(ctx))
+	configData, err := file.Create(ctx.String("provider"), args)
 	if err != nil {
 		return Exit(err, ExitCodeToolError)
 	}
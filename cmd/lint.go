@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/lint"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Compares the keys in every config file in `DIR` with those in its `defaults` or `default` file, and reports for each environment keys that are:",
+
+		"  - missing, when they have a nil placeholder in defaults, like the ones `gcy set` adds\n"+
+			"  - extra, when they're not in defaults at all\n"+
+			"  - typed differently, like a string overriding a number in defaults\n"+
+			"  - plaintext, when they're encrypted in another environment",
+
+		"Keys with a value in defaults don't need to be overridden. Secrets are not decrypted, so no keys or passwords are needed, and schemas in `DIR` are skipped. Each problem is output as `FILE: KEYPATH problem`, and `gcy lint` fails with exit code 1 if there are any.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "lint",
+		Usage:       "Check config files in a directory define the same keys as their defaults",
+		ArgsUsage:   "DIR",
+		Description: description,
+		Action:      lintAction,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Compare environments' config files with their defaults
+func lintAction(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return showUsage(ctx, "Missing DIR")
+	}
+	dir := ctx.Args().Get(0)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return Exit(fmt.Sprintf("Could not list config files in %s: %s", dir, err), ExitCodeInputError)
+	}
+
+	defaultsPath := ""
+	paths := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		extension := filepath.Ext(name)
+		if entry.IsDir() || strings.HasPrefix(name, ".") || (extension != ".yml" && extension != ".yaml") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, extension)
+		if defaultsPath == "" && (base == "defaults" || base == "default") {
+			defaultsPath = filepath.Join(dir, name)
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+
+	if defaultsPath == "" {
+		return Exit(fmt.Sprintf("Could not find a defaults.yml or default.yml file in %s", dir), ExitCodeInputError)
+	}
+
+	configs := map[string]*file.ConfigFile{}
+	schemas := map[string]bool{}
+	failed := 0
+	for _, path := range append([]string{defaultsPath}, paths...) {
+		config, err := file.Load(path)
+		if err != nil {
+			log.Errorf("Could not load %s: %s", path, err)
+			failed++
+			continue
+		}
+		configs[path] = config
+
+		if schema, err := util.SchemaPath(path, config); err == nil && schema != "" {
+			schemas[absolutePath(schema)] = true
+		}
+	}
+	if failed > 0 {
+		return Exit(fmt.Sprintf("Could not load %d file(s)", failed), ExitCodeInputError)
+	}
+
+	defaults := configs[defaultsPath].ListTypes()
+	delete(defaults, util.SchemaKey)
+	environments := map[string]map[string]string{}
+	for _, path := range paths {
+		if schemas[absolutePath(path)] {
+			log.Debugf("Skipping schema %s", path)
+			continue
+		}
+		types := configs[path].ListTypes()
+		delete(types, util.SchemaKey)
+		environments[path] = types
+	}
+
+	problems := lint.Check(filepath.Base(defaultsPath), defaults, environments)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		return Exit(fmt.Sprintf("Found %d problem(s) in %d environment(s)", len(problems), len(environments)), ExitCodeFindings)
+	}
+
+	log.Infof("Checked %d environment(s) against %s, no problems found", len(environments), defaultsPath)
+	return nil
+}
+
+func absolutePath(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return filepath.Clean(path)
+}
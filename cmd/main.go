@@ -11,9 +11,12 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/blinkhealth/go-config-yourself/cmd/autocomplete"
 	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/plugin"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 )
@@ -35,8 +38,14 @@ var App = &cli.App{
 			Aliases: []string{"v"},
 			Usage:   "Print debug statements",
 		},
+		&cli.BoolFlag{
+			Name:    "backup",
+			EnvVars: []string{"GCY_BACKUP"},
+			Usage:   "Keep the previous contents of files gcy overwrites next to them, with a .bak suffix",
+		},
 	},
 	Before: func(ctx *cli.Context) error {
+		util.KeepBackups = ctx.Bool("backup")
 		if ctx.Bool("verbose") {
 			log.SetLevel(log.DebugLevel)
 			if ctx.IsSet("generate-bash-completion") {
@@ -60,6 +69,54 @@ var App = &cli.App{
 // KeyFlags point to a list of cli flags for key-related operations
 var KeyFlags = util.KeyFlags()
 
+// commandsWithPlugins offer the flags of every plugin in PATH, so every plugin is started to describe them first
+var commandsWithPlugins = map[string]bool{"init": true, "rekey": true, "help": true, "h": true}
+
+// needsPlugins tells whether the command in `args` offers plugin flags, or is completing them
+func needsPlugins(args []string) bool {
+	for _, arg := range args {
+		if arg == "--generate-bash-completion" {
+			return true
+		}
+	}
+
+	// global flags are all switches, so the first other argument is the command
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return commandsWithPlugins[arg]
+		}
+	}
+	return false
+}
+
+// addPluginFlags registers every plugin in PATH, and replaces KeyFlags with flags including theirs
+func addPluginFlags() {
+	known := len(pvd.ProviderList)
+	plugin.DiscoverPATH()
+	if len(pvd.ProviderList) == known {
+		return
+	}
+
+	keyFlags := map[cli.Flag]bool{}
+	for _, flag := range KeyFlags {
+		keyFlags[flag] = true
+	}
+	KeyFlags = util.KeyFlags()
+
+	for _, command := range App.Commands {
+		if !commandsWithPlugins[command.Name] {
+			continue
+		}
+		flags := []cli.Flag{}
+		for _, flag := range command.Flags {
+			if !keyFlags[flag] {
+				flags = append(flags, flag)
+			}
+		}
+		command.Flags = append(flags, KeyFlags...)
+	}
+}
+
 // Main main function for go-config-yourself
 func Main(version string) {
 	log.SetFormatter(&log.TextFormatter{
@@ -76,6 +133,10 @@ func Main(version string) {
 		Usage:   "print the version",
 	}
 
+	if needsPlugins(os.Args[1:]) {
+		addPluginFlags()
+	}
+
 	cli.HelpPrinter = helpPrinter
 	cli.AppHelpTemplate = helpTemplateApp
 	cli.CommandHelpTemplate = helpTemplateCmd
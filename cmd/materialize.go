@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/materialize"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Writes every value in `CONFIG_FILE`, decrypted, as its own file in `DIR`, like mounted secrets in Docker or Kubernetes. Keypath segments become directories, so `db.password` is written to `DIR/db/password`, and `--keypath` writes only the values below it.",
+
+		"Files hold the exact value, without a trailing newline, and are only readable by their owner. Lists are written as JSON. Values are written to a new directory in `DIR` and swapped in all at once, through the `DIR/..data` symlink, and files for keys that were deleted are removed. `DIR` must be empty the first time.",
+
+		"With `--watch`, `gcy materialize` keeps running and writes the files again whenever `CONFIG_FILE` changes. If it can't be loaded, the previous files are kept.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "materialize",
+		Usage:       "Write the values in CONFIG_FILE as files in DIR",
+		ArgsUsage:   "CONFIG_FILE DIR",
+		Description: description,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "keypath",
+				Usage: "Only write the values below this keypath",
+			},
+			&cli.BoolFlag{
+				Name:  "watch",
+				Usage: "Keep running, and write the files again when CONFIG_FILE changes",
+			},
+		}, util.LoaderFlags()...),
+		Action: materializeAction,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Write the values of a config file as files
+func materializeAction(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return showUsage(ctx, "Missing CONFIG_FILE or DIR")
+	}
+	target, dir := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	keyPath := ctx.String("keypath")
+	if keyPath == "crypto" || strings.HasPrefix(keyPath, "crypto.") {
+		return Exit("Refusing to write the crypto property", ExitCodeInputError)
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	if !ctx.Bool("watch") {
+		config, err := file.Load(target, options...)
+		if err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+		if err = materializeFile(config, dir, keyPath); err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+		log.Infof("Wrote the values of %s to %s", target, dir)
+		return nil
+	}
+
+	watcher, err := file.Watch(target, options...)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	defer watcher.Close()
+
+	if err = materializeFile(watcher.Current(), dir, keyPath); err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	log.Infof("Wrote the values of %s to %s, watching for changes", target, dir)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	for {
+		select {
+		case <-signals:
+			log.Info("Stopped watching")
+			return nil
+		case err := <-watcher.Errors():
+			log.Errorf("%s, keeping the previous files", err)
+		case config := <-watcher.Updates():
+			if err := materializeFile(config, dir, keyPath); err != nil {
+				log.Errorf("Could not write %s again, keeping the previous files: %s", target, err)
+				continue
+			}
+			log.Infof("Wrote the values of %s to %s again", target, dir)
+		}
+	}
+}
+
+// materializeFile writes the values of `config` below `keyPath` into `dir`
+func materializeFile(config *file.ConfigFile, dir string, keyPath string) (err error) {
+	var value interface{}
+	name := ""
+	if keyPath == "" {
+		var tree map[string]interface{}
+		if tree, err = config.GetAll(); err != nil {
+			return err
+		}
+		delete(tree, "crypto")
+		value = tree
+	} else {
+		if value, err = config.Get(keyPath); err != nil {
+			return err
+		}
+		// a single value is written to a file named like its key
+		segments := strings.Split(keyPath, ".")
+		if _, isMap := value.(map[string]interface{}); !isMap {
+			name = segments[len(segments)-1]
+		}
+	}
+
+	files, err := materialize.Files(value, name)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("There are no values to write")
+	}
+	return materialize.Write(dir, files)
+}
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"sync"
 
 	"github.com/blinkhealth/go-config-yourself/cmd/autocomplete"
 	"github.com/blinkhealth/go-config-yourself/cmd/util"
 	"github.com/blinkhealth/go-config-yourself/pkg/file"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
 
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
@@ -16,6 +19,10 @@ func init() {
 		"Re-encrypts all the secret values with specified arguments in `CONFIG_FILE`.",
 
 		"By default, it will reuse the same provider for this operation, unless `--provider` is passed. If needed, `gcy rekey` will query your provider for a list of keys to choose from when using the `aws` or `gpg` providers, and a password will be prompted for when using the `password` provider.",
+
+		"Arguments missing from the command line are taken from the creation rule matching `CONFIG_FILE` in the closest `.gcy.yaml`, when it uses the same provider. Pass `--apply-rules` to switch to the rule's provider too, for files that have drifted from it. See `gcy help config-file` for more information about creation rules.",
+
+		"`CONFIG_FILE` may also be a directory, searched recursively for `.yml` and `.yaml` files, or a glob like `config/**/*.yml`. Keys and passwords are asked for once per provider and creation rule, files are re-keyed concurrently, and none are written unless all of them succeed. Pass `--continue-on-error` to write the ones that did.",
 	)
 
 	App.Commands = append(App.Commands, &cli.Command{
@@ -24,7 +31,13 @@ func init() {
 		Description: description,
 		ArgsUsage:   "CONFIG_FILE",
 		Action:      rekey,
-		Flags:       KeyFlags,
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "apply-rules",
+				Usage: "Re-key with the provider and arguments from the creation rule matching CONFIG_FILE, even if the file uses a different provider",
+			},
+			continueOnErrorFlag,
+		}, KeyFlags...),
 		BashComplete: func(ctx *cli.Context) {
 			if ctx.NArg() == 0 {
 				if !autocomplete.ListProviderFlags(ctx) {
@@ -43,10 +56,22 @@ func init() {
 // Rekey a config file
 func rekey(ctx *cli.Context) (err error) {
 	fileName := ctx.Args().Get(0)
-	originalConfig, err := file.Load(fileName)
+	options, err := util.LoaderOptions(ctx)
 	if err != nil {
 		return Exit(err, ExitCodeInputError)
 	}
+	// keep others from changing files between reading and re-keying them
+	options = append(options, file.WithLock())
+
+	if util.IsBulkTarget(fileName) {
+		return rekeyAll(ctx, fileName, options)
+	}
+
+	originalConfig, err := file.Load(fileName, options...)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	defer originalConfig.Close()
 
 	if originalConfig.Provider == "" {
 		// Help non-migrated old configs
@@ -54,12 +79,28 @@ func rekey(ctx *cli.Context) (err error) {
 		originalConfig.Provider = "kms"
 	}
 
-	if !ctx.IsSet("provider") {
+	applyRules := ctx.Bool("apply-rules")
+	if applyRules && !ctx.IsSet("provider") {
+		rule, err := util.FindCreationRule(fileName)
+		if err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+		if rule == nil {
+			return Exit(fmt.Sprintf("No creation rule matches %s", fileName), ExitCodeInputError)
+		}
+	}
+
+	if !ctx.IsSet("provider") && !applyRules {
 		log.Warnf("Re-encrypting with same crypto.provider: %s", originalConfig.Provider)
 		_ = ctx.Set("provider", originalConfig.Provider)
 	}
 
-	args := util.GetKeyArguments(ctx)
+	args, err := util.GetKeyArguments(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	delete(args, "apply-rules")
+	delete(args, "continue-on-error")
 	newProvider := ctx.String("provider")
 
 	newConfig, err := originalConfig.Rekey(newProvider, args)
@@ -74,3 +115,146 @@ func rekey(ctx *cli.Context) (err error) {
 
 	return nil
 }
+
+// a set of files re-keyed with the same provider and creation rule, which share their arguments
+type rekeyGroup struct {
+	provider string
+	rule     *util.CreationRule
+	paths    []string
+	args     map[string]interface{}
+}
+
+// Rekey every config file in a directory or glob
+func rekeyAll(ctx *cli.Context, target string, options []file.Option) error {
+	configs, paths, failures, err := loadAll(target, options)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	for _, config := range configs {
+		defer config.Close()
+	}
+
+	applyRules := ctx.Bool("apply-rules")
+	groups := map[string]*rekeyGroup{}
+	names := []string{}
+	total := len(failures)
+	for _, path := range paths {
+		config, loaded := configs[path]
+		if !loaded {
+			continue
+		}
+		if !config.HasCrypto() {
+			log.Infof("Skipping %s, it has no crypto config", path)
+			continue
+		}
+		total++
+
+		rule, err := util.FindCreationRule(path)
+		if err != nil {
+			failures[path] = err
+			continue
+		}
+
+		provider := ctx.String("provider")
+		switch {
+		case ctx.IsSet("provider"):
+		case applyRules && rule == nil:
+			failures[path] = fmt.Errorf("No creation rule matches %s", path)
+			continue
+		case applyRules:
+			provider = rule.Provider
+		case config.Provider == "":
+			log.Warnf("Unspecified crypto.provider for %s, defaulting to kms", path)
+			provider = "kms"
+		default:
+			provider = config.Provider
+		}
+
+		name := provider
+		if rule != nil {
+			name = fmt.Sprintf("%s <%s> in %s", provider, rule.Path, rule.Source)
+		}
+		if _, exists := groups[name]; !exists {
+			groups[name] = &rekeyGroup{provider: provider, rule: rule}
+			names = append(names, name)
+		}
+		groups[name].paths = append(groups[name].paths, path)
+	}
+
+	flagArgs, err := util.GetKeyArguments(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	// prompt for every group's arguments before re-keying any file
+	groupOf := map[string]*rekeyGroup{}
+	pending := []string{}
+	for _, name := range names {
+		group := groups[name]
+		// files may use different providers, only pass each one its own flags
+		args := map[string]interface{}{}
+		for _, flag := range pvd.Providers[group.provider].Flags {
+			if value, set := flagArgs[flag.Name]; set {
+				args[flag.Name] = value
+			}
+		}
+		if group.rule != nil && group.rule.Provider == group.provider {
+			for arg, value := range group.rule.Args {
+				if _, set := args[arg]; !set {
+					args[arg] = value
+				}
+			}
+		}
+
+		log.Infof("Re-keying %d file(s) with %s", len(group.paths), name)
+		group.args, err = file.CollectArguments(group.provider, args)
+		if err != nil {
+			for _, path := range group.paths {
+				failures[path] = err
+			}
+			continue
+		}
+
+		for _, path := range group.paths {
+			groupOf[path] = group
+			pending = append(pending, path)
+		}
+	}
+
+	var lock sync.Mutex
+	rekeyed := map[string]*file.ConfigFile{}
+	for path, err := range forEachFile(pending, func(path string) error {
+		group := groupOf[path]
+		newConfig, err := configs[path].Rekey(group.provider, group.args)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		rekeyed[path] = newConfig
+		lock.Unlock()
+		return nil
+	}) {
+		failures[path] = err
+	}
+
+	if len(failures) > 0 && !ctx.Bool("continue-on-error") {
+		message := bulkSummary("Re-keyed", total, failures)
+		return Exit(message+"; no files were written, pass --continue-on-error to write the ones that succeeded", ExitCodeToolError)
+	}
+
+	for _, path := range pending {
+		if newConfig, succeeded := rekeyed[path]; succeeded {
+			if err := util.SerializeAndWrite(path, newConfig); err != nil {
+				failures[path] = err
+			}
+		}
+	}
+
+	message := bulkSummary("Re-keyed", total, failures)
+	if len(failures) > 0 {
+		return Exit(message, ExitCodeToolError)
+	}
+	log.Info(message)
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/render"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Evaluates the Go text/template at `TEMPLATE` with the values of `CONFIG_FILE` as data, and prints the result, or writes it to `--output`. Only the secrets the template uses are decrypted, and nothing is written unless the whole template renders.",
+
+		"Values are referenced by keypath, like `{{ .db.password }}`, and referencing a key missing from `CONFIG_FILE` is an error. Templates may also call `secret \"keypath\"` to get the decrypted value at a keypath, `b64enc` and `toJSON` to encode values, and `required \"message\" value` to fail with a message when a value is nil or empty. Secrets are only decrypted when printed or passed to a function, so `if`, `eq` and other comparisons need them through `secret` or `required`.",
+
+		"Files written with `--output` are only readable and writable by their owner, unless `--mode` is passed.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "render",
+		Usage:       "Render a template with values from CONFIG_FILE",
+		ArgsUsage:   "CONFIG_FILE TEMPLATE",
+		Description: description,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Write the result to this file instead of printing it",
+			},
+			&cli.StringFlag{
+				Name:  "mode",
+				Value: "0600",
+				Usage: "Set the permissions of the file written with --output, in octal",
+			},
+		}, util.LoaderFlags()...),
+		Action: renderAction,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Render a template with the values of a config file
+func renderAction(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return showUsage(ctx, "Missing CONFIG_FILE or TEMPLATE")
+	}
+	target, templatePath := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	mode, err := strconv.ParseUint(ctx.String("mode"), 8, 32)
+	if err != nil {
+		return Exit(fmt.Sprintf("Invalid --mode %s, use an octal number like 0600", ctx.String("mode")), ExitCodeInputError)
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	config, err := file.Load(target, options...)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	source, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return Exit(fmt.Sprintf("Could not read template %s: %s", templatePath, err), ExitCodeInputError)
+	}
+
+	var rendered bytes.Buffer
+	if err = render.Render(templatePath, string(source), config, &rendered); err != nil {
+		return Exit(fmt.Sprintf("Could not render %s: %s", templatePath, err), ExitCodeInputError)
+	}
+
+	output := ctx.String("output")
+	if output == "" {
+		_, err = rendered.WriteTo(os.Stdout)
+		return err
+	}
+
+	if err = writeRendered(output, rendered.Bytes(), os.FileMode(mode)); err != nil {
+		return Exit(fmt.Sprintf("Could not write %s: %s", output, err), ExitCodeToolError)
+	}
+	log.Infof("Rendered %s to %s", templatePath, output)
+	return nil
+}
+
+// writeRendered writes `contents` to `path` with `mode`, restricting permissions before anything is written
+func writeRendered(path string, contents []byte, mode os.FileMode) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if err = out.Chmod(mode); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err = out.Write(contents); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
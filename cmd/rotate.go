@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/autocomplete"
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/generate"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// generateFlags choose how values are generated by `gcy set --generate` and `gcy rotate`
+func generateFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "length",
+			Usage: fmt.Sprintf("Generate values with this many characters, %d by default, or words, %d by default", generate.DefaultLength, generate.DefaultWords),
+		},
+		&cli.StringFlag{
+			Name:  "charset",
+			Value: generate.DefaultCharset,
+			Usage: fmt.Sprintf("Generate values with characters from this set, one of %s", strings.Join(generate.Charsets, ", ")),
+		},
+		&cli.BoolFlag{
+			Name:  "show",
+			Usage: "Print generated values, which are otherwise never shown",
+		},
+	}
+}
+
+// generateValue creates a random value as chosen by `--length` and `--charset`
+func generateValue(ctx *cli.Context) ([]byte, error) {
+	charset := ctx.String("charset")
+	length := ctx.Int("length")
+	if !ctx.IsSet("length") {
+		length = generate.DefaultLength
+		if charset == "words" {
+			length = generate.DefaultWords
+		}
+	}
+
+	return generate.Generate(charset, length)
+}
+
+func init() {
+	description := multiLineDescription(
+		"Replaces the secrets at every `KEYPATH` in `CONFIG_FILE` with new random values, and saves it. Values are generated with a cryptographically secure random number generator and encrypted right away, so they're never shown unless `--show` is passed.",
+
+		"Values are 32 characters long and alphanumeric by default. Choose other characters with `--charset`: `hex`, `base64`, or `words` to generate a passphrase of dash-separated words, 8 by default. `--length` counts characters, or words.",
+
+		"Rotated secrets keep their description and owner, and are stamped with the time they were rotated. Pass `--expires` to set when the new values stop being valid, see `gcy audit`. To generate a new secret, use `gcy set --generate` instead.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "rotate",
+		Before:      beforeWriteCommand,
+		Usage:       "Replace secrets with new random values",
+		ArgsUsage:   "CONFIG_FILE KEYPATH...",
+		Description: description,
+		Flags: append(append([]cli.Flag{
+			&cli.StringFlag{
+				Name:   "keypath",
+				Value:  "",
+				Usage:  "Used internally by the app",
+				Hidden: true,
+			},
+			&cli.StringFlag{
+				Name:  "expires",
+				Usage: "Record when the new values stop being valid, as a date like 2021-01-31 or a number of days like 90d",
+			},
+		}, generateFlags()...), util.LoaderFlags()...),
+		BashComplete: func(ctx *cli.Context) {
+			if ctx.NArg() == 0 {
+				autocomplete.ListAllFlags(ctx)
+			} else {
+				autocomplete.ListKeys(ctx)
+			}
+			os.Exit(1)
+		},
+		Action: rotate,
+	})
+}
+
+// Rotate secrets, replacing them with new random values
+func rotate(ctx *cli.Context) error {
+	defer configFile.Close()
+	target := ctx.Args().Get(0)
+	keyPaths := ctx.Args().Slice()[1:]
+
+	if !configFile.HasCrypto() {
+		return Exit(fmt.Sprintf("Cannot rotate secrets in %s, it has no crypto provider", target), ExitCodeInputError)
+	}
+
+	setOptions, err := metadataOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	generated := map[string][]byte{}
+	for _, keyPath := range keyPaths {
+		if _, err := configFile.GetMetadata(keyPath); err != nil {
+			return Exit(fmt.Sprintf("Cannot rotate %s, it's not a secret. Use gcy set --generate to create one", keyPath), ExitCodeInputError)
+		}
+
+		value, err := generateValue(ctx)
+		if err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+
+		if err = checkValue(target, configFile, keyPath, value, true); err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+
+		if err = configFile.Set(keyPath, value, setOptions...); err != nil {
+			return Exit(fmt.Sprintf("Could not rotate %s: %s", keyPath, err), ExitCodeToolError)
+		}
+		generated[keyPath] = value
+	}
+
+	if err := util.SerializeAndWrite(target, configFile); err != nil {
+		return Exit(err, ExitCodeToolError)
+	}
+
+	if ctx.Bool("show") {
+		for _, keyPath := range keyPaths {
+			fmt.Printf("%s: %s\n", keyPath, generated[keyPath])
+		}
+	}
+	log.Infof("Rotated %d secret(s) in %s", len(keyPaths), target)
+
+	return nil
+}
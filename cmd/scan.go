@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/scan"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// the allowlist used when --allowlist is not passed, if it exists
+const defaultAllowlist = ".gcy-allowlist"
+
+func init() {
+	description := multiLineDescription(
+		"Looks for plaintext values in `FILES` that look like secrets, because of their key name, a known token format like AWS access keys or GitHub tokens, or their randomness. `FILES` may be files, directories or globs, and default to every `.yml` and `.yaml` file in the current directory.",
+
+		"Each finding is output as `FILE:LINE:COLUMN: message`, and `gcy scan` fails with exit code 1 if there are any, so it can run as a pre-commit hook. Values in the `crypto` property, encrypted values and references like `${DB_PASSWORD}` are skipped.",
+
+		"The allowlist, `.gcy-allowlist` in the current directory unless `--allowlist` is passed, lists values to skip: one `KEYPATH` glob per line, or `FILE:KEYPATH` to skip it only in matching files. Keypath globs are dot-delimited, and `**` matches any number of keys.",
+
+		"Pass `--fix` to encrypt flagged values in place with each file's crypto provider. Values inside lists can't be encrypted, so they are reported but left alone. `gcy scan --fix` still fails when it changes files, so they can be reviewed and staged again.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "scan",
+		Usage:       "Find plaintext values that look like secrets",
+		ArgsUsage:   "[FILES...]",
+		Description: description,
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "fix",
+				Usage: "Encrypt flagged values in place",
+			},
+			&cli.StringFlag{
+				Name:  "allowlist",
+				Usage: "Read values to skip from this file, instead of .gcy-allowlist",
+			},
+		}, util.LoaderFlags()...),
+		Action: scanAction,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Scan config files for plaintext secrets
+func scanAction(ctx *cli.Context) error {
+	targets := ctx.Args().Slice()
+	if len(targets) == 0 {
+		targets = []string{"."}
+	}
+
+	paths := []string{}
+	for _, target := range targets {
+		if !util.IsBulkTarget(target) {
+			paths = append(paths, target)
+			continue
+		}
+		expanded, err := util.ExpandTarget(target)
+		if err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+		paths = append(paths, expanded...)
+	}
+
+	allowlistPath := ctx.String("allowlist")
+	if allowlistPath == "" {
+		if _, err := os.Stat(defaultAllowlist); err == nil {
+			allowlistPath = defaultAllowlist
+		}
+	}
+	var allowlist *scan.Allowlist
+	if allowlistPath != "" {
+		var err error
+		if allowlist, err = scan.LoadAllowlist(allowlistPath); err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+	}
+
+	found := 0
+	failed := 0
+	byFile := map[string][]scan.Finding{}
+	for _, path := range paths {
+		findings, err := scan.File(path, allowlist)
+		if err != nil {
+			log.Errorf("Could not scan %s: %s", path, err)
+			failed++
+			continue
+		}
+
+		for _, finding := range findings {
+			fmt.Println(finding)
+		}
+		found += len(findings)
+		if len(findings) > 0 {
+			byFile[path] = findings
+		}
+	}
+
+	if failed > 0 {
+		return Exit(fmt.Sprintf("Could not scan %d file(s)", failed), ExitCodeInputError)
+	}
+	if found == 0 {
+		return nil
+	}
+
+	if !ctx.Bool("fix") {
+		return Exit(fmt.Sprintf("Found %d plaintext value(s) that look like secrets, encrypt them with gcy set or gcy scan --fix, or add them to the allowlist", found), ExitCodeFindings)
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	encrypted := 0
+	for _, path := range paths {
+		findings, hasFindings := byFile[path]
+		if !hasFindings {
+			continue
+		}
+		fixable := []scan.Finding{}
+		for _, finding := range findings {
+			if finding.InList {
+				log.Errorf("Cannot fix %s in %s, values inside lists can't be encrypted", finding.KeyPath, path)
+				continue
+			}
+			fixable = append(fixable, finding)
+		}
+		if len(fixable) == 0 {
+			continue
+		}
+
+		if err := encryptFindings(path, fixable, options); err != nil {
+			log.Errorf("Could not encrypt values in %s: %s", path, err)
+			continue
+		}
+		log.Infof("Encrypted %d value(s) in %s", len(fixable), path)
+		encrypted += len(fixable)
+	}
+
+	if encrypted < found {
+		return Exit(fmt.Sprintf("Encrypted %d of %d plaintext value(s) that look like secrets", encrypted, found), ExitCodeFindings)
+	}
+	return Exit(fmt.Sprintf("Encrypted %d plaintext value(s) that looked like secrets, review and stage the changes", encrypted), ExitCodeFindings)
+}
+
+// encryptFindings replaces each finding's plaintext value with its encrypted form, in the file at `path`
+func encryptFindings(path string, findings []scan.Finding, options []file.Option) error {
+	config, err := file.Load(path, append(options, file.WithLock())...)
+	if err != nil {
+		return err
+	}
+	defer config.Close()
+
+	if !config.HasCrypto() {
+		return fmt.Errorf("it has no crypto provider, add one with gcy rekey")
+	}
+
+	for _, finding := range findings {
+		if err := config.Set(finding.KeyPath, []byte(finding.Value)); err != nil {
+			return fmt.Errorf("Could not encrypt %s: %s", finding.KeyPath, err)
+		}
+	}
+
+	return util.SerializeAndWrite(path, config)
+}
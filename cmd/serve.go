@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/serve"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Serves the values of `CONFIG_FILE` over HTTP, for programs that can't read it themselves. `GET /v1/keys/KEYPATH` answers with the JSON value at `KEYPATH`, decrypted, the same as `gcy get` would print. `KEYPATH` may be delimited with dots or slashes, so `/v1/keys/db.password` and `/v1/keys/db/password` are the same.",
+
+		"`gcy serve` listens on a unix socket only accessible to the current user with `--socket`, or on a TCP address with `--listen`, which requires requests to carry a bearer token: `Authorization: Bearer TOKEN`. Set the token with `GCY_SERVE_TOKEN` or `--token-file`.",
+
+		"`CONFIG_FILE` is loaded again when it changes. If it can't be loaded, its last version keeps being served. Passwords from `--password-fd` are read once and used for every reload, password files and commands are read again.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "serve",
+		Usage:       "Serve decrypted values from CONFIG_FILE over HTTP",
+		ArgsUsage:   "CONFIG_FILE",
+		Description: description,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "socket",
+				Usage: "The path of a unix socket to listen at",
+			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "A TCP address to listen at, like 127.0.0.1:8200",
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "The bearer token requests must carry, prefer setting it in the environment",
+				EnvVars: []string{"GCY_SERVE_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:  "token-file",
+				Usage: "Read the bearer token from the first line of a file",
+			},
+		}, util.LoaderFlags()...),
+		Action: serveAction,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Serve the values of a config file over HTTP
+func serveAction(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return showUsage(ctx, "Missing CONFIG_FILE")
+	}
+	target := ctx.Args().First()
+
+	socket, address := ctx.String("socket"), ctx.String("listen")
+	if (socket == "") == (address == "") {
+		return Exit("Pass either --socket or --listen", ExitCodeInputError)
+	}
+
+	token := ctx.String("token")
+	if path := ctx.String("token-file"); path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Exit(fmt.Sprintf("Could not read token file: %s", err), ExitCodeInputError)
+		}
+		token = strings.TrimSpace(strings.SplitN(string(contents), "\n", 2)[0])
+	}
+	if address != "" && token == "" {
+		return Exit("A bearer token is required with --listen, set GCY_SERVE_TOKEN or pass --token-file", ExitCodeInputError)
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	server, err := serve.New(target, token, options...)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	var listener net.Listener
+	if socket != "" {
+		listener, err = serve.Listen(socket)
+		address = socket
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		server.Close()
+		return Exit(err, ExitCodeToolError)
+	}
+	defer server.Close()
+
+	httpServer := &http.Server{Handler: server}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		httpServer.Close()
+	}()
+
+	log.Infof("Serving %s at %s", target, address)
+	if err = httpServer.Serve(listener); err != http.ErrServerClosed {
+		return Exit(err, ExitCodeToolError)
+	}
+	if socket != "" {
+		os.Remove(socket)
+	}
+	log.Info("Stopped serving")
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blinkhealth/go-config-yourself/cmd/autocomplete"
 	"github.com/blinkhealth/go-config-yourself/cmd/util"
@@ -25,17 +27,23 @@ func init() {
 
 		"A properly configured `crypto` property must exist `CONFIG_FILE` for encryption to succeed, `gcy set` will exit with a non-zero status code otherwise. See `gcy help config-file` for more information about `CONFIG_FILE`.",
 
+		"Pass `--generate` to store a new random value instead, 32 alphanumeric characters long by default, which is encrypted right away and never shown unless `--show` is passed. See `gcy help rotate` for how to choose its `--length` and `--charset`.",
+
+		"If `CONFIG_FILE` has a schema, see `gcy help validate`, values that break it are not stored.",
+
+		"Secrets are stored along with the time they were set, and optionally a `--description`, an `--owner` and an `--expires` date, see `gcy audit`. Setting a secret again keeps its description and owner, unless new ones are passed.",
+
 		"If a `defaults` or `default` file with the same extension as `CONFIG_FILE` exists in the same directory, `gcy set` will add a nil value for `KEYPATH` in said file.",
 	)
 
 	App.Commands = append(App.Commands, &cli.Command{
 		Name:        "set",
-		Before:      beforeCommand,
+		Before:      beforeWriteCommand,
 		Aliases:     []string{"edit"},
 		Usage:       "Set a value in CONFIG_FILE at KEYPATH",
 		ArgsUsage:   "CONFIG_FILE KEYPATH",
 		Description: description,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:   "keypath",
 				Value:  "",
@@ -54,7 +62,23 @@ func init() {
 				Usage:   "Use the specified file path instead of prompting for input from `stdin`",
 				Aliases: []string{"i"},
 			},
-		},
+			&cli.BoolFlag{
+				Name:  "generate",
+				Usage: "Store a new random value instead of reading one, see --length and --charset",
+			},
+			&cli.StringFlag{
+				Name:  "description",
+				Usage: "Describe what the secret is for",
+			},
+			&cli.StringFlag{
+				Name:  "owner",
+				Usage: "Record who to ask about the secret, like a team or a person",
+			},
+			&cli.StringFlag{
+				Name:  "expires",
+				Usage: "Record when the secret stops being valid, as a date like 2021-01-31 or a number of days like 90d",
+			},
+		}, append(generateFlags(), util.LoaderFlags()...)...),
 		BashComplete: func(ctx *cli.Context) {
 			argCount := ctx.NArg()
 
@@ -81,6 +105,7 @@ const noCryptoError = "Unable to store an encrypted value for '%s', use --plain-
 
 //Set saves an encrypted or plaintext value on the file
 func set(ctx *cli.Context) error {
+	defer configFile.Close()
 	keyPath := ctx.String("keypath")
 
 	if keyPath == "crypto" || strings.HasPrefix(keyPath, "crypto.") {
@@ -94,9 +119,26 @@ func set(ctx *cli.Context) error {
 		return Exit(errors.New(message), ExitCodeInputError)
 	}
 
+	setOptions, err := metadataOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+	if isPlainText && len(setOptions) > 0 {
+		return Exit("Cannot store a description, owner or expiry for plain text values", ExitCodeInputError)
+	}
+
+	isGenerated := ctx.Bool("generate")
+	if isGenerated && (isPlainText || ctx.IsSet("input-file")) {
+		return Exit("Cannot --generate a value to store as --plain-text or read from --input-file", ExitCodeInputError)
+	}
+	if !isGenerated && (ctx.IsSet("length") || ctx.IsSet("charset") || ctx.IsSet("show")) {
+		return Exit("--length, --charset and --show only apply to --generate", ExitCodeInputError)
+	}
+
 	var plainText []byte
-	var err error
-	if file := ctx.String("input-file"); file != "" {
+	if isGenerated {
+		plainText, err = generateValue(ctx)
+	} else if file := ctx.String("input-file"); file != "" {
 		plainText, err = input.ReadFile(file)
 	} else {
 		prompt := fmt.Sprintf("Enter value for “%s”", keyPath)
@@ -107,21 +149,28 @@ func set(ctx *cli.Context) error {
 		return Exit(err, ExitCodeInputError)
 	}
 
+	target := ctx.Args().Get(0)
+	if err = checkValue(target, configFile, keyPath, plainText, !isPlainText); err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
 	if isPlainText {
 		err = configFile.VeryInsecurelySetPlaintext(keyPath, plainText)
 	} else {
-		err = configFile.Set(keyPath, plainText)
+		err = configFile.Set(keyPath, plainText, setOptions...)
 	}
 
 	if err != nil {
 		return Exit(fmt.Sprintf("Could not set %s: %s", keyPath, err), ExitCodeToolError)
 	}
 
-	target := ctx.Args().Get(0)
 	if err := util.SerializeAndWrite(target, configFile); err != nil {
 		return Exit(err, ExitCodeToolError)
 	}
 
+	if ctx.Bool("show") {
+		fmt.Println(string(plainText))
+	}
 	log.Infof("Value set at %s", keyPath)
 	// update defaults file if write was successful
 	updateDefaultsFile(target, keyPath)
@@ -143,8 +192,9 @@ func updateDefaultsFile(target string, keyPath string) {
 		candidate := fmt.Sprintf("%s%s%s", configFolder, name, extension)
 		if _, err := os.Stat(candidate); !os.IsNotExist(err) {
 			log.Debugf("Found defaults file: %s", candidate)
-			defaultsFile, err := file.Load(candidate)
+			defaultsFile, err := file.Load(candidate, file.WithLock())
 			if err == nil {
+				defer defaultsFile.Close()
 				_, err := defaultsFile.Get(keyPath)
 				if err != nil && strings.Contains(err.Error(), "Could not find a value") {
 					if err := defaultsFile.VeryInsecurelySetPlaintext(keyPath, nil); err == nil {
@@ -159,3 +209,37 @@ func updateDefaultsFile(target string, keyPath string) {
 		}
 	}
 }
+
+// metadataOptions reads the secret's metadata from `--description`, `--owner` and `--expires`
+func metadataOptions(ctx *cli.Context) (options []file.SetOption, err error) {
+	if ctx.IsSet("description") {
+		options = append(options, file.WithDescription(ctx.String("description")))
+	}
+	if ctx.IsSet("owner") {
+		options = append(options, file.WithOwner(ctx.String("owner")))
+	}
+	if ctx.IsSet("expires") {
+		expires, err := parseExpiry(ctx.String("expires"), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, file.WithExpiry(expires))
+	}
+	return options, nil
+}
+
+// parseExpiry reads a date like `2021-01-31`, a timestamp in RFC 3339 format, or a number of days after `from` like `90d`
+func parseExpiry(value string, from time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days > 0 {
+			return from.AddDate(0, 0, days), nil
+		}
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamp, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid expiry <%s>, use a date like 2021-01-31 or a number of days like 90d", value)
+}
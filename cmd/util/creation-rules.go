@@ -0,0 +1,153 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blinkhealth/go-config-yourself/internal/glob"
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/plugin"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProjectConfigName is the name of the file holding creation rules, found by walking up from a config file's directory
+const ProjectConfigName = ".gcy.yaml"
+
+// CreationRule maps config files matching a path glob to a provider and its arguments
+type CreationRule struct {
+	// A slash-delimited glob, relative to the project config's directory. `**` matches any number of directories
+	Path string
+	// The provider to encrypt matching files with
+	Provider string
+	// Arguments for the provider, named like their flags
+	Args map[string]interface{}
+	// The JSON Schema matching files must follow, relative to the project config's directory, if any
+	Schema string
+	// The project config this rule comes from
+	Source string
+}
+
+// FindCreationRule returns the first rule matching `target` in the closest project config, or nil if there's none
+func FindCreationRule(target string) (*CreationRule, error) {
+	absolute, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+
+	for dir := filepath.Dir(absolute); ; dir = filepath.Dir(dir) {
+		source := filepath.Join(dir, ProjectConfigName)
+		if _, err := os.Stat(source); err == nil {
+			rules, err := loadCreationRules(source)
+			if err != nil {
+				return nil, err
+			}
+
+			relative, _ := filepath.Rel(dir, absolute)
+			for _, rule := range rules {
+				if glob.Match(rule.Path, filepath.ToSlash(relative)) {
+					log.Debugf("Creation rule <%s> in %s matches %s", rule.Path, source, target)
+					return rule, nil
+				}
+			}
+			log.Debugf("No creation rule in %s matches %s", source, target)
+			return nil, nil
+		}
+
+		if dir == filepath.Dir(dir) {
+			return nil, nil
+		}
+	}
+}
+
+// CreationRulePaths returns the path globs of every creation rule in the project config at `source`
+func CreationRulePaths(source string) (paths []string, err error) {
+	rules, err := loadCreationRules(source)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		paths = append(paths, rule.Path)
+	}
+	return paths, nil
+}
+
+// untrustedArgs can't be set by creation rules, since project configs are committed along with the files, and these
+// choose how passwords are read
+var untrustedArgs = map[string]bool{"password": true, "password-file": true, "password-fd": true, "password-command": true}
+
+// loadCreationRules reads and validates the `creation_rules` in the project config at `source`
+func loadCreationRules(source string) (rules []*CreationRule, err error) {
+	data, err := yaml.FromPathname(source)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %s", source, err)
+	}
+
+	entries := []map[string]interface{}{}
+	if err = data.Get("creation_rules", &entries); err != nil {
+		return nil, fmt.Errorf("Invalid %s, creation_rules must be a list of rules: %s", source, err)
+	}
+
+	for index, entry := range entries {
+		rule := &CreationRule{Args: map[string]interface{}{}, Source: source}
+		rule.Path, _ = entry["path"].(string)
+		rule.Provider, _ = entry["provider"].(string)
+		rule.Schema, _ = entry["schema"].(string)
+		if rule.Path == "" || rule.Provider == "" {
+			return nil, fmt.Errorf("Invalid creation rule #%d in %s, both path and provider are required", index+1, source)
+		}
+
+		if !plugin.Find(rule.Provider) {
+			return nil, fmt.Errorf("Unknown provider <%s> in creation rule <%s> in %s", rule.Provider, rule.Path, source)
+		}
+
+		// plugins are only registered once a rule uses them
+		flags := map[string]pvd.Argument{}
+		for _, flag := range pvd.AvailableFlags() {
+			flags[flag.Name] = flag
+		}
+
+		for name, value := range entry {
+			if name == "path" || name == "provider" || name == "schema" {
+				continue
+			}
+
+			if untrustedArgs[name] {
+				return nil, fmt.Errorf("Creation rule <%s> in %s cannot set <%s>, pass it on the command line instead", rule.Path, source, name)
+			}
+
+			flag, known := flags[name]
+			if !known {
+				return nil, fmt.Errorf("Unknown argument <%s> in creation rule <%s> in %s", name, rule.Path, source)
+			}
+			rule.Args[name] = ruleArgument(flag, value)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ruleArgument converts a yaml `value` into what the provider expects for `flag`, like the command line would
+func ruleArgument(flag pvd.Argument, value interface{}) interface{} {
+	switch {
+	case flag.Repeatable:
+		values, isList := value.([]interface{})
+		if !isList {
+			values = []interface{}{value}
+		}
+		strs := []string{}
+		for _, item := range values {
+			strs = append(strs, fmt.Sprintf("%v", item))
+		}
+		return strs
+	case flag.IsSwitch:
+		enabled, _ := value.(bool)
+		return enabled
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	// register kms
+	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/kms"
+)
+
+func TestFindCreationRule(t *testing.T) {
+	project, err := ioutil.TempDir("", "gcy-rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(project)
+
+	rules := `creation_rules:
+  - path: config/production.yml
+    provider: kms
+    key: arn:aws:kms:us-east-1:000000000000:key/production
+    kms-context: env=production
+  - path: config/**
+    provider: kms
+    key: arn:aws:kms:us-east-1:000000000000:key/development
+    schema: config/schema.yaml
+`
+	if err = ioutil.WriteFile(filepath.Join(project, ProjectConfigName), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rule, err := FindCreationRule(filepath.Join(project, "config", "production.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"key": "arn:aws:kms:us-east-1:000000000000:key/production", "kms-context": []string{"env=production"}}
+	if rule == nil || rule.Provider != "kms" || !reflect.DeepEqual(rule.Args, expected) {
+		t.Fatalf("Found wrong rule: %v", rule)
+	}
+
+	rule, _ = FindCreationRule(filepath.Join(project, "config", "dev", "app.yml"))
+	if rule == nil || rule.Args["key"] != "arn:aws:kms:us-east-1:000000000000:key/development" || rule.Schema != "config/schema.yaml" {
+		t.Fatalf("Found wrong rule: %v", rule)
+	}
+
+	if rule, _ = FindCreationRule(filepath.Join(project, "app.yml")); rule != nil {
+		t.Fatalf("Found a rule for an unmatched file: %v", rule)
+	}
+
+	bad := "creation_rules:\n  - path: '**'\n    provider: kms\n    colour: red\n"
+	if err = ioutil.WriteFile(filepath.Join(project, ProjectConfigName), []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = FindCreationRule(filepath.Join(project, "app.yml")); err == nil || !strings.Contains(err.Error(), "Unknown argument <colour>") {
+		t.Fatalf("Accepted an unknown argument: %v", err)
+	}
+
+	injected := "creation_rules:\n  - path: '**'\n    provider: kms\n    password-command: touch /tmp/pwned\n"
+	if err = ioutil.WriteFile(filepath.Join(project, ProjectConfigName), []byte(injected), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = FindCreationRule(filepath.Join(project, "app.yml")); err == nil || !strings.Contains(err.Error(), "cannot set <password-command>") {
+		t.Fatalf("Accepted a password command: %v", err)
+	}
+}
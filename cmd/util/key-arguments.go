@@ -8,12 +8,37 @@ import (
 )
 
 // GetKeyArguments reads arguments from the command line and parses them into flags
-func GetKeyArguments(ctx *cli.Context) (args map[string]interface{}) {
+//
+// Absent flags fall back to the creation rule for CONFIG_FILE, if any: the rule picks the provider unless
+// `--provider` is set, and fills in the arguments missing for it. Rules are not looked up when CONFIG_FILE names many
+// files, since each might match a different one
+func GetKeyArguments(ctx *cli.Context) (args map[string]interface{}, err error) {
+	var rule *CreationRule
+	if target := ctx.Args().Get(0); !IsBulkTarget(target) {
+		rule, err = FindCreationRule(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rule != nil && !ctx.IsSet("provider") {
+		log.Infof("Using provider %s from creation rule <%s> in %s", rule.Provider, rule.Path, rule.Source)
+		if err = ctx.Set("provider", rule.Provider); err != nil {
+			return nil, err
+		}
+	}
+
 	commandArgs := ctx.Args().Tail()
 	args = make(map[string]interface{})
 	for _, flag := range ctx.Command.Flags {
 		log.Debugf("looking up flag %s, args remaining: %d", flag.Names(), len(commandArgs))
 		name := flag.Names()[0]
+		if name == "password-fd" && ctx.IsSet(name) {
+			// the descriptor may have been read already to load CONFIG_FILE, share its password instead
+			if args["password"], err = PasswordFromFD(ctx.String(name)); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if ctx.IsSet(name) {
 			log.Debugf("found flag %s", name)
 			switch f := flag.(type) {
@@ -30,13 +55,40 @@ func GetKeyArguments(ctx *cli.Context) (args map[string]interface{}) {
 	}
 
 	if len(commandArgs) > 0 {
-		if ctx.String("provider") == "gpg" && !ctx.IsSet("public-keys") {
+		provider := ctx.String("provider")
+		if provider == "" {
+			// kms is the default provider
+			provider = "kms"
+		}
+
+		if provider == "gpg" && !ctx.IsSet("public-keys") {
 			args["public-key"] = commandArgs
 		}
 
-		if ctx.String("provider") == "kms" && !ctx.IsSet("key") {
+		if provider == "kms" && !ctx.IsSet("key") {
 			args["key"] = commandArgs[0]
 		}
+
+		if provider == "vault" && !ctx.IsSet("vault-key") {
+			args["vault-key"] = commandArgs[0]
+		}
+
+		if provider == "gcpkms" && !ctx.IsSet("gcp-key") {
+			args["gcp-key"] = commandArgs[0]
+		}
+
+		if provider == "azurekv" && !ctx.IsSet("azure-key") {
+			args["azure-key"] = commandArgs[0]
+		}
+	}
+
+	if rule != nil && ctx.String("provider") == rule.Provider {
+		for name, value := range rule.Args {
+			if _, set := args[name]; !set {
+				log.Debugf("Using %s from creation rule <%s>", name, rule.Path)
+				args[name] = value
+			}
+		}
 	}
 
 	return
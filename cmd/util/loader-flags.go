@@ -0,0 +1,71 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+
+	pwd "github.com/blinkhealth/go-config-yourself/pkg/crypto/password"
+	file "github.com/blinkhealth/go-config-yourself/pkg/file"
+	cli "github.com/urfave/cli/v2"
+)
+
+// LoaderFlags returns a list of cli flags for commands that read existing config files
+func LoaderFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "password-file",
+			Usage: "Read the password from the first line of a file, instead of prompting for it",
+		},
+		&cli.StringFlag{
+			Name:  "password-fd",
+			Usage: "Read the password from an open file descriptor, instead of prompting for it",
+		},
+		&cli.StringFlag{
+			Name:  "password-command",
+			Usage: "Read the password from the output of a shell command, instead of prompting for it, for example: `pass show team/gcy`",
+		},
+	}
+}
+
+// LoaderOptions reads LoaderFlags from the command line and turns them into file.Options
+func LoaderOptions(ctx *cli.Context) (options []file.Option, err error) {
+	if path := ctx.String("password-file"); path != "" {
+		options = append(options, file.WithPasswordFile(path))
+	}
+
+	if fd := ctx.String("password-fd"); fd != "" {
+		password, err := PasswordFromFD(fd)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, file.WithPassword(password))
+	}
+
+	if command := ctx.String("password-command"); command != "" {
+		options = append(options, file.WithPasswordCommand(command))
+	}
+
+	return
+}
+
+// passwordsByFD keeps the passwords read from file descriptors, which can only be read once
+var passwordsByFD = map[string]string{}
+
+// PasswordFromFD reads the password from the file descriptor `fd` the first time it's called, and returns the same
+// password afterwards, so the files and keys a command handles can share it
+func PasswordFromFD(fd string) (password string, err error) {
+	if password, read := passwordsByFD[fd]; read {
+		return password, nil
+	}
+
+	fdInt, err := strconv.Atoi(fd)
+	if err != nil || fdInt < 0 {
+		return "", fmt.Errorf("Invalid password file descriptor <%s>", fd)
+	}
+
+	if password, err = pwd.ReadFD(fdInt); err != nil {
+		return "", err
+	}
+	passwordsByFD[fd] = password
+	return password, nil
+}
@@ -1,23 +1,21 @@
 package util
 
 import (
-	"fmt"
-	"io/ioutil"
-
 	file "github.com/blinkhealth/go-config-yourself/pkg/file"
 	cli "github.com/urfave/cli/v2"
 )
 
-// SerializeAndWrite a config file to disk
+// KeepBackups makes SerializeAndWrite keep the previous contents of files it overwrites, with a `.bak` suffix
+var KeepBackups = false
+
+// SerializeAndWrite a config file to disk, atomically
 func SerializeAndWrite(path string, cfg *file.ConfigFile) (err error) {
-	outBytes, err := cfg.Serialize()
-	if err != nil {
-		return cli.Exit(err, 2)
+	options := []file.SaveOption{}
+	if KeepBackups {
+		options = append(options, file.WithBackup())
 	}
 
-	err = ioutil.WriteFile(path, outBytes, 0644)
-	if err != nil {
-		err = fmt.Errorf("Unable to write configuration to %s: %v", path, err)
+	if err = cfg.Save(path, options...); err != nil {
 		return cli.Exit(err, 2)
 	}
 	return
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/blinkhealth/go-config-yourself/internal/schema"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+// SchemaKey is the property of config files that may point to the schema they follow, relative to them
+const SchemaKey = "$schema"
+
+// FindSchema loads the schema the config file at `target` follows, or returns nil if there's none
+func FindSchema(target string, config *file.ConfigFile) (*schema.Schema, error) {
+	path, err := SchemaPath(target, config)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return schema.Load(path)
+}
+
+// SchemaPath returns the path to the schema the config file at `target` follows, as set by its `$schema` property
+// or the `schema` of the creation rule matching it, or an empty string if there's none
+func SchemaPath(target string, config *file.ConfigFile) (string, error) {
+	if declared, err := config.Get(SchemaKey); err == nil && declared != nil {
+		path, isString := declared.(string)
+		if !isString {
+			return "", fmt.Errorf("Invalid %s in %s, it must be the path to a schema", SchemaKey, target)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(target), path)
+		}
+		return path, nil
+	}
+
+	rule, err := FindCreationRule(target)
+	if err != nil || rule == nil || rule.Schema == "" {
+		return "", err
+	}
+
+	path := rule.Schema
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(rule.Source), path)
+	}
+	return path, nil
+}
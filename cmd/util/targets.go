@@ -0,0 +1,83 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/internal/glob"
+)
+
+// IsBulkTarget tells whether `target` names many config files, either as a directory or a glob, instead of a single one
+func IsBulkTarget(target string) bool {
+	if strings.ContainsAny(target, "*?[") {
+		return true
+	}
+
+	info, err := os.Stat(target)
+	return err == nil && info.IsDir()
+}
+
+// ExpandTarget lists the config files named by `target`, sorted by path
+//
+// Directories are searched recursively for `.yml` and `.yaml` files, skipping hidden directories. Globs are
+// slash-delimited and match any file, where `**` matches any number of directories
+func ExpandTarget(target string) (paths []string, err error) {
+	base, pattern := filepath.Clean(target), "**/*"
+	onlyYAML := true
+
+	if strings.ContainsAny(target, "*?[") {
+		onlyYAML = false
+		segments := strings.Split(filepath.ToSlash(target), "/")
+		for index, segment := range segments {
+			if strings.ContainsAny(segment, "*?[") {
+				base = strings.Join(segments[:index], "/")
+				pattern = strings.Join(segments[index:], "/")
+				break
+			}
+		}
+		if base == "" {
+			base = "."
+		}
+		if strings.HasPrefix(target, "/") && base == "." {
+			base = "/"
+		}
+	}
+
+	err = filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != base && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Name() == ProjectConfigName {
+			return nil
+		}
+
+		if onlyYAML && filepath.Ext(path) != ".yml" && filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+
+		relative, _ := filepath.Rel(base, path)
+		if glob.Match(pattern, filepath.ToSlash(relative)) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not list config files in %s: %s", target, err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("Could not find any config files in %s", target)
+	}
+
+	return paths, nil
+}
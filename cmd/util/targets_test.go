@@ -0,0 +1,63 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandTarget(t *testing.T) {
+	project, err := ioutil.TempDir("", "gcy-targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(project)
+
+	for _, name := range []string{"app.yml", "config/production.yaml", "config/dev/app.yml", "config/notes.txt", ".git/config.yml", ProjectConfigName} {
+		path := filepath.Join(project, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string][]string{
+		project:                                          {"app.yml", "config/dev/app.yml", "config/production.yaml"},
+		filepath.Join(project, "config"):                 {"config/dev/app.yml", "config/production.yaml"},
+		filepath.Join(project, "config", "*"):            {"config/notes.txt", "config/production.yaml"},
+		filepath.Join(project, "**", "app.yml"):          {"app.yml", "config/dev/app.yml"},
+		filepath.Join(project, "config", "**", "*.y*ml"): {"config/dev/app.yml", "config/production.yaml"},
+	}
+
+	for target, expected := range cases {
+		if !IsBulkTarget(target) {
+			t.Errorf("Expected %s to be a bulk target", target)
+		}
+
+		paths, err := ExpandTarget(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := []string{}
+		for _, path := range paths {
+			relative, _ := filepath.Rel(project, path)
+			found = append(found, filepath.ToSlash(relative))
+		}
+		if !reflect.DeepEqual(found, expected) {
+			t.Errorf("Expanded %s to %v, expected %v", target, found, expected)
+		}
+	}
+
+	if IsBulkTarget(filepath.Join(project, "app.yml")) {
+		t.Error("Expected a single file not to be a bulk target")
+	}
+
+	if _, err = ExpandTarget(filepath.Join(project, "*.json")); err == nil {
+		t.Error("Expanded a glob without matches")
+	}
+}
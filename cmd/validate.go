@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/cmd/util"
+	"github.com/blinkhealth/go-config-yourself/internal/schema"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func init() {
+	description := multiLineDescription(
+		"Decrypts `CONFIG_FILE` and checks its values against a JSON Schema, written in JSON or YAML. `CONFIG_FILE` may also be a directory or glob, and many can be passed.",
+
+		"The schema is read from `--schema` if passed, otherwise from the path in the file's `$schema` property, relative to the file, or from the `schema` of the creation rule matching the file in `.gcy.yaml`, relative to it. Files without a schema are skipped.",
+
+		"Secrets are decrypted as strings, and read as JSON when the schema expects another type, so a secret port is checked as a number. Schemas support the `type`, `enum`, `const`, `properties`, `required`, `additionalProperties`, `items`, `minItems`, `maxItems`, `minimum`, `maximum`, `minLength`, `maxLength` and `pattern` keywords. `gcy set` also checks values against the schema before storing them.",
+
+		"Each problem is output as `FILE: KEYPATH problem`, and `gcy validate` fails with exit code 1 if there are any.",
+	)
+
+	App.Commands = append(App.Commands, &cli.Command{
+		Name:        "validate",
+		Usage:       "Check config files against their schema",
+		ArgsUsage:   "CONFIG_FILE...",
+		Description: description,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "schema",
+				Usage: "Check files against the schema at this path, instead of their own",
+			},
+		}, util.LoaderFlags()...),
+		Action: validate,
+		BashComplete: func(ctx *cli.Context) {
+			os.Exit(1)
+		},
+	})
+}
+
+// Validate config files against a schema
+func validate(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return showUsage(ctx, "Missing CONFIG_FILE")
+	}
+
+	paths := []string{}
+	for _, target := range ctx.Args().Slice() {
+		if !util.IsBulkTarget(target) {
+			paths = append(paths, target)
+			continue
+		}
+		expanded, err := util.ExpandTarget(target)
+		if err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+		paths = append(paths, expanded...)
+	}
+
+	options, err := util.LoaderOptions(ctx)
+	if err != nil {
+		return Exit(err, ExitCodeInputError)
+	}
+
+	var override *schema.Schema
+	if path := ctx.String("schema"); path != "" {
+		if override, err = schema.Load(path); err != nil {
+			return Exit(err, ExitCodeInputError)
+		}
+	}
+
+	validated := 0
+	problems := 0
+	failed := 0
+	for _, path := range paths {
+		violations, checked, err := validateFile(path, override, options)
+		if err != nil {
+			log.Errorf("Could not validate %s: %s", path, err)
+			failed++
+			continue
+		}
+		if !checked {
+			log.Warnf("Skipping %s, no schema applies to it", path)
+			continue
+		}
+
+		validated++
+		for _, violation := range violations {
+			fmt.Printf("%s: %s\n", path, violation)
+		}
+		problems += len(violations)
+	}
+
+	if failed > 0 {
+		return Exit(fmt.Sprintf("Could not validate %d file(s)", failed), ExitCodeInputError)
+	}
+	if validated == 0 {
+		return Exit(fmt.Sprintf("No schema applies to %s, pass one with --schema", strings.Join(ctx.Args().Slice(), ", ")), ExitCodeInputError)
+	}
+	if problems > 0 {
+		return Exit(fmt.Sprintf("Found %d problem(s) in config values", problems), ExitCodeFindings)
+	}
+
+	log.Infof("Validated %d file(s), no problems found", validated)
+	return nil
+}
+
+// validateFile decrypts the config file at `path` and checks it against `override`, or its own schema. `checked` is
+// false if there's no schema to check it against
+func validateFile(path string, override *schema.Schema, options []file.Option) (violations []schema.Violation, checked bool, err error) {
+	config, err := file.Load(path, options...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer config.Close()
+
+	rules := override
+	if rules == nil {
+		if rules, err = util.FindSchema(path, config); err != nil || rules == nil {
+			return nil, false, err
+		}
+	}
+
+	tree, err := config.GetAll()
+	if err != nil {
+		return nil, false, err
+	}
+	delete(tree, "crypto")
+	delete(tree, util.SchemaKey)
+
+	return rules.Validate(tree, config.ListSecrets()), true, nil
+}
+
+// checkValue makes sure the value about to be stored at `keyPath` in `target` follows its schema, if it has one
+func checkValue(target string, config *file.ConfigFile, keyPath string, plainText []byte, secret bool) error {
+	if keyPath == util.SchemaKey {
+		return nil
+	}
+
+	rules, err := util.FindSchema(target, config)
+	if err != nil || rules == nil {
+		return err
+	}
+
+	// read values like ConfigFile.VeryInsecurelySetPlaintext stores them
+	var value interface{} = string(plainText)
+	if !secret {
+		var parsed interface{}
+		if err := json.Unmarshal(plainText, &parsed); err == nil {
+			value = parsed
+		}
+	}
+
+	violations := rules.ValidateAt(keyPath, value, secret)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	problems := []string{}
+	for _, violation := range violations {
+		problems = append(problems, violation.String())
+	}
+	return fmt.Errorf("Not storing a value that breaks the schema: %s", strings.Join(problems, "; "))
+}
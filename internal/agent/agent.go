@@ -0,0 +1,218 @@
+// Copyright 2018 Blink Health LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+
+// Package agent caches unwrapped keys for go-config-yourself in a local process, much like ssh-agent
+//
+// Providers ask the agent for a key before prompting the user or calling remote services, and hand it
+// the keys they unwrap, identified by a fingerprint of the wrapped key. Providers without data keys, like kms, hand it
+// decrypted values instead, identified by a fingerprint of their ciphertext.
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SocketEnvVar names the environment variable pointing to the agent's socket. Set it to
+// an empty string to disable the agent
+const SocketEnvVar = "GCY_AGENT_SOCK"
+
+const (
+	opGet  = "get"
+	opPut  = "put"
+	opLock = "lock"
+)
+
+type request struct {
+	Op    string `json:"op"`
+	ID    string `json:"id,omitempty"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type response struct {
+	Found bool   `json:"found"`
+	Value []byte `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+	// forgets the entry once it expires, even if it's never asked for again
+	timer *time.Timer
+}
+
+// Agent holds unwrapped keys in memory for a limited time
+type Agent struct {
+	ttl  time.Duration
+	mu   sync.Mutex
+	keys map[string]*entry
+}
+
+// New returns an Agent that forgets keys `ttl` after receiving them
+func New(ttl time.Duration) *Agent {
+	return &Agent{
+		ttl:  ttl,
+		keys: map[string]*entry{},
+	}
+}
+
+// SocketPath returns the path to the agent's socket
+func SocketPath() string {
+	if path, isSet := os.LookupEnv(SocketEnvVar); isSet {
+		return path
+	}
+
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("gcy-%d", os.Getuid()))
+	}
+
+	return filepath.Join(dir, "gcy-agent.sock")
+}
+
+// Listen creates a unix socket at `path` only accessible by the current user
+func Listen(path string) (listener net.Listener, err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Could not create agent directory %s: %s", dir, err)
+	}
+
+	if err = checkOwnership(dir); err != nil {
+		return nil, err
+	}
+
+	// a previous agent might have died without cleaning up
+	if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+		conn.Close()
+		return nil, fmt.Errorf("An agent is already listening at %s", path)
+	}
+	os.Remove(path)
+
+	if listener, err = net.Listen("unix", path); err != nil {
+		return nil, fmt.Errorf("Could not listen at %s: %s", path, err)
+	}
+
+	if err = os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// Serve answers requests on `listener` until it is closed
+func (agent *Agent) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go agent.handle(conn)
+	}
+}
+
+// Lock forgets all keys
+func (agent *Agent) Lock() {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	for id := range agent.keys {
+		agent.forget(id)
+	}
+	log.Info("Agent locked, all keys forgotten")
+}
+
+// forget wipes the entry for `id` and stops its timer, callers must hold agent.mu
+func (agent *Agent) forget(id string) {
+	e := agent.keys[id]
+	e.timer.Stop()
+	wipe(e.value)
+	delete(agent.keys, id)
+}
+
+func (agent *Agent) get(id string) ([]byte, bool) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	e, found := agent.keys[id]
+	if !found {
+		return nil, false
+	}
+
+	// the timer may not have fired yet
+	if time.Now().After(e.expires) {
+		agent.forget(id)
+		return nil, false
+	}
+
+	// the entry may be wiped while the copy is sent
+	return append([]byte(nil), e.value...), true
+}
+
+func (agent *Agent) put(id string, value []byte) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	if _, found := agent.keys[id]; found {
+		agent.forget(id)
+	}
+
+	e := &entry{
+		value:   value,
+		expires: time.Now().Add(agent.ttl),
+	}
+	e.timer = time.AfterFunc(agent.ttl, func() {
+		agent.mu.Lock()
+		defer agent.mu.Unlock()
+		// the entry may have been forgotten or replaced already
+		if agent.keys[id] == e {
+			agent.forget(id)
+		}
+	})
+	agent.keys[id] = e
+}
+
+func (agent *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := &request{}
+	res := &response{}
+	if err := json.NewDecoder(conn).Decode(req); err != nil {
+		res.Error = fmt.Sprintf("Invalid request: %s", err)
+	} else {
+		switch req.Op {
+		case opGet:
+			res.Value, res.Found = agent.get(req.ID)
+			log.Debugf("get %s, found: %v", req.ID, res.Found)
+		case opPut:
+			agent.put(req.ID, req.Value)
+			log.Debugf("put %s", req.ID)
+		case opLock:
+			agent.Lock()
+		default:
+			res.Error = fmt.Sprintf("Unknown operation <%s>", req.Op)
+		}
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		log.Debugf("Could not reply: %s", err)
+	}
+}
+
+func wipe(value []byte) {
+	for i := range value {
+		value[i] = 0
+	}
+}
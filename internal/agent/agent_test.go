@@ -0,0 +1,108 @@
+package agent_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	os.Exit(m.Run())
+}
+
+func startAgent(t *testing.T, ttl time.Duration) func() {
+	dir, err := ioutil.TempDir("", "gcy-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "private", "gcy-agent.sock")
+	os.Setenv(agent.SocketEnvVar, path)
+	listener, err := agent.Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_ = agent.New(ttl).Serve(listener)
+	}()
+
+	return func() {
+		listener.Close()
+		os.Unsetenv(agent.SocketEnvVar)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAgentCache(t *testing.T) {
+	stop := startAgent(t, time.Minute)
+	defer stop()
+
+	id := agent.Fingerprint([]byte("wrapped key"))
+	if _, found := agent.Get(id); found {
+		t.Fatal("Found a key before storing it")
+	}
+
+	agent.Put(id, []byte("unwrapped key"))
+	value, found := agent.Get(id)
+	if !found {
+		t.Fatal("Did not find a stored key")
+	}
+	if !bytes.Equal(value, []byte("unwrapped key")) {
+		t.Fatalf("Found the wrong key: %s", value)
+	}
+
+	if err := agent.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := agent.Get(id); found {
+		t.Fatal("Found a key after locking")
+	}
+}
+
+func TestAgentTTL(t *testing.T) {
+	stop := startAgent(t, 10*time.Millisecond)
+	defer stop()
+
+	id := agent.Fingerprint([]byte("wrapped key"))
+	agent.Put(id, []byte("unwrapped key"))
+	time.Sleep(20 * time.Millisecond)
+	if _, found := agent.Get(id); found {
+		t.Fatal("Found an expired key")
+	}
+}
+
+func TestAgentUnavailable(t *testing.T) {
+	os.Setenv(agent.SocketEnvVar, "")
+	defer os.Unsetenv(agent.SocketEnvVar)
+
+	agent.Put("id", []byte("key"))
+	if _, found := agent.Get("id"); found {
+		t.Fatal("Found a key with the agent disabled")
+	}
+	if err := agent.Lock(); err == nil {
+		t.Fatal("Locked a disabled agent")
+	}
+}
+
+func TestAgentRefusesSharedDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := agent.Listen(filepath.Join(dir, "gcy-agent.sock")); err == nil {
+		t.Fatal("Listened in a directory shared with other users")
+	}
+}
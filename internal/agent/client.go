@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// how long to wait for an agent before prompting like it wasn't there
+var dialTimeout = 500 * time.Millisecond
+
+// Fingerprint returns an identifier for a wrapped key
+func Fingerprint(parts ...[]byte) string {
+	hasher := sha256.New()
+	for _, part := range parts {
+		_, _ = hasher.Write(part)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil))
+}
+
+// Get asks the agent for the key with `id`, if an agent is running
+func Get(id string) (value []byte, found bool) {
+	res, err := call(&request{Op: opGet, ID: id})
+	if err != nil {
+		log.Debugf("Agent unavailable: %s", err)
+		return nil, false
+	}
+
+	return res.Value, res.Found
+}
+
+// Put hands the agent a key with `id`, if an agent is running
+func Put(id string, value []byte) {
+	if _, err := call(&request{Op: opPut, ID: id, Value: value}); err != nil {
+		log.Debugf("Agent unavailable: %s", err)
+	}
+}
+
+// Lock makes a running agent forget all of its keys
+func Lock() error {
+	_, err := call(&request{Op: opLock})
+	return err
+}
+
+func call(req *request) (res *response, err error) {
+	path := SocketPath()
+	if path == "" {
+		return nil, errors.New("agent disabled")
+	}
+
+	// don't hand keys to sockets other users could have put in place
+	if err = checkOwnership(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	res = &response{}
+	if err = json.NewDecoder(conn).Decode(res); err != nil {
+		return nil, err
+	}
+
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+
+	return res, nil
+}
+
+func checkOwnership(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("Agent directory %s is not owned by the current user", dir)
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("Agent directory %s is accessible by other users", dir)
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package agent
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestExpiredKeysAreForgotten(t *testing.T) {
+	agent := New(10 * time.Millisecond)
+	value := []byte("unwrapped key")
+	agent.put("id", value)
+	time.Sleep(50 * time.Millisecond)
+
+	// without asking for the key again
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	if len(agent.keys) != 0 {
+		t.Fatalf("Kept expired keys: %v", agent.keys)
+	}
+	if !bytes.Equal(value, make([]byte, len(value))) {
+		t.Fatalf("Did not wipe an expired key: %s", value)
+	}
+}
@@ -0,0 +1,218 @@
+// Package fakekms is a tiny stand-in for the AWS KMS API, for tests that exercise the real AWS SDK
+//
+// It speaks enough of the KMS JSON protocol for go-config-yourself: Encrypt, Decrypt, ListAliases and DescribeKey, plus STS's
+// AssumeRole. It does not encrypt anything, ciphertexts are the plaintext, key id and encryption context serialized as JSON.
+package fakekms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// GoodAccessKey is the only access key id the server accepts
+	GoodAccessKey = "AGOODACCESSKEYID"
+	// Account owns every key the server knows about
+	Account = "000000000000"
+	// DeniedAccount owns keys and roles the server denies access to
+	DeniedAccount = "111111111111"
+	// RoleAccount owns keys only accessible after assuming one of its roles
+	RoleAccount = "222222222222"
+	// RoleAccessKey is the access key id handed out when assuming roles
+	RoleAccessKey = "ANASSUMEDROLEKEYID"
+	// ExternalID must be passed when assuming roles named `external`
+	ExternalID = "gcy-external-id"
+	// OptInRegion rejects every request like regions not enabled for the account do
+	OptInRegion = "ap-east-1"
+	// DeniedRegion denies listing keys
+	DeniedRegion = "eu-south-1"
+	// UnavailableRegion never answers, until clients give up
+	UnavailableRegion = "eu-west-3"
+	// TestKeyID is the id of the enabled key every region has, aliased as `alias/gcy-test`
+	TestKeyID = "00000000-0000-0000-0000-000000000000"
+	// DisabledKeyID is the id of the disabled key every region has, aliased as `alias/gcy-old`
+	DisabledKeyID = "11111111-1111-1111-1111-111111111111"
+)
+
+var credentialPattern = regexp.MustCompile(`Credential=([^/]+)/[^/]+/([^/]+)/(kms|sts)/`)
+
+type ciphertext struct {
+	KeyID     string            `json:"k"`
+	Plaintext []byte            `json:"p"`
+	Context   map[string]string `json:"c,omitempty"`
+}
+
+type alias struct {
+	AliasArn    string
+	AliasName   string
+	TargetKeyId string `json:",omitempty"` // nolint:golint
+}
+
+// Start runs a fake KMS server on a random local port, close it when done
+func Start() *httptest.Server {
+	return httptest.NewServer(Handler())
+}
+
+// Handler replies to KMS requests
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := func(status int, data interface{}) {
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(data)
+		}
+		fail := func(code string, format string, args ...interface{}) {
+			reply(400, map[string]string{"__type": code, "message": fmt.Sprintf(format, args...)})
+		}
+
+		credential := credentialPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+		if len(credential) != 4 || (credential[1] != GoodAccessKey && credential[1] != RoleAccessKey) {
+			fail("UnrecognizedClientException", "The security token included in the request is invalid.")
+			return
+		}
+		accessKey, region := credential[1], credential[2]
+		if region == UnavailableRegion {
+			// the request context is only canceled once the body is read
+			_, _ = io.Copy(ioutil.Discard, r.Body)
+			<-r.Context().Done()
+			return
+		}
+		if region == OptInRegion {
+			fail("UnrecognizedClientException", "The security token included in the request is invalid.")
+			return
+		}
+
+		if credential[3] == "sts" {
+			assumeRole(w, r, accessKey)
+			return
+		}
+
+		body := struct {
+			KeyID             string `json:"KeyId"`
+			Plaintext         []byte
+			CiphertextBlob    []byte
+			EncryptionContext map[string]string
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fail("SerializationException", "Could not parse request: %s", err)
+			return
+		}
+
+		switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "TrentService.") {
+		case "Encrypt":
+			if err := keyError(body.KeyID, region, accessKey); err != "" {
+				fail(err, "Access to key %s was denied", body.KeyID)
+				return
+			}
+			blob, _ := json.Marshal(&ciphertext{KeyID: body.KeyID, Plaintext: body.Plaintext, Context: body.EncryptionContext})
+			reply(200, map[string]interface{}{"CiphertextBlob": blob, "KeyId": body.KeyID})
+		case "Decrypt":
+			ct := &ciphertext{}
+			// like kms, a different encryption context makes for an invalid ciphertext
+			if err := json.Unmarshal(body.CiphertextBlob, ct); err != nil || !reflect.DeepEqual(ct.Context, body.EncryptionContext) {
+				fail("InvalidCiphertextException", "")
+				return
+			}
+			keyID := ct.KeyID
+			if pieces := strings.Split(keyID, ":"); len(pieces) == 6 && strings.HasPrefix(pieces[5], "key/mrk-") {
+				// every region holds a replica of multi-region keys, with the same id
+				pieces[3] = region
+				keyID = strings.Join(pieces, ":")
+			}
+			if err := keyError(keyID, region, accessKey); err != "" {
+				fail(err, "Access to key %s was denied", keyID)
+				return
+			}
+			reply(200, map[string]interface{}{"Plaintext": ct.Plaintext, "KeyId": keyID})
+		case "ListAliases":
+			if region == DeniedRegion {
+				fail("AccessDeniedException", "Not authorized to list aliases")
+				return
+			}
+			keyARN := func(id string) string { return fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", region, Account, id) }
+			aliasARN := func(name string) string { return fmt.Sprintf("arn:aws:kms:%s:%s:%s", region, Account, name) }
+			reply(200, map[string]interface{}{
+				"Truncated": false,
+				"Aliases": []alias{
+					{aliasARN("alias/gcy-test"), "alias/gcy-test", keyARN(TestKeyID)},
+					{aliasARN("alias/gcy-old"), "alias/gcy-old", keyARN(DisabledKeyID)},
+					{aliasARN("alias/aws/ebs"), "alias/aws/ebs", keyARN(TestKeyID)},
+					// aliases AWS reserves don't point to keys until used
+					{aliasARN("alias/aws/rds"), "alias/aws/rds", ""},
+				},
+			})
+		case "DescribeKey":
+			if err := keyError(body.KeyID, region, accessKey); err != "" {
+				fail(err, "Access to key %s was denied", body.KeyID)
+				return
+			}
+			metadata := map[string]interface{}{
+				"Arn":         body.KeyID,
+				"KeyId":       body.KeyID[strings.LastIndex(body.KeyID, "/")+1:],
+				"Description": "gcy test key",
+				"Enabled":     true,
+				"KeyState":    "Enabled",
+			}
+			if strings.HasSuffix(body.KeyID, DisabledKeyID) {
+				metadata["Description"] = "retired gcy test key"
+				metadata["Enabled"] = false
+				metadata["KeyState"] = "Disabled"
+			}
+			reply(200, map[string]interface{}{"KeyMetadata": metadata})
+		default:
+			fail("UnknownOperationException", "Unsupported operation %s", r.Header.Get("X-Amz-Target"))
+		}
+	})
+}
+
+// keyError returns the error code for using `keyID` from `region` with `accessKey`, if any
+func keyError(keyID string, region string, accessKey string) string {
+	// arn:aws:kms:REGION:ACCOUNT:ID
+	pieces := strings.Split(keyID, ":")
+	switch {
+	case len(pieces) != 6 || pieces[3] != region:
+		return "NotFoundException"
+	case pieces[4] == DeniedAccount, pieces[4] == RoleAccount && accessKey != RoleAccessKey:
+		return "AccessDeniedException"
+	case pieces[4] == RoleAccount:
+		return ""
+	case pieces[4] != Account:
+		return "NotFoundException"
+	}
+
+	return ""
+}
+
+// assumeRole replies to STS's AssumeRole, handing out RoleAccessKey for roles outside DeniedAccount
+func assumeRole(w http.ResponseWriter, r *http.Request, accessKey string) {
+	_ = r.ParseForm()
+	w.Header().Set("Content-Type", "text/xml")
+	fail := func(status int, code string, message string) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error><RequestId>fake</RequestId></ErrorResponse>", code, message)
+	}
+
+	role := r.Form.Get("RoleArn")
+	switch {
+	case r.Form.Get("Action") != "AssumeRole":
+		fail(400, "InvalidAction", "Unsupported action")
+	case accessKey != GoodAccessKey, strings.Contains(role, ":"+DeniedAccount+":"):
+		fail(403, "AccessDenied", fmt.Sprintf("Not authorized to perform sts:AssumeRole on %s", role))
+	case strings.HasSuffix(role, ":role/external") && r.Form.Get("ExternalId") != ExternalID:
+		fail(403, "AccessDenied", fmt.Sprintf("Not authorized to perform sts:AssumeRole on %s without its external id", role))
+	default:
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult>
+<Credentials><AccessKeyId>%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>%s</Expiration></Credentials>
+<AssumedRoleUser><Arn>%s/%s</Arn><AssumedRoleId>AROAFAKE:%s</AssumedRoleId></AssumedRoleUser>
+</AssumeRoleResult><ResponseMetadata><RequestId>fake</RequestId></ResponseMetadata></AssumeRoleResponse>`,
+			RoleAccessKey, time.Now().Add(time.Hour).UTC().Format(time.RFC3339), role, r.Form.Get("RoleSessionName"), r.Form.Get("RoleSessionName"))
+	}
+}
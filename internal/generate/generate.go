@@ -0,0 +1,78 @@
+// Package generate creates random values for secrets
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/internal/datakey"
+)
+
+// DefaultCharset is used when no charset is chosen
+const DefaultCharset = "alnum"
+
+// DefaultLength is the number of characters in generated values, unless chosen otherwise
+const DefaultLength = 32
+
+// DefaultWords is the number of words in generated passphrases, unless chosen otherwise
+const DefaultWords = 8
+
+// the characters to pick from, by charset name
+var alphabets = map[string]string{
+	"alnum":  "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"hex":    "0123456789abcdef",
+	"base64": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/",
+}
+
+// Charsets lists the names of the charsets values can be generated with
+var Charsets = []string{"alnum", "hex", "base64", "words"}
+
+// Generate returns a random value of `length` characters picked from `charset`, or `length` words joined by dashes
+// if `charset` is `words`
+func Generate(charset string, length int) ([]byte, error) {
+	if length < 1 {
+		return nil, fmt.Errorf("Cannot generate a value with length %d", length)
+	}
+
+	if charset == "words" {
+		picked := make([]string, length)
+		for index := range picked {
+			pick, err := randomIndex(len(words))
+			if err != nil {
+				return nil, err
+			}
+			picked[index] = words[pick]
+		}
+		return []byte(strings.Join(picked, "-")), nil
+	}
+
+	alphabet, known := alphabets[charset]
+	if !known {
+		return nil, fmt.Errorf("Unknown charset <%s>, use one of %s", charset, strings.Join(Charsets, ", "))
+	}
+
+	value := make([]byte, length)
+	for index := range value {
+		pick, err := randomIndex(len(alphabet))
+		if err != nil {
+			return nil, err
+		}
+		value[index] = alphabet[pick]
+	}
+	return value, nil
+}
+
+// randomIndex returns a uniformly random number in [0, size), for sizes up to 65536
+func randomIndex(size int) (int, error) {
+	// discard numbers past the last multiple of size, which would make lower ones more likely
+	limit := 65536 - 65536%size
+	random := make([]byte, 2)
+	for {
+		if err := datakey.RandomBytes(&random); err != nil {
+			return 0, err
+		}
+		if number := int(random[0])<<8 | int(random[1]); number < limit {
+			return number % size, nil
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package generate
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	cases := []struct {
+		charset string
+		length  int
+		format  *regexp.Regexp
+	}{
+		{"alnum", 32, regexp.MustCompile(`^[0-9A-Za-z]{32}$`)},
+		{"hex", 64, regexp.MustCompile(`^[0-9a-f]{64}$`)},
+		{"base64", 20, regexp.MustCompile(`^[0-9A-Za-z+/]{20}$`)},
+		{"words", 4, regexp.MustCompile(`^[a-z]+(-[a-z]+){3}$`)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.charset, func(t *testing.T) {
+			value, err := Generate(c.charset, c.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !c.format.Match(value) {
+				t.Fatalf("Generated a value in the wrong format: %s", value)
+			}
+
+			other, _ := Generate(c.charset, c.length)
+			if string(value) == string(other) {
+				t.Fatalf("Generated the same value twice: %s", value)
+			}
+		})
+	}
+
+	if _, err := Generate("emoji", 10); err == nil || !strings.Contains(err.Error(), "Unknown charset") {
+		t.Fatalf("Generated a value with an unknown charset: %v", err)
+	}
+	if _, err := Generate("alnum", 0); err == nil {
+		t.Fatal("Generated an empty value")
+	}
+}
+
+func TestWords(t *testing.T) {
+	if len(words) != 1024 {
+		t.Fatalf("Expected 1024 words, found %d", len(words))
+	}
+
+	seen := map[string]bool{}
+	for _, word := range words {
+		if seen[word] {
+			t.Fatalf("Found %s twice", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestRandomIndex(t *testing.T) {
+	counts := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		index, err := randomIndex(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[index]++
+	}
+
+	for index, count := range counts {
+		if count < 800 || count > 1200 {
+			t.Fatalf("Picked %d unevenly, %d times out of 3000", index, count)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package generate
+
+// words are picked from for passphrases. There are 1024 of them, so each word adds 10 bits of entropy
+var words = []string{
+	"able", "acid", "acorn", "acre", "actor", "adobe", "adult", "advice", "agent", "aisle", "alarm", "album", "alert",
+	"alley", "alloy", "almond", "alpha", "amber", "anchor", "angle", "animal", "ankle", "answer", "anthem", "apex",
+	"apple", "april", "apron", "arcade", "arch", "arena", "argue", "armor", "arrow", "artist", "ash", "aspen", "atlas",
+	"atom", "attic", "audio", "august", "aunt", "autumn", "avocado", "award", "axis", "baby", "bacon", "badge", "bagel",
+	"baker", "bakery", "balance", "ball", "ballet", "bamboo", "banana", "band", "banjo", "bank", "banner", "barge",
+	"barley", "barn", "barrel", "basalt", "basil", "basin", "basket", "bath", "bay", "beach", "beacon", "bead",
+	"beagle", "beam", "bean", "bear", "beaver", "bed", "beef", "beetle", "bell", "belt", "bench", "berry", "bicycle",
+	"bird", "biscuit", "bishop", "bison", "blade", "blanket", "blaze", "blend", "blimp", "blossom", "blue", "blues",
+	"board", "boat", "bobcat", "body", "bolt", "bone", "bonnet", "bonus", "book", "boot", "border", "bottle", "boulder",
+	"bounty", "bow", "bowl", "bowler", "box", "boxer", "brain", "bran", "branch", "brass", "breach", "bread", "breath",
+	"breeze", "brick", "bridge", "bridle", "brine", "bronze", "brook", "broom", "brother", "brush", "bubble", "bucket",
+	"buffalo", "bugle", "bulb", "bundle", "bunny", "buoy", "bureau", "burger", "burrow", "butter", "button", "cabbage",
+	"cabin", "cable", "cactus", "caddy", "cadet", "cake", "calm", "camel", "camera", "camp", "camper", "canal",
+	"candle", "candy", "cannon", "canoe", "canopy", "canvas", "canyon", "cape", "captain", "car", "carbon", "card",
+	"cargo", "caribou", "carnival", "carpet", "carrot", "cart", "cashew", "casino", "castle", "cat", "catfish",
+	"cattle", "cave", "cavern", "cedar", "celery", "cellar", "cement", "cereal", "chain", "chair", "chalk", "chapel",
+	"charm", "cheese", "cheetah", "cherry", "chess", "chest", "chicken", "chief", "child", "chimney", "chin", "chip",
+	"choir", "chorus", "cider", "cinema", "circle", "citrus", "city", "clam", "class", "clay", "clerk", "cliff",
+	"climb", "clock", "cloud", "clover", "clown", "club", "coach", "coast", "coat", "cobalt", "cobra", "cocoa",
+	"coconut", "cod", "coffee", "coin", "collar", "comet", "comic", "compass", "condor", "cone", "cookbook", "cookie",
+	"cooper", "copper", "coral", "cord", "corn", "corner", "corral", "cosmos", "cottage", "cotton", "couch", "cougar",
+	"country", "cousin", "cover", "cow", "coyote", "crab", "cradle", "crane", "crate", "crater", "crayon", "cream",
+	"creek", "crest", "cricket", "crisp", "crow", "crown", "cruise", "crumb", "crystal", "cube", "cuckoo", "cumin",
+	"cup", "cupboard", "curry", "curtain", "cushion", "cycle", "dagger", "dahlia", "dairy", "daisy", "dance", "dart",
+	"dawn", "deck", "deer", "delta", "denim", "depot", "desert", "desk", "dew", "dial", "diamond", "diary", "dingo",
+	"dinner", "diploma", "disco", "dish", "ditch", "diver", "dock", "doctor", "dog", "doll", "dolphin", "domain",
+	"domino", "donkey", "door", "dough", "dove", "dragon", "drama", "drawer", "dream", "dress", "drift", "drill",
+	"drum", "duck", "dune", "dust", "dynamo", "eagle", "earring", "earth", "easel", "easter", "echo", "eclipse", "eel",
+	"egg", "elbow", "elder", "elephant", "elevator", "elk", "elm", "ember", "emblem", "emerald", "empire", "engine",
+	"envoy", "equal", "eraser", "espresso", "essay", "evening", "exit", "fable", "fabric", "face", "factory", "fairy",
+	"falcon", "falls", "family", "fan", "farm", "fawn", "feast", "feather", "fedora", "fence", "fern", "ferret",
+	"ferry", "festival", "fiber", "fiddle", "field", "fiesta", "fig", "film", "finch", "finger", "fire", "fish",
+	"fjord", "flag", "flame", "flamingo", "flannel", "flash", "flask", "fleet", "flight", "flint", "flipper", "flock",
+	"floor", "flour", "flower", "flute", "foam", "fog", "folder", "folk", "forest", "forge", "fork", "fortune",
+	"fossil", "fountain", "fox", "frame", "freckle", "freedom", "fridge", "frog", "frost", "fruit", "fudge", "fuel",
+	"furnace", "gadget", "gala", "galaxy", "galleon", "garage", "garden", "garlic", "gate", "gazelle", "gear", "gecko",
+	"gem", "geyser", "ghost", "giant", "gibbon", "ginger", "giraffe", "glacier", "glass", "glider", "globe", "glove",
+	"goat", "goblet", "gold", "golf", "gondola", "goose", "gopher", "gorilla", "gospel", "gourd", "grain", "granite",
+	"grape", "graph", "grass", "gravel", "gravity", "gravy", "green", "grid", "griffin", "grill", "grotto", "group",
+	"grove", "guard", "guest", "guitar", "gull", "gumbo", "guppy", "gust", "habit", "hail", "halibut", "hall", "halo",
+	"hammer", "hammock", "hamster", "hand", "harbor", "harness", "harp", "harvest", "hat", "hatch", "haven", "hawk",
+	"hazel", "heart", "hearth", "hedge", "helium", "helmet", "herb", "hermit", "hero", "heron", "hickory", "hill",
+	"hinge", "hippo", "hobby", "hockey", "holly", "honey", "honeybee", "hood", "hook", "horizon", "horn", "hornet",
+	"horse", "hostel", "hotel", "house", "hub", "hull", "humor", "hunter", "husky", "hut", "hydrant", "ice", "iceberg",
+	"icon", "idea", "igloo", "iguana", "image", "impact", "inch", "index", "ink", "inlet", "insect", "iris", "iron",
+	"island", "ivory", "ivy", "jackal", "jacket", "jaguar", "jam", "jar", "jasmine", "jaw", "jeans", "jelly", "jersey",
+	"jet", "jewel", "jigsaw", "job", "jockey", "jogger", "joke", "journal", "journey", "judge", "juice", "jungle",
+	"juniper", "jury", "kayak", "kernel", "kettle", "key", "kidney", "king", "kiosk", "kit", "kitchen", "kite",
+	"kitten", "kiwi", "knee", "knife", "knot", "koala", "label", "ladder", "lady", "lagoon", "lake", "lamb", "lamp",
+	"lantern", "laptop", "laser", "lava", "lawn", "layer", "leaf", "leather", "lemon", "lens", "leopard", "letter",
+	"lettuce", "lever", "library", "lid", "light", "lily", "lime", "linen", "lion", "lizard", "llama", "lobster",
+	"lock", "locust", "lodge", "log", "lotus", "lumber", "lunar", "lunch", "lynx", "machine", "magnet", "magpie",
+	"mail", "mango", "manor", "maple", "marble", "march", "market", "mask", "meadow", "medal", "melon", "memory",
+	"menu", "mercury", "mesa", "metal", "meteor", "mill", "mineral", "mint", "mirror", "mist", "mitten", "model",
+	"monkey", "moon", "moose", "mosaic", "moss", "motor", "mouse", "mouth", "movie", "mud", "muffin", "mule", "museum",
+	"music", "mustard", "nail", "napkin", "nature", "nectar", "needle", "nest", "net", "nickel", "night", "noble",
+	"noodle", "north", "nose", "note", "novel", "number", "nurse", "nut", "oak", "oar", "oasis", "ocean", "octopus",
+	"office", "olive", "omelet", "onion", "opal", "opera", "orange", "orbit", "orchard", "orchid", "organ", "otter",
+	"oven", "owl", "oyster", "paddle", "page", "paint", "palace", "palm", "pan", "panda", "panel", "panther", "paper",
+	"parade", "parrot", "party", "pasta", "path", "peach", "peanut", "pear", "pearl", "pebble", "pecan", "pedal",
+	"pelican", "pen", "pencil", "pepper", "piano", "picnic", "pie", "pier", "pig", "pigeon", "pillow", "pilot", "pine",
+	"pipe", "pirate", "pizza", "planet", "plank", "plant", "plate", "plaza", "plum", "pocket", "poem", "point", "polar",
+	"pond", "pony", "poppy", "porch", "potato", "pottery", "pouch", "powder", "prairie", "prism", "prize", "pulse",
+	"pumpkin", "puppy", "puzzle", "pyramid", "quail", "quartz", "queen", "quest", "quill", "quilt", "quiz", "rabbit",
+	"raccoon", "radar", "radio", "raft", "rail", "rain", "rainbow", "raisin", "ranch", "raven", "razor", "reef",
+	"relay", "ribbon", "rice", "ridge", "ring", "river", "road", "robin", "robot", "rock", "rocket", "roof", "room",
+	"root", "rope", "rose", "rover", "ruby", "rug", "ruler", "saddle", "safari", "sail", "salad", "salmon", "salt",
+	"sand", "sandal", "satin", "sauce", "sausage", "scale", "scarf", "school", "scooter", "scout", "screen", "sea",
+	"seal", "season", "seed", "shadow", "shark", "sheep", "shelf", "shell", "shield", "ship", "shirt", "shoe", "shore",
+	"shovel", "shrimp", "silk", "silver", "singer", "sink", "siren", "sketch", "ski", "skirt", "sky", "slate", "sled",
+	"slope", "snail", "snake", "snow", "soap", "soccer", "sock", "sofa", "soil", "solar", "sonnet", "soup", "spade",
+	"spark", "sparrow", "spice", "spider", "spinach", "spire", "sponge", "spoon", "spring", "spruce", "square", "squid",
+	"stable", "stage", "stamp", "star", "station", "statue", "steam", "steel", "stem", "stereo", "stick", "stone",
+	"stool", "storm", "stove", "straw", "stream", "street", "string", "studio", "sugar", "suit", "summer", "summit",
+	"sun", "sunset", "swamp", "swan", "sweater", "swing", "sword", "syrup", "table", "tablet", "tail", "tango", "tank",
+	"tape", "target", "tea", "teacher", "teapot", "temple", "tennis", "tent", "thistle", "thread", "throne", "thumb",
+	"thunder", "ticket", "tiger", "timber", "toast", "toe", "tomato", "tongue", "tool", "tooth", "torch", "tortoise",
+	"towel", "tower", "town", "toy", "track", "tractor", "trail", "train", "tree", "trophy", "trout", "truck",
+	"trumpet", "trunk", "tulip", "tuna", "tunnel", "turkey", "turtle", "tuxedo", "twig", "umbrella", "uncle", "unicorn",
+	"unit", "valley", "van", "vase", "vault", "velvet", "vessel", "vest", "video", "village", "vine", "violet",
+	"violin", "visitor", "voice", "volcano", "voyage", "wagon", "waiter", "walnut", "walrus", "wand", "water", "wave",
+	"wax", "weasel", "weather", "wheat", "wheel", "whistle", "willow", "window", "wing", "winter", "wire", "wizard",
+	"wolf", "wood", "wool", "world", "worm", "wrench", "yacht", "yak", "yard", "yarn", "year", "yogurt", "zebra",
+	"zero", "zinc", "zipper", "zone",
+}
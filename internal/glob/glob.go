@@ -0,0 +1,36 @@
+// Package glob matches slash-delimited paths against patterns where `**` matches any number of segments
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match tells whether slash-delimited `name` matches `pattern`, where `**` matches any number of directories
+func Match(pattern string, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == "**" {
+		for skip := 0; skip <= len(name); skip++ {
+			if matchSegments(pattern[1:], name[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+
+	if matched, err := path.Match(pattern[0], name[0]); err != nil || !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
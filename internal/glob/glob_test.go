@@ -0,0 +1,31 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := map[string]map[string]bool{
+		"config/*.yml": {
+			"config/production.yml":     true,
+			"config/dev/production.yml": false,
+			"production.yml":            false,
+		},
+		"config/**/*.yml": {
+			"config/production.yml":           true,
+			"config/dev/production.yml":       true,
+			"config/dev/local/production.yml": true,
+			"other/production.yml":            false,
+		},
+		"**": {
+			"production.yml":        true,
+			"config/production.yml": true,
+		},
+	}
+
+	for pattern, names := range cases {
+		for name, expected := range names {
+			if Match(pattern, name) != expected {
+				t.Errorf("Expected %s to match %s: %v", pattern, name, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package input
+
+import (
+	"os"
+	"strings"
+)
+
+// SecretEnvVars lists environment variables that hold secrets, and must not
+// be handed down to child processes
+var SecretEnvVars = []string{"CONFIG_PASSWORD"}
+
+// SanitizedEnv returns the current environment, minus any SecretEnvVars,
+// for use by processes spawned by gcy
+func SanitizedEnv() (env []string) {
+	for _, pair := range os.Environ() {
+		secret := false
+		for _, name := range SecretEnvVars {
+			if strings.HasPrefix(pair, name+"=") {
+				secret = true
+				break
+			}
+		}
+
+		if !secret {
+			env = append(env, pair)
+		}
+	}
+
+	return
+}
@@ -65,16 +65,44 @@ func ReadSecret(prompt string, maskInput bool) (plainText []byte, err error) {
 	return checkInputSize(plainText, nil)
 }
 
+// Item is a choice offered by SelectionFromItems
+type Item struct {
+	// The value returned when selected
+	Value string
+	// What the user sees instead of Value, if set
+	Label string
+	// Details shown next to the label, like a description or state
+	Details string
+}
+
 // SelectionFromList returns a number of values from `list`
 func SelectionFromList(list []string, prompt string, takeMultiple bool) (output []string, err error) {
+	items := make([]Item, len(list))
+	for index, value := range list {
+		items[index] = Item{Value: value}
+	}
+
+	return SelectionFromItems(items, prompt, takeMultiple)
+}
+
+// SelectionFromItems returns the values of a number of `items`, showing their labels and details
+func SelectionFromItems(items []Item, prompt string, takeMultiple bool) (output []string, err error) {
 
-	if len(list) == 0 {
+	if len(items) == 0 {
 		return nil, errors.New("Unable to make a selection, no items found")
 	}
 
+	choices := make([]Item, len(items))
+	for index, item := range items {
+		if item.Label == "" {
+			item.Label = item.Value
+		}
+		choices[index] = item
+	}
+
 	searcher := func(input string, index int) bool {
-		item := list[index]
-		name := strings.ToLower(item)
+		item := choices[index]
+		name := strings.ToLower(item.Label + " " + item.Details)
 		input = strings.ToLower(input)
 
 		return strings.Contains(name, input)
@@ -82,16 +110,20 @@ func SelectionFromList(list []string, prompt string, takeMultiple bool) (output
 
 	ui := &promptui.Select{
 		Label:    prompt,
-		Items:    list,
+		Items:    choices,
 		Size:     10,
 		Stdin:    os.Stdin,
 		Searcher: searcher,
 		Templates: &promptui.SelectTemplates{
-			Help: "Move: ← ↓ ↑ →, search: /",
+			Label:    "{{ . }}",
+			Active:   promptui.IconSelect + ` {{ .Label | underline }} {{ .Details | faint }}`,
+			Inactive: `  {{ .Label }} {{ .Details | faint }}`,
+			Selected: promptui.IconGood + ` {{ .Label }}`,
+			Help:     "Move: ← ↓ ↑ →, search: /",
 		},
 	}
 
-	selected, err := runUI(ui)
+	selected, err := runUI(ui, &choices, false)
 
 	if err != nil && err != io.EOF {
 		return output, fmt.Errorf("Prompt failed: %q", err)
@@ -100,11 +132,11 @@ func SelectionFromList(list []string, prompt string, takeMultiple bool) (output
 	output = append(output, selected)
 
 	if takeMultiple {
-		items := ui.Items.([]string)
-		ui.Items = append([]string{"Done"}, items...)
+		choices = append([]Item{{Label: "Done"}}, choices...)
+		ui.Items = choices
 		ui.Label = ui.Label.(string) + ", or <Done> to continue"
 		for {
-			selected, err := runUI(ui)
+			selected, err := runUI(ui, &choices, true)
 			if err == promptui.ErrInterrupt {
 				return []string{}, fmt.Errorf("Cancelled selection")
 			}
@@ -120,25 +152,23 @@ func SelectionFromList(list []string, prompt string, takeMultiple bool) (output
 
 }
 
-func runUI(ui *promptui.Select) (out string, err error) {
-	i, selected, err := ui.Run()
+// runUI prompts for one of `choices`, and removes it from the ones offered next
+func runUI(ui *promptui.Select, choices *[]Item, offersDone bool) (out string, err error) {
+	i, _, err := ui.Run()
 
 	if err != nil {
 		return
 	}
 
-	if i == 0 && selected == "Done" {
+	if offersDone && i == 0 {
 		return "", promptui.ErrEOF
 	}
 
-	items := ui.Items.([]string)
-	if i == len(out)-1 {
-		ui.Items = items[:i]
-	} else {
-		newItems := items[:i]
-		newItems = append(newItems, items[i+1:]...)
-		ui.Items = newItems
-	}
+	items := *choices
+	out = items[i].Value
+	remaining := append([]Item{}, items[:i]...)
+	*choices = append(remaining, items[i+1:]...)
+	ui.Items = *choices
 
-	return selected, nil
+	return out, nil
 }
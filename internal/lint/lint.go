@@ -0,0 +1,159 @@
+// Package lint compares the keys in config files for each environment with those in their defaults file
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem is a key in an environment's config file that's inconsistent with defaults or other environments
+type Problem struct {
+	// The environment's config file
+	File string
+	// The dot-delimited path to the key
+	KeyPath string
+	// What's wrong with it
+	Message string
+}
+
+func (problem Problem) String() string {
+	return fmt.Sprintf("%s: %s %s", problem.File, problem.KeyPath, problem.Message)
+}
+
+// Check compares the value types of each environment's config file, by file name, with those of the defaults file
+// named `defaultsName`, as listed by ConfigFile.ListTypes
+//
+// Keys with a `null` placeholder in defaults must be defined by every environment, other keys in defaults may be
+// overridden with values of the same type, and environments may not define keys missing from defaults. Keys must
+// also be encrypted in every environment that defines them, or in none
+func Check(defaultsName string, defaults map[string]string, environments map[string]map[string]string) (problems []Problem) {
+	names := []string{}
+	for name := range environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		problems = append(problems, checkEnvironment(name, environments[name], defaultsName, defaults)...)
+	}
+	problems = append(problems, checkEncryption(names, environments)...)
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].KeyPath < problems[j].KeyPath
+	})
+	return problems
+}
+
+func checkEnvironment(name string, types map[string]string, defaultsName string, defaults map[string]string) (problems []Problem) {
+	reported := map[string]bool{}
+	report := func(keyPath string, format string, args ...interface{}) {
+		if !reported[keyPath] {
+			reported[keyPath] = true
+			problems = append(problems, Problem{File: name, KeyPath: keyPath, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	for keyPath, expected := range defaults {
+		actual, defined := types[keyPath]
+		ancestor, hasAncestor := findAncestor(types, keyPath)
+
+		switch {
+		case defined:
+			if !compatible(expected, actual) {
+				report(keyPath, "is %s, but %s in %s", withArticle(actual), withArticle(expected), defaultsName)
+			}
+		case hasAncestor:
+			report(ancestor, "is %s, but a map in %s", withArticle(types[ancestor]), defaultsName)
+		case hasDescendants(types, keyPath):
+			if expected != "null" && expected != "map" {
+				report(keyPath, "is a map, but %s in %s", withArticle(expected), defaultsName)
+			}
+		case expected == "null":
+			report(keyPath, "is missing, %s has a placeholder for it", defaultsName)
+		}
+	}
+
+	for keyPath := range types {
+		_, inDefaults := defaults[keyPath]
+		_, hasAncestor := findAncestor(defaults, keyPath)
+		if !inDefaults && !hasAncestor && !hasDescendants(defaults, keyPath) {
+			report(keyPath, "is not in %s", defaultsName)
+		}
+	}
+
+	return problems
+}
+
+// checkEncryption reports keys stored as plaintext in some environments and encrypted in others
+func checkEncryption(names []string, environments map[string]map[string]string) (problems []Problem) {
+	encryptedIn := map[string][]string{}
+	plaintextIn := map[string][]string{}
+	for _, name := range names {
+		for keyPath, valueType := range environments[name] {
+			switch valueType {
+			case "secret":
+				encryptedIn[keyPath] = append(encryptedIn[keyPath], name)
+			case "null":
+			default:
+				plaintextIn[keyPath] = append(plaintextIn[keyPath], name)
+			}
+		}
+	}
+
+	for keyPath, encrypted := range encryptedIn {
+		for _, name := range plaintextIn[keyPath] {
+			problems = append(problems, Problem{
+				File:    name,
+				KeyPath: keyPath,
+				Message: fmt.Sprintf("is plaintext, but encrypted in %s", strings.Join(encrypted, ", ")),
+			})
+		}
+	}
+	return problems
+}
+
+// compatible tells whether a value of type `actual` may override one of type `expected`. Placeholders may be
+// replaced with anything, and whether values are encrypted is checked across environments instead
+func compatible(expected string, actual string) bool {
+	switch {
+	case expected == actual, expected == "null", actual == "null", expected == "secret", actual == "secret":
+		return true
+	case expected == "number" && actual == "integer", expected == "integer" && actual == "number":
+		return true
+	}
+	return false
+}
+
+// findAncestor returns the closest keypath in `types` that holds the one at `keyPath`
+func findAncestor(types map[string]string, keyPath string) (string, bool) {
+	for index := strings.LastIndex(keyPath, "."); index > 0; index = strings.LastIndex(keyPath[:index], ".") {
+		if _, found := types[keyPath[:index]]; found {
+			return keyPath[:index], true
+		}
+	}
+	return "", false
+}
+
+// hasDescendants tells whether `types` has keypaths within the one at `keyPath`
+func hasDescendants(types map[string]string, keyPath string) bool {
+	for candidate := range types {
+		if strings.HasPrefix(candidate, keyPath+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func withArticle(name string) string {
+	switch name {
+	case "null":
+		return name
+	case "integer":
+		return "an " + name
+	}
+	return "a " + name
+}
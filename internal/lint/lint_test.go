@@ -0,0 +1,70 @@
+package lint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	defaults := map[string]string{
+		"db.host":     "string",
+		"db.password": "null",
+		"db.pool":     "integer",
+		"port":        "integer",
+		"features":    "null",
+		"hosts":       "list",
+		"timeout":     "number",
+	}
+
+	environments := map[string]map[string]string{
+		"production.yml": {
+			"db.host":      "string",
+			"db.password":  "secret",
+			"port":         "integer",
+			"features.new": "boolean",
+			"timeout":      "integer",
+			"api.token":    "secret",
+		},
+		"staging.yml": {
+			"db.password": "string",
+			"db.pool":     "string",
+			"hosts.0":     "string",
+			"features":    "boolean",
+			"api.token":   "string",
+		},
+		"dev.yml": {
+			"db":       "null",
+			"features": "map",
+		},
+	}
+
+	expected := []string{
+		"dev.yml: db is null, but a map in defaults.yml",
+		"production.yml: api.token is not in defaults.yml",
+		"staging.yml: api.token is not in defaults.yml",
+		"staging.yml: api.token is plaintext, but encrypted in production.yml",
+		"staging.yml: db.password is plaintext, but encrypted in production.yml",
+		"staging.yml: db.pool is a string, but an integer in defaults.yml",
+		"staging.yml: hosts is a map, but a list in defaults.yml",
+	}
+
+	found := []string{}
+	for _, problem := range Check("defaults.yml", defaults, environments) {
+		found = append(found, problem.String())
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("Found problems:\n%s\nexpected:\n%s", strings.Join(found, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCheckMissing(t *testing.T) {
+	defaults := map[string]string{"db.password": "null", "db.host": "string"}
+	environments := map[string]map[string]string{"production.yml": {}}
+
+	found := Check("defaults.yml", defaults, environments)
+	expected := []Problem{{File: "production.yml", KeyPath: "db.password", Message: "is missing, defaults.yml has a placeholder for it"}}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("Found problems %v, expected %v", found, expected)
+	}
+}
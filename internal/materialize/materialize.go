@@ -0,0 +1,229 @@
+// Package materialize writes the values of a config file as a tree of files, like mounted secrets in Docker or
+// Kubernetes
+//
+// Every leaf keypath becomes a read-only file, with keypath segments as directories. Files are written to a new
+// directory inside the target, which is then swapped in with a symlink, so readers see either all of the previous
+// values or all of the new ones.
+package materialize
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DataLink names the symlink pointing to the current version of the files
+const DataLink = "..data"
+
+// versionPrefix starts the names of the directories holding each version of the files
+const versionPrefix = "..version-"
+
+// FileMode is the permissions materialized files are written with
+const FileMode os.FileMode = 0400
+
+// Files flattens `value` into file contents by relative path, one per leaf. Strings are written as they are, `nil` as
+// an empty file, lists and empty maps as JSON, and anything else as its plain representation. A leaf `value` is
+// written at `name`, which must be usable as a file name like any key
+func Files(value interface{}, name string) (files map[string][]byte, err error) {
+	if name != "" {
+		if err = checkName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	files = map[string][]byte{}
+	return files, addFiles(files, value, name)
+}
+
+func addFiles(files map[string][]byte, value interface{}, path string) error {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if len(typed) > 0 {
+			for key, child := range typed {
+				if err := checkName(key); err != nil {
+					return err
+				}
+				if err := addFiles(files, child, filepath.Join(path, key)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if path != "" {
+			files[path] = []byte("{}")
+		}
+	case nil:
+		files[path] = []byte{}
+	case string:
+		files[path] = []byte(typed)
+	case []interface{}:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return err
+		}
+		files[path] = encoded
+	default:
+		files[path] = []byte(fmt.Sprintf("%v", typed))
+	}
+	return nil
+}
+
+// checkName makes sure a key can be used as a file name, without escaping the directory or clashing with versions
+func checkName(key string) error {
+	if key == "" || key == "." || strings.HasPrefix(key, "..") || strings.ContainsAny(key, `/\`) {
+		return fmt.Errorf("Cannot write key <%s> as a file", key)
+	}
+	return nil
+}
+
+// Write replaces the files in `dir` with `files` at once, removing files for keys no longer present
+//
+// `dir` is created if needed, and must be empty unless it was written to by Write before. Versions other than the one
+// written are removed, even those left behind when a previous Write stopped halfway
+func Write(dir string, files map[string][]byte) (err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	previous, err := os.Readlink(filepath.Join(dir, DataLink))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if previous == "" {
+		if err = checkEmpty(dir); err != nil {
+			return err
+		}
+	}
+
+	version, err := ioutil.TempDir(dir, versionPrefix)
+	if err != nil {
+		return err
+	}
+	if err = writeVersion(version, files); err != nil {
+		os.RemoveAll(version)
+		return err
+	}
+
+	// renaming a symlink over another one is atomic, unlike replacing a directory
+	link := filepath.Join(dir, DataLink+"-tmp")
+	os.Remove(link)
+	if err = os.Symlink(filepath.Base(version), link); err != nil {
+		os.RemoveAll(version)
+		return err
+	}
+	if err = os.Rename(link, filepath.Join(dir, DataLink)); err != nil {
+		os.Remove(link)
+		os.RemoveAll(version)
+		return err
+	}
+
+	if err = linkEntries(dir, files); err != nil {
+		return err
+	}
+
+	return removeVersions(dir, filepath.Base(version))
+}
+
+// isLeftover tells whether an entry in a directory was created by Write, and may be left behind if it stopped halfway
+func isLeftover(name string) bool {
+	return strings.HasPrefix(name, versionPrefix) || name == DataLink+"-tmp"
+}
+
+// checkEmpty makes sure `dir` has nothing but leftovers from Write
+func checkEmpty(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !isLeftover(entry.Name()) {
+			return fmt.Errorf("Refusing to write to %s, it's not empty", dir)
+		}
+	}
+	return nil
+}
+
+// removeVersions removes every version in `dir` but `current`, including those left behind by Write stopping halfway
+func removeVersions(dir string, current string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, versionPrefix) || name == current {
+			continue
+		}
+		log.Debugf("Removing previous version %s", name)
+		if err = os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeVersion(version string, files map[string][]byte) error {
+	for name, contents := range files {
+		path := filepath.Join(version, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, contents, FileMode); err != nil {
+			return err
+		}
+		if err := os.Chmod(path, FileMode); err != nil {
+			return err
+		}
+	}
+
+	// so others can't tell which keys exist
+	return os.Chmod(version, 0700)
+}
+
+// linkEntries points every top-level entry in `dir` to the current version, and removes entries for deleted keys
+func linkEntries(dir string, files map[string][]byte) error {
+	current := map[string]bool{}
+	for name := range files {
+		current[strings.SplitN(filepath.ToSlash(name), "/", 2)[0]] = true
+	}
+
+	names := []string{}
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		target := filepath.Join(DataLink, name)
+		if existing, err := os.Readlink(path); err == nil && existing == target {
+			continue
+		}
+		os.Remove(path)
+		if err := os.Symlink(target, path); err != nil {
+			return err
+		}
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "..") || current[name] {
+			continue
+		}
+		log.Debugf("Removing %s, its key was deleted", name)
+		if err = os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package materialize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	tree := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "hunter2\n",
+			"port":     5432,
+		},
+		"enabled": true,
+		"hosts":   []interface{}{"a", "b"},
+		"nothing": nil,
+		"empty":   map[string]interface{}{},
+	}
+
+	files, err := Files(tree, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]byte{
+		filepath.Join("db", "password"): []byte("hunter2\n"),
+		filepath.Join("db", "port"):     []byte("5432"),
+		"enabled":                       []byte("true"),
+		"hosts":                         []byte(`["a","b"]`),
+		"nothing":                       {},
+		"empty":                         []byte("{}"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("Flattened wrong files: %v", files)
+	}
+
+	if files, _ = Files("hunter2", "password"); string(files["password"]) != "hunter2" {
+		t.Fatalf("Did not name a single value: %v", files)
+	}
+
+	for _, key := range []string{"..data", "a/b", ".", ""} {
+		if _, err = Files(map[string]interface{}{key: "value"}, ""); err == nil {
+			t.Fatalf("Accepted key <%s>", key)
+		}
+	}
+
+	// a single value is named after the last segment of its keypath
+	for _, name := range []string{"..data", "..version-1", "."} {
+		if _, err = Files("value", name); err == nil {
+			t.Fatalf("Accepted name <%s>", name)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-materialize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = Write(dir, map[string][]byte{
+		filepath.Join("db", "password"): []byte("hunter2"),
+		"token":                         []byte("asdf"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "db", "password"))
+	if err != nil || string(contents) != "hunter2" {
+		t.Fatalf("Did not write db/password: %s, %v", contents, err)
+	}
+	info, _ := os.Stat(filepath.Join(dir, "token"))
+	if info.Mode().Perm() != FileMode {
+		t.Fatalf("Wrote token with mode %s", info.Mode())
+	}
+	first, _ := os.Readlink(filepath.Join(dir, DataLink))
+
+	if err = Write(dir, map[string][]byte{"token": []byte("qwer")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if contents, _ = ioutil.ReadFile(filepath.Join(dir, "token")); string(contents) != "qwer" {
+		t.Fatalf("Did not update token: %s", contents)
+	}
+	if _, err = os.Lstat(filepath.Join(dir, "db")); !os.IsNotExist(err) {
+		t.Fatalf("Did not remove the deleted db key: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, first)); !os.IsNotExist(err) {
+		t.Fatalf("Did not remove the previous version: %v", err)
+	}
+
+	entries, _ := ioutil.ReadDir(dir)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 3 || names[0] != DataLink || !strings.HasPrefix(names[1], "..version-") || names[2] != "token" {
+		t.Fatalf("Left unexpected entries: %v", names)
+	}
+}
+
+func TestWriteNonEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-materialize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "important"), []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Write(dir, map[string][]byte{"token": []byte("asdf")}); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("Wrote to a directory with other files: %v", err)
+	}
+}
+
+func TestWriteLeftovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-materialize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a write that stopped before swapping in its version, and another from before the current one
+	for _, leftover := range []string{"..version-crashed", "..version-stale"} {
+		if err = os.Mkdir(filepath.Join(dir, leftover), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, leftover, "token"), []byte("old"), FileMode); err != nil {
+			t.Fatal(err)
+		}
+		if leftover == "..version-crashed" {
+			if err = Write(dir, map[string][]byte{"token": []byte("asdf")}); err != nil {
+				t.Fatalf("Refused to write over leftovers: %v", err)
+			}
+		}
+	}
+
+	if err = Write(dir, map[string][]byte{"token": []byte("qwer")}); err != nil {
+		t.Fatal(err)
+	}
+
+	current, _ := os.Readlink(filepath.Join(dir, DataLink))
+	entries, _ := ioutil.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..version-") && entry.Name() != current {
+			t.Fatalf("Did not remove version %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package render evaluates Go templates with the values of a config file, decrypting only the secrets they use
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"text/template"
+
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+// Render evaluates the text/template `source`, named `name` in errors, with the values in `config` as data, and
+// writes the result to `out` only if it succeeds
+//
+// Secrets are decrypted when the template prints them or passes them to a function below. Until then they're
+// *file.Secret values, so `if`, `eq` and other comparisons must get them through `secret` or `required` instead.
+// Referencing keys missing from `config` is an error, and the template may call these functions:
+//
+//   - `secret "keypath"` returns the decrypted value at a keypath
+//   - `b64enc` encodes a value as base64
+//   - `toJSON` encodes a value as JSON
+//   - `required "message" value` fails with `message` if `value` is nil or empty, and returns it decrypted otherwise
+func Render(name string, source string, config *file.ConfigFile, out io.Writer) error {
+	data, err := config.GetAllLazily()
+	if err != nil {
+		return err
+	}
+	delete(data, "crypto")
+
+	resolve := func(value interface{}) (interface{}, error) {
+		if secret, isSecret := value.(*file.Secret); isSecret {
+			return secret.Value()
+		}
+		return value, nil
+	}
+
+	funcs := template.FuncMap{
+		"secret": func(keyPath string) (interface{}, error) {
+			return config.Get(keyPath)
+		},
+		"b64enc": func(value interface{}) (string, error) {
+			resolved, err := resolve(value)
+			if err != nil {
+				return "", err
+			}
+			return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%v", resolved))), nil
+		},
+		"toJSON": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+		"required": func(message string, value interface{}) (interface{}, error) {
+			resolved, err := resolve(value)
+			if err != nil {
+				return nil, err
+			}
+			if isEmpty(resolved) {
+				return nil, fmt.Errorf("%s", message)
+			}
+			return resolved, nil
+		},
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(source)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		return err
+	}
+
+	// secrets printed directly can't fail the template, so check them afterwards
+	if err = firstError(data); err != nil {
+		return err
+	}
+
+	_, err = buffer.WriteTo(out)
+	return err
+}
+
+// firstError returns the first error any secret in `value` failed to decrypt with
+func firstError(value interface{}) error {
+	switch typed := value.(type) {
+	case *file.Secret:
+		if err := typed.Err(); err != nil {
+			return fmt.Errorf("Could not decrypt %s: %s", typed.KeyPath, err)
+		}
+	case map[string]interface{}:
+		for _, child := range typed {
+			if err := firstError(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range typed {
+			if err := firstError(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isEmpty tells whether `value` is nil, an empty string, or an empty list or map
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch reflected := reflect.ValueOf(value); reflected.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return reflected.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+// a secret that fails to decrypt, to tell whether it was used
+const undecryptable = `
+broken:
+  ciphertext: bm90IGEgcmVhbCBjaXBoZXJ0ZXh0
+  encrypted: true
+  hash: 00
+brokenList:
+  - ciphertext: bm90IGEgcmVhbCBjaXBoZXJ0ZXh0
+    encrypted: true
+    hash: 00
+`
+
+func loadConfig(t *testing.T) *file.ConfigFile {
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.kms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "gcy-render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	if err = ioutil.WriteFile(path, append(contents, []byte(undecryptable)...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := file.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestRender(t *testing.T) {
+	fx.MockAWS()
+	config := loadConfig(t)
+
+	cases := []struct {
+		template string
+		expected string
+	}{
+		{`{{ .string }} {{ .number }} {{ .object.key }}`, "value 1 value"},
+		{`password={{ .secret }}`, "password=asdf"},
+		{`{{ secret "secret" }}`, "asdf"},
+		{`{{ .secret | b64enc }}`, "YXNkZg=="},
+		{`{{ toJSON .object }} {{ toJSON .list }} {{ toJSON .secret }}`, `{"key":"value"} ["a","b","c"] "asdf"`},
+		{`{{ required "string is required" .string }}`, "value"},
+		{`{{ range .list }}{{ . }},{{ end }}`, "a,b,c,"},
+		{`{{ if eq (secret "secret") "asdf" }}matched{{ end }}`, "matched"},
+		{`{{ eq (required "secret is required" .secret) "asdf" }}`, "true"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.template, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Render("test", c.template, config, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != c.expected {
+				t.Fatalf("Rendered %q, expected %q", out.String(), c.expected)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	fx.MockAWS()
+
+	cases := []struct {
+		template string
+		expected string
+	}{
+		{`{{ .missing }}`, `map has no entry for key "missing"`},
+		{`{{ required "empty is required" .emptyString }}`, "empty is required"},
+		{`{{ required "list is required" (index . "empty-list") }}`, "list is required"},
+		{`{{ secret "nope" }}`, "Could not find a value at nope"},
+		{`{{ .broken }}`, "Could not decrypt broken"},
+		{`{{ .broken | b64enc }}`, "b64enc"},
+		{`{{ index .brokenList 0 }}`, "Could not decrypt brokenList.0"},
+		{`{{ if`, "unclosed action"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.template, func(t *testing.T) {
+			config := loadConfig(t)
+			_ = config.VeryInsecurelySetPlaintext("emptyString", []byte(`""`))
+
+			var out bytes.Buffer
+			err := Render("test", c.template, config, &out)
+			if err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Fatalf("Rendering failed with %v, expected %s", err, c.expected)
+			}
+			if out.Len() > 0 {
+				t.Fatalf("Wrote output for a failed template: %s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package scan finds plaintext values in config files that look like they should be encrypted
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/internal/glob"
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+
+	yml "gopkg.in/yaml.v3"
+)
+
+// Finding is a plaintext value that looks like a secret
+type Finding struct {
+	// The file the value is in
+	File string
+	// The dot-delimited path to the value
+	KeyPath string
+	// Where the value starts in the file
+	Line   int
+	Column int
+	// Why the value looks like a secret
+	Reason string
+	// The plaintext value
+	Value string
+	// Whether the value is inside a list, where values can't be encrypted
+	InList bool
+}
+
+func (finding Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: plaintext value at %s looks like a secret, %s", finding.File, finding.Line, finding.Column, finding.KeyPath, finding.Reason)
+}
+
+// key names that usually hold secrets
+var secretKeyName = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[-_]?key|private[-_]?key|access[-_]?key|credentials?)`)
+
+// the shortest value flagged because of its key name
+const minKeyNameValueLength = 6
+
+// prefixes and formats of well-known credentials
+var knownTokens = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"an AWS access key ID", regexp.MustCompile(`^(AKIA|ASIA)[0-9A-Z]{16}$`)},
+	{"a GitHub token", regexp.MustCompile(`^(gh[pousr]_[0-9A-Za-z]{36,}|github_pat_[0-9A-Za-z_]{22,})$`)},
+	{"a GitLab token", regexp.MustCompile(`^glpat-[0-9A-Za-z_-]{20,}$`)},
+	{"a Slack token", regexp.MustCompile(`^xox[abposr]-[0-9A-Za-z-]{10,}$`)},
+	{"a Stripe key", regexp.MustCompile(`^(sk|rk)_live_[0-9A-Za-z]{16,}$`)},
+	{"a Google API key", regexp.MustCompile(`^AIza[0-9A-Za-z_-]{35}$`)},
+	{"an npm token", regexp.MustCompile(`^npm_[0-9A-Za-z]{36}$`)},
+	{"a JSON web token", regexp.MustCompile(`^eyJ[0-9A-Za-z_-]+\.eyJ[0-9A-Za-z_-]+\.[0-9A-Za-z_-]+$`)},
+	{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"a URL with a password", regexp.MustCompile(`^[a-z][a-z0-9+.-]*://[^/:@\s]+:[^/@\s]+@`)},
+}
+
+// random-looking strings, and how many bits of entropy per character make them suspicious
+var entropyChecks = []struct {
+	charset   *regexp.Regexp
+	threshold float64
+}{
+	{regexp.MustCompile(`^[0-9a-fA-F]{32,}$`), 3.0},
+	{regexp.MustCompile(`^[0-9A-Za-z+/=_-]{20,}$`), 4.5},
+}
+
+// values that reference secrets stored elsewhere, like `${DB_PASSWORD}`
+var reference = regexp.MustCompile(`^\$\{.*\}$|\{\{.*\}\}`)
+
+// File scans the config file at `path`, skipping its `crypto` property, encrypted values and those in `allowed`
+func File(path string, allowed *Allowlist) (findings []Finding, err error) {
+	tree, err := yaml.FromPathname(path)
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil || tree.Node == nil {
+		return nil, nil
+	}
+
+	var walk func(node *yml.Node, keyPath string, key string, inList bool)
+	walk = func(node *yml.Node, keyPath string, key string, inList bool) {
+		switch node.Kind {
+		case yml.MappingNode:
+			subtree := &yaml.Tree{}
+			if err := subtree.UnmarshalYAML(node); err == nil && subtree.IsEncrypted() {
+				return
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				childKey := node.Content[i].Value
+				if keyPath == "" && childKey == "crypto" {
+					continue
+				}
+				walk(node.Content[i+1], joinKeyPath(keyPath, childKey), childKey, inList)
+			}
+		case yml.SequenceNode:
+			for index, child := range node.Content {
+				walk(child, joinKeyPath(keyPath, fmt.Sprintf("%d", index)), key, true)
+			}
+		case yml.ScalarNode:
+			if node.Tag != "!!str" || allowed.Allows(path, keyPath) {
+				return
+			}
+			if reason := Check(key, node.Value); reason != "" {
+				findings = append(findings, Finding{
+					File:    path,
+					KeyPath: keyPath,
+					Line:    node.Line,
+					Column:  node.Column,
+					Reason:  reason,
+					Value:   node.Value,
+					InList:  inList,
+				})
+			}
+		}
+	}
+	walk(tree.Node, "", "", false)
+
+	return findings, nil
+}
+
+// Check tells why a plaintext `value` under `key` looks like a secret, or returns an empty string if it doesn't
+func Check(key string, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || reference.MatchString(value) {
+		return ""
+	}
+
+	for _, token := range knownTokens {
+		if token.pattern.MatchString(value) {
+			return fmt.Sprintf("it matches the format of %s", token.name)
+		}
+	}
+
+	if secretKeyName.MatchString(key) && len(value) >= minKeyNameValueLength && !strings.ContainsAny(value, " \n") {
+		return fmt.Sprintf("its key name <%s> suggests it", key)
+	}
+
+	for _, check := range entropyChecks {
+		if check.charset.MatchString(value) && entropy(value) >= check.threshold {
+			return fmt.Sprintf("it looks random, with %.1f bits of entropy per character", entropy(value))
+		}
+	}
+
+	return ""
+}
+
+// entropy returns the Shannon entropy of `value`, in bits per character
+func entropy(value string) (bits float64) {
+	counts := map[rune]float64{}
+	for _, char := range value {
+		counts[char]++
+	}
+
+	length := float64(len([]rune(value)))
+	for _, count := range counts {
+		frequency := count / length
+		bits -= frequency * math.Log2(frequency)
+	}
+	return bits
+}
+
+func joinKeyPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// Allowlist holds keypaths to skip when scanning, optionally only in some files
+type Allowlist struct {
+	entries []allowed
+}
+
+type allowed struct {
+	// a slash-delimited glob for files, empty for all of them
+	file string
+	// a dot-delimited glob for keypaths, where `**` matches any number of keys
+	keyPath string
+}
+
+// LoadAllowlist reads the allowlist at `path`, where each line is a `KEYPATH` glob, or a `FILE:KEYPATH` pair of globs.
+// Empty lines and those starting with `#` are ignored
+func LoadAllowlist(path string) (*Allowlist, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read allowlist %s: %s", path, err)
+	}
+	defer source.Close()
+
+	list := &Allowlist{}
+	scanner := bufio.NewScanner(source)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := allowed{keyPath: line}
+		if separator := strings.LastIndex(line, ":"); separator >= 0 {
+			entry = allowed{file: line[:separator], keyPath: line[separator+1:]}
+		}
+		list.entries = append(list.entries, entry)
+	}
+
+	return list, scanner.Err()
+}
+
+// Allows tells whether values at `keyPath` in `file` should be skipped
+func (list *Allowlist) Allows(file string, keyPath string) bool {
+	if list == nil {
+		return false
+	}
+
+	slashedKeyPath := strings.Replace(keyPath, ".", "/", -1)
+	for _, entry := range list.entries {
+		if entry.file != "" && !glob.Match(entry.file, filepath.ToSlash(filepath.Clean(file))) {
+			continue
+		}
+		if glob.Match(strings.Replace(entry.keyPath, ".", "/", -1), slashedKeyPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,453 @@
+// Package schema validates config values against the subset of JSON Schema most config files need
+//
+// Schemas may be written in JSON or YAML, and support the `type`, `enum`, `const`, `properties`, `required`,
+// `additionalProperties`, `items`, `minItems`, `maxItems`, `minimum`, `maximum`, `minLength`, `maxLength` and
+// `pattern` keywords, along with annotations like `title` and `description`. Other keywords, like `$ref`, are
+// rejected instead of ignored, so values are never left unchecked by mistake
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+)
+
+// Schema constrains a value and, for objects and arrays, the values within
+type Schema struct {
+	types      []string
+	enum       []interface{}
+	properties map[string]*Schema
+	required   []string
+	// nil allows any additional properties
+	additional    *Schema
+	noAdditional  bool
+	items         *Schema
+	minItems      *float64
+	maxItems      *float64
+	minimum       *float64
+	maximum       *float64
+	minLength     *float64
+	maxLength     *float64
+	pattern       *regexp.Regexp
+	patternSource string
+	constant      interface{}
+	hasConstant   bool
+	unsatisfiable bool
+}
+
+// Violation is a value that breaks a schema
+type Violation struct {
+	// The dot-delimited path to the value, empty for the whole config
+	KeyPath string
+	// How the value breaks the schema
+	Message string
+}
+
+func (violation Violation) String() string {
+	if violation.KeyPath == "" {
+		return fmt.Sprintf("the config %s", violation.Message)
+	}
+	return fmt.Sprintf("%s %s", violation.KeyPath, violation.Message)
+}
+
+// keywords that only describe values
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true, "default": true,
+	"examples": true, "deprecated": true,
+}
+
+// Load reads the schema at `path`, written in JSON or YAML
+func Load(path string) (*Schema, error) {
+	tree, err := yaml.FromPathname(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse schema %s: %s", path, err)
+	}
+
+	var raw interface{}
+	if tree != nil && tree.Node != nil {
+		if err = tree.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("Could not parse schema %s: %s", path, err)
+		}
+	}
+
+	schema, err := parse(raw, "")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid schema %s: %s", path, err)
+	}
+	return schema, nil
+}
+
+// parse reads a schema found at `location` within the schema file
+func parse(raw interface{}, location string) (schema *Schema, err error) {
+	schema = &Schema{}
+	switch typed := raw.(type) {
+	case nil:
+		return schema, nil
+	case bool:
+		schema.unsatisfiable = !typed
+		return schema, nil
+	}
+	keywords, isMap := raw.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("%s must be an object or a boolean", describeLocation(location))
+	}
+
+	names := []string{}
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := keywords[name]
+		at := strings.TrimPrefix(location+"."+name, ".")
+		switch name {
+		case "type":
+			schema.types, err = stringList(value, at)
+		case "enum":
+			values, isList := value.([]interface{})
+			if !isList {
+				return nil, fmt.Errorf("%s must be a list", at)
+			}
+			schema.enum = values
+		case "const":
+			schema.constant, schema.hasConstant = value, true
+		case "properties":
+			properties, isMap := value.(map[string]interface{})
+			if !isMap {
+				return nil, fmt.Errorf("%s must be an object", at)
+			}
+			schema.properties = map[string]*Schema{}
+			for property, raw := range properties {
+				if schema.properties[property], err = parse(raw, at+"."+property); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			schema.required, err = stringList(value, at)
+		case "additionalProperties":
+			if allowed, isBool := value.(bool); isBool {
+				schema.noAdditional = !allowed
+			} else {
+				schema.additional, err = parse(value, at)
+			}
+		case "items":
+			schema.items, err = parse(value, at)
+		case "minItems":
+			schema.minItems, err = number(value, at)
+		case "maxItems":
+			schema.maxItems, err = number(value, at)
+		case "minimum":
+			schema.minimum, err = number(value, at)
+		case "maximum":
+			schema.maximum, err = number(value, at)
+		case "minLength":
+			schema.minLength, err = number(value, at)
+		case "maxLength":
+			schema.maxLength, err = number(value, at)
+		case "pattern":
+			source, isString := value.(string)
+			if !isString {
+				return nil, fmt.Errorf("%s must be a string", at)
+			}
+			schema.patternSource = source
+			if schema.pattern, err = regexp.Compile(source); err != nil {
+				return nil, fmt.Errorf("%s is not a valid regular expression: %s", at, err)
+			}
+		default:
+			if !annotations[name] {
+				return nil, fmt.Errorf("%s is not supported", at)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schema, nil
+}
+
+func describeLocation(location string) string {
+	if location == "" {
+		return "the schema"
+	}
+	return location
+}
+
+func stringList(value interface{}, at string) (strs []string, err error) {
+	if str, isString := value.(string); isString {
+		return []string{str}, nil
+	}
+
+	values, isList := value.([]interface{})
+	if !isList {
+		return nil, fmt.Errorf("%s must be a string or a list of strings", at)
+	}
+	for _, item := range values {
+		str, isString := item.(string)
+		if !isString {
+			return nil, fmt.Errorf("%s must be a string or a list of strings", at)
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+func number(value interface{}, at string) (*float64, error) {
+	if n, isNumber := toFloat(value); isNumber {
+		return &n, nil
+	}
+	return nil, fmt.Errorf("%s must be a number", at)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// Validate checks a whole decrypted config `tree`. Values at the keypaths in `secrets` were decrypted as strings, and
+// are read as JSON when the schema expects something else, the same way `gcy set --plain-text` reads values
+func (schema *Schema) Validate(tree interface{}, secrets []string) []Violation {
+	isSecret := map[string]bool{}
+	for _, keyPath := range secrets {
+		isSecret[keyPath] = true
+	}
+	return schema.validate(tree, "", isSecret)
+}
+
+// ValidateAt checks a single `value` about to be stored at `keyPath`, without checking the rest of the config
+func (schema *Schema) ValidateAt(keyPath string, value interface{}, secret bool) []Violation {
+	current := schema
+	walked := []string{}
+	for _, key := range strings.Split(keyPath, ".") {
+		walked = append(walked, key)
+		next, allowed := current.child(key)
+		if !allowed {
+			return []Violation{{KeyPath: strings.Join(walked, "."), Message: "is not allowed"}}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+
+	return current.validate(value, keyPath, map[string]bool{keyPath: secret})
+}
+
+// child returns the schema for `key` within this one, nil if it's unconstrained, and whether it's allowed at all
+func (schema *Schema) child(key string) (child *Schema, allowed bool) {
+	if property, defined := schema.properties[key]; defined {
+		return property, true
+	}
+	if _, err := strconv.Atoi(key); err == nil && schema.items != nil {
+		return schema.items, true
+	}
+	if schema.noAdditional {
+		return nil, false
+	}
+	return schema.additional, true
+}
+
+func (schema *Schema) validate(value interface{}, keyPath string, secrets map[string]bool) (violations []Violation) {
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{KeyPath: keyPath, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if schema.unsatisfiable {
+		fail("is not allowed")
+		return
+	}
+
+	if str, isString := value.(string); isString && secrets[keyPath] && len(schema.types) > 0 && !contains(schema.types, "string") {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(str), &parsed); err == nil {
+			value = normalize(parsed)
+		}
+	}
+
+	actual := typeOf(value)
+	if len(schema.types) > 0 && !matchesType(schema.types, actual) {
+		fail("must be %s, not %s", describeTypes(schema.types), withArticle(actual))
+		return
+	}
+
+	if schema.hasConstant && !equal(value, schema.constant) {
+		fail("must be %v", schema.constant)
+	}
+	if len(schema.enum) > 0 {
+		found := false
+		for _, option := range schema.enum {
+			found = found || equal(value, option)
+		}
+		if !found {
+			fail("must be one of %v", schema.enum)
+		}
+	}
+
+	switch typed := value.(type) {
+	case string:
+		length := float64(len([]rune(typed)))
+		if schema.minLength != nil && length < *schema.minLength {
+			fail("must be at least %v character(s) long", *schema.minLength)
+		}
+		if schema.maxLength != nil && length > *schema.maxLength {
+			fail("must be at most %v character(s) long", *schema.maxLength)
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(typed) {
+			fail("must match %s", schema.patternSource)
+		}
+	case map[string]interface{}:
+		for _, name := range schema.required {
+			if _, present := typed[name]; !present {
+				violations = append(violations, Violation{KeyPath: join(keyPath, name), Message: "is missing, but required"})
+			}
+		}
+
+		keys := []string{}
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child, allowed := schema.child(key)
+			if !allowed {
+				violations = append(violations, Violation{KeyPath: join(keyPath, key), Message: "is not allowed"})
+				continue
+			}
+			if child != nil {
+				violations = append(violations, child.validate(typed[key], join(keyPath, key), secrets)...)
+			}
+		}
+	case []interface{}:
+		count := float64(len(typed))
+		if schema.minItems != nil && count < *schema.minItems {
+			fail("must have at least %v item(s)", *schema.minItems)
+		}
+		if schema.maxItems != nil && count > *schema.maxItems {
+			fail("must have at most %v item(s)", *schema.maxItems)
+		}
+		if schema.items != nil {
+			for index, item := range typed {
+				violations = append(violations, schema.items.validate(item, join(keyPath, strconv.Itoa(index)), secrets)...)
+			}
+		}
+	default:
+		if n, isNumber := toFloat(value); isNumber {
+			if schema.minimum != nil && n < *schema.minimum {
+				fail("must be at least %v", *schema.minimum)
+			}
+			if schema.maximum != nil && n > *schema.maximum {
+				fail("must be at most %v", *schema.maximum)
+			}
+		}
+	}
+
+	return violations
+}
+
+// normalize turns numbers parsed from JSON into integers when they are whole, like YAML would
+func normalize(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case float64:
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<53 {
+			return int(typed)
+		}
+	case map[string]interface{}:
+		for key, child := range typed {
+			typed[key] = normalize(child)
+		}
+	case []interface{}:
+		for index, child := range typed {
+			typed[index] = normalize(child)
+		}
+	}
+	return value
+}
+
+// typeOf names the JSON Schema type of a decoded YAML `value`
+func typeOf(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case float64:
+		if typed == math.Trunc(typed) && !math.IsInf(typed, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	if _, isNumber := toFloat(value); isNumber {
+		return "integer"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesType(types []string, actual string) bool {
+	return contains(types, actual) || (actual == "integer" && contains(types, "number"))
+}
+
+func describeTypes(types []string) string {
+	described := []string{}
+	for _, name := range types {
+		described = append(described, withArticle(name))
+	}
+	return strings.Join(described, " or ")
+}
+
+func withArticle(name string) string {
+	switch name {
+	case "null":
+		return name
+	case "array", "integer", "object":
+		return "an " + name
+	}
+	return "a " + name
+}
+
+func equal(value interface{}, expected interface{}) bool {
+	if a, isNumber := toFloat(value); isNumber {
+		b, bothNumbers := toFloat(expected)
+		return bothNumbers && a == b
+	}
+	return reflect.DeepEqual(value, expected)
+}
+
+func contains(list []string, item string) bool {
+	for _, candidate := range list {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+func join(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
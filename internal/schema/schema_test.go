@@ -0,0 +1,190 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+title: An app's config
+type: object
+required: [port, db]
+additionalProperties: false
+properties:
+  port:
+    type: integer
+    minimum: 1
+    maximum: 65535
+  env:
+    enum: [dev, production]
+  db:
+    type: object
+    required: [password]
+    properties:
+      host: {type: string, pattern: "^[a-z.]+$"}
+      password: {type: string, minLength: 8}
+      pool: {type: integer}
+  hosts:
+    type: array
+    minItems: 1
+    items: {type: string}
+  features:
+    type: object
+    additionalProperties: {type: boolean}
+`
+
+func loadTestSchema(t *testing.T, contents string) (*Schema, error) {
+	dir, err := ioutil.TempDir("", "gcy-schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "schema.yaml")
+	if err = ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func messages(violations []Violation) []string {
+	strs := []string{}
+	for _, violation := range violations {
+		strs = append(strs, violation.String())
+	}
+	return strs
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := loadTestSchema(t, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		tree     map[string]interface{}
+		secrets  []string
+		expected []string
+	}{
+		{
+			name: "valid",
+			tree: map[string]interface{}{
+				"port":     8080,
+				"env":      "dev",
+				"db":       map[string]interface{}{"host": "db.local", "password": "hunter22"},
+				"hosts":    []interface{}{"a", "b"},
+				"features": map[string]interface{}{"beta": true},
+			},
+			expected: []string{},
+		},
+		{
+			name: "invalid",
+			tree: map[string]interface{}{
+				"port":     "abc",
+				"env":      "staging",
+				"db":       map[string]interface{}{"host": "DB", "pool": 1.5},
+				"hosts":    []interface{}{},
+				"features": map[string]interface{}{"beta": "yes"},
+				"extra":    1,
+			},
+			expected: []string{
+				"db.password is missing, but required",
+				"db.host must match ^[a-z.]+$",
+				"db.pool must be an integer, not a number",
+				"env must be one of [dev production]",
+				"extra is not allowed",
+				"features.beta must be a boolean, not a string",
+				"hosts must have at least 1 item(s)",
+				"port must be an integer, not a string",
+			},
+		},
+		{
+			name:     "missing",
+			tree:     map[string]interface{}{},
+			expected: []string{"port is missing, but required", "db is missing, but required"},
+		},
+		{
+			name: "secrets are read as JSON",
+			tree: map[string]interface{}{
+				"port": "8080",
+				"db":   map[string]interface{}{"password": "hunter22", "pool": "10"},
+			},
+			secrets:  []string{"port", "db.password", "db.pool"},
+			expected: []string{},
+		},
+		{
+			name: "secrets keep their types",
+			tree: map[string]interface{}{
+				"port": "99999",
+				"db":   map[string]interface{}{"password": "short", "pool": "many"},
+			},
+			secrets: []string{"port", "db.password", "db.pool"},
+			expected: []string{
+				"db.password must be at least 8 character(s) long",
+				"db.pool must be an integer, not a string",
+				"port must be at most 65535",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			found := messages(schema.Validate(c.tree, c.secrets))
+			if !reflect.DeepEqual(found, c.expected) {
+				t.Fatalf("Found violations:\n%s\nexpected:\n%s", strings.Join(found, "\n"), strings.Join(c.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateAt(t *testing.T) {
+	schema, err := loadTestSchema(t, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		keyPath  string
+		value    interface{}
+		secret   bool
+		expected []string
+	}{
+		{"port", 80, false, []string{}},
+		{"port", "abc", false, []string{"port must be an integer, not a string"}},
+		{"port", "443", true, []string{}},
+		{"db.password", "short", true, []string{"db.password must be at least 8 character(s) long"}},
+		{"db.other", "anything", false, []string{}},
+		{"hosts.0", 1, false, []string{"hosts.0 must be a string, not an integer"}},
+		{"features.new", true, false, []string{}},
+		{"extra.nested", "x", false, []string{"extra is not allowed"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.keyPath, func(t *testing.T) {
+			found := messages(schema.ValidateAt(c.keyPath, c.value, c.secret))
+			if !reflect.DeepEqual(found, c.expected) {
+				t.Fatalf("Found violations %v, expected %v", found, c.expected)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cases := map[string]string{
+		`{"type": "object", "$ref": "#/definitions/a"}`: "$ref is not supported",
+		`properties: {port: {minimum: low}}`:            "properties.port.minimum must be a number",
+		`properties: {host: {pattern: "("}}`:            "properties.host.pattern is not a valid regular expression",
+		`- a list`:                                      "the schema must be an object or a boolean",
+	}
+
+	for contents, expected := range cases {
+		if _, err := loadTestSchema(t, contents); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Loading %s failed with %v, expected %s", contents, err, expected)
+		}
+	}
+}
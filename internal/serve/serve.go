@@ -0,0 +1,145 @@
+// Package serve answers HTTP requests for the values of a config file, so programs that can't use go-config-yourself
+// can fetch their config from a sidecar
+//
+// Values are served as JSON at `/v1/keys/<keypath>`, where keypath segments are separated by dots or slashes, and
+// the config file is loaded again whenever it changes.
+package serve
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// KeysPrefix is the path values are served under
+const KeysPrefix = "/v1/keys/"
+
+// Server serves the values of a config file, loading it again when it changes
+type Server struct {
+	token   string
+	watcher *file.Watcher
+}
+
+// New loads the config file at `path` with `options` and returns a Server for it. Requests must carry `token` as a
+// bearer token, unless it's empty
+func New(path string, token string, options ...file.Option) (*Server, error) {
+	watcher, err := file.Watch(path, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case _, open := <-watcher.Updates():
+				if !open {
+					return
+				}
+				log.Infof("Reloaded %s", path)
+			case err := <-watcher.Errors():
+				log.Errorf("%s, serving its last version", err)
+			}
+		}
+	}()
+
+	return &Server{token: token, watcher: watcher}, nil
+}
+
+// Close stops watching the config file for changes
+func (server *Server) Close() error {
+	return server.watcher.Close()
+}
+
+// Listen creates a unix socket at `path` only accessible by the current user, replacing any stale socket left there
+func Listen(path string) (listener net.Listener, err error) {
+	if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+		conn.Close()
+		return nil, fmt.Errorf("Something is already listening at %s", path)
+	}
+	os.Remove(path)
+
+	// sockets get their permissions from the umask, so nobody else can connect to it at any point
+	mask := syscall.Umask(0177)
+	listener, err = net.Listen("unix", path)
+	syscall.Umask(mask)
+	if err != nil {
+		return nil, fmt.Errorf("Could not listen at %s: %s", path, err)
+	}
+
+	return listener, nil
+}
+
+// ServeHTTP answers GET requests for values at `/v1/keys/<keypath>`
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !server.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		reply(w, http.StatusUnauthorized, errorBody("Missing or wrong bearer token"))
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		reply(w, http.StatusMethodNotAllowed, errorBody("Only GET requests are supported"))
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, KeysPrefix) {
+		reply(w, http.StatusNotFound, errorBody(fmt.Sprintf("Values are served at %s<keypath>", KeysPrefix)))
+		return
+	}
+
+	keyPath := strings.Trim(strings.Replace(strings.TrimPrefix(r.URL.Path, KeysPrefix), "/", ".", -1), ".")
+	if keyPath == "" || keyPath == "crypto" || strings.HasPrefix(keyPath, "crypto.") {
+		reply(w, http.StatusNotFound, errorBody(fmt.Sprintf("There is no value at <%s>", keyPath)))
+		return
+	}
+
+	value, err := server.watcher.Current().Get(keyPath)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if _, notFound := err.(yaml.NotFoundError); notFound {
+			status = http.StatusNotFound
+		}
+		log.Debugf("Could not get %s: %s", keyPath, err)
+		reply(w, status, errorBody(err.Error()))
+		return
+	}
+
+	log.Debugf("Served %s", keyPath)
+	reply(w, http.StatusOK, value)
+}
+
+func (server *Server) authorized(r *http.Request) bool {
+	if server.token == "" {
+		return true
+	}
+
+	expected := []byte("Bearer " + server.token)
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) == 1
+}
+
+func errorBody(message string) map[string]string {
+	return map[string]string{"error": message}
+}
+
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	// values are not meant for browsers, so serve them as they are
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		log.Debugf("Could not reply: %s", err)
+	}
+}
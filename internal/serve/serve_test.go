@@ -0,0 +1,176 @@
+package serve
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+)
+
+func copyFixture(t *testing.T, dir string) string {
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.kms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config.yml")
+	if err = ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func get(server *Server, path string, token string) (int, string) {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder.Code, strings.TrimSpace(recorder.Body.String())
+}
+
+func TestServe(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server, err := New(copyFixture(t, dir), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	cases := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/v1/keys/secret", 200, `"asdf"`},
+		{"/v1/keys/object.key", 200, `"value"`},
+		{"/v1/keys/object/key", 200, `"value"`},
+		{"/v1/keys/object", 200, `{"key":"value"}`},
+		{"/v1/keys/list", 200, `["a","b","c"]`},
+		{"/v1/keys/number", 200, `1`},
+		{"/v1/keys/missing", 404, `{"error":"Could not find a value at missing"}`},
+		{"/v1/keys/crypto.key", 404, `{"error":"There is no value at <crypto.key>"}`},
+		{"/v1/keys/", 404, `{"error":"There is no value at <>"}`},
+		{"/secret", 404, `{"error":"Values are served at /v1/keys/<keypath>"}`},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.path, func(t *testing.T) {
+			status, body := get(server, c.path, "")
+			if status != c.status || body != c.expected {
+				t.Fatalf("Got %d %s, expected %d %s", status, body, c.status, c.expected)
+			}
+		})
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server, err := New(copyFixture(t, dir), "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, token := range []string{"", "hunter3"} {
+		if status, _ := get(server, "/v1/keys/secret", token); status != 401 {
+			t.Fatalf("Accepted token <%s>, got %d", token, status)
+		}
+	}
+
+	if status, body := get(server, "/v1/keys/secret", "hunter2"); status != 200 || body != `"asdf"` {
+		t.Fatalf("Rejected the right token, got %d %s", status, body)
+	}
+}
+
+// eventually retries `get` until it answers `expected`, since reloads happen in the background
+func eventually(t *testing.T, server *Server, path string, expected string, message string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, body := get(server, path, "")
+		if status == 200 && body == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s, got %d %s", message, status, body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeReload(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := copyFixture(t, dir)
+	server, err := New(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	contents, _ := ioutil.ReadFile(path)
+	if err = ioutil.WriteFile(path, append(contents, []byte("added: true\n")...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, server, "/v1/keys/added", "true", "Did not reload")
+
+	if err = ioutil.WriteFile(path, []byte("crypto: ["), 0600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	eventually(t, server, "/v1/keys/added", "true", "Did not keep the last good config")
+}
+
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer syscall.Umask(syscall.Umask(0))
+	path := filepath.Join(dir, "gcy.sock")
+	listener, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Created the socket with the wrong permissions: %s", info.Mode())
+	}
+
+	if _, err = Listen(path); err == nil {
+		t.Fatal("Listened at a socket in use")
+	}
+}
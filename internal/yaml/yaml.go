@@ -36,6 +36,15 @@ type encryptedNode struct {
 	Hash       string
 }
 
+// NotFoundError is returned by Get when there is no value at a path
+type NotFoundError struct {
+	Path string
+}
+
+func (err NotFoundError) Error() string {
+	return fmt.Sprintf("Could not find a value at %s", err.Path)
+}
+
 type nodePair struct {
 	Key   *yml.Node
 	Value *yml.Node
@@ -114,7 +123,7 @@ func (n *Tree) Get(path string, dest interface{}) (err error) {
 	result, _, err = findInNode(n.Node, keyPath[0])
 	if err != nil {
 		if err.Error() == "Not found" {
-			err = fmt.Errorf("Could not find a value at %s", path)
+			err = NotFoundError{Path: path}
 		}
 		return
 	}
@@ -180,6 +189,60 @@ func (n *Tree) Set(path string, value interface{}) (err error) {
 	return
 }
 
+// Delete the value at a path within this node, along with its key
+func (n *Tree) Delete(path string) error {
+	keyPath := strings.SplitN(path, ".", 2)
+
+	result, nodeIndex, err := findInNode(n.Node, keyPath[0])
+	if err != nil {
+		return NotFoundError{Path: path}
+	}
+
+	if len(keyPath) > 1 {
+		// recurse
+		v := &Tree{Node: result}
+		return v.Delete(keyPath[1])
+	}
+
+	switch n.Node.Kind {
+	case yml.MappingNode:
+		// the key comes right before its value
+		n.Node.Content = append(n.Node.Content[:nodeIndex-1], n.Node.Content[nodeIndex+1:]...)
+	case yml.SequenceNode:
+		n.Node.Content = append(n.Node.Content[:nodeIndex], n.Node.Content[nodeIndex+1:]...)
+	default:
+		return fmt.Errorf("Cannot delete %s from an alias", path)
+	}
+
+	return nil
+}
+
+// Copy returns a deep copy of this node, that can be changed without changing this one
+func (n *Tree) Copy() *Tree {
+	copies := map[*yml.Node]*yml.Node{}
+	var copyNode func(node *yml.Node) *yml.Node
+	copyNode = func(node *yml.Node) *yml.Node {
+		if node == nil {
+			return nil
+		}
+		// aliases point to nodes copied already
+		if copied, found := copies[node]; found {
+			return copied
+		}
+
+		copied := *node
+		copies[node] = &copied
+		copied.Content = make([]*yml.Node, len(node.Content))
+		for index, child := range node.Content {
+			copied.Content[index] = copyNode(child)
+		}
+		copied.Alias = copyNode(node.Alias)
+		return &copied
+	}
+
+	return &Tree{Node: copyNode(n.Node), Secret: n.Secret}
+}
+
 // IsMap returns true if this is a mapping node
 func (n *Tree) IsMap() bool {
 	return n.Node != nil && n.Node.Kind == yml.MappingNode
@@ -228,9 +291,21 @@ func newNode(key string, value interface{}) (nodes []*yml.Node) {
 		})
 	}
 
+	// nodes from another tree are kept as they are
+	if node, isNode := value.(*yml.Node); isNode {
+		return append(nodes, node)
+	}
+
 	v := reflect.ValueOf(value)
 	kind := v.Kind()
 	switch kind {
+	case reflect.Invalid:
+		nodes = append(nodes, &yml.Node{
+			Kind:    yml.ScalarNode,
+			Tag:     "!!null",
+			Value:   "null",
+			Content: []*yml.Node{},
+		})
 	case reflect.Map:
 		theMap := &yml.Node{
 			Kind:    yml.MappingNode,
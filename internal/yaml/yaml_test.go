@@ -129,6 +129,7 @@ func TestGetValidValues(t *testing.T) {
 		{"list", []interface{}{"a", "b", "c"}},
 		{"list.0", "a"},
 		{"list.4", nil},
+		{"missing", nil},
 		{"nestedList", []interface{}{
 			map[string]interface{}{"prop": true},
 			map[string]interface{}{"prop": false},
@@ -151,8 +152,10 @@ func TestGetValidValues(t *testing.T) {
 		t.Run(tst.prop, func(t *testing.T) {
 			var value interface{}
 			err := yaml.Get(tst.prop, &value)
-			if tst.value == nil && err == nil {
-				t.Fatal("Got no error with missing prop")
+			if tst.value == nil {
+				if _, notFound := err.(NotFoundError); !notFound {
+					t.Fatalf("Got the wrong error with missing prop: %v", err)
+				}
 			}
 
 			if !reflect.DeepEqual(value, tst.value) {
@@ -184,6 +187,7 @@ func TestSetValidValues(t *testing.T) {
 		{"new.object", map[string]interface{}{"key": "value"}, ""},
 		{"new.object.newKey", "value", ""},
 		{"new.string", "value", ""},
+		{"new.null", nil, ""},
 		{"new.doublenestedList.0.0", "value", ""},
 		// overwrite
 		{"string", true, ""},
@@ -222,6 +226,54 @@ func TestSetValidValues(t *testing.T) {
 	}
 }
 
+func TestSetNode(t *testing.T) {
+	source, err := FromBytes([]byte("quoted: \"true\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &Tree{}
+	if err = source.Get("quoted", &node); err != nil {
+		t.Fatal(err)
+	}
+
+	yaml, _ := FromValue(map[string]interface{}{})
+	if err = yaml.Set("copied.quoted", node.Node); err != nil {
+		t.Fatal(err)
+	}
+
+	var value interface{}
+	if err = yaml.Get("copied.quoted", &value); err != nil || value != "true" {
+		t.Fatalf("Did not keep the node as it was: %#v, %v", value, err)
+	}
+}
+
+func TestDeleteAndCopy(t *testing.T) {
+	yaml, err := FromBytes([]byte("# kept with its key\nmap:\n  gone: 1\n  kept: 2\nlist: [a, b, c]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := yaml.Copy()
+
+	for _, keyPath := range []string{"map.gone", "list.1"} {
+		if err = copied.Delete(keyPath); err != nil {
+			t.Fatalf("Could not delete %s: %v", keyPath, err)
+		}
+	}
+	if err = copied.Delete("map.missing"); err == nil {
+		t.Fatal("Deleted a missing value")
+	}
+
+	serialized, _ := copied.Serialize()
+	if string(serialized) != "list: [a, c]\n# kept with its key\nmap:\n  kept: 2\n" {
+		t.Fatalf("Deleted wrong values: %s", serialized)
+	}
+
+	var value interface{}
+	if err = yaml.Get("map.gone", &value); err != nil || value != 1 {
+		t.Fatalf("Deleting from the copy changed the original: %v, %v", value, err)
+	}
+}
+
 func TestGetBadEncryptedNode(t *testing.T) {
 
 	yaml, err := FromPathname(fx.Path("bad/secret.ciphertext"))
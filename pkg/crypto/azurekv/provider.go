@@ -0,0 +1,227 @@
+// Copyright 2018 Blink Health LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+
+// Package azurekv adds Azure Key Vault support for go-config-yourself
+//
+// It encrypts values with a data key, wrapped by a key stored in Azure Key Vault (https://azure.microsoft.com/services/key-vault/).
+// The values are encrypted using AES in GCM mode.
+package azurekv
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/agent"
+	"github.com/blinkhealth/go-config-yourself/internal/datakey"
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+)
+
+func init() {
+	pvd.RegisterProvider("azurekv", New, []pvd.Argument{
+		{
+			Name:        "azure-key",
+			Description: "The Azure Key Vault key identifier to use, like `https://VAULT.vault.azure.net/keys/KEY`",
+		},
+		{
+			Name:        "azure-vault",
+			Description: "The Azure Key Vault name or URL to list keys from, when --azure-key is omitted",
+			EnvVarName:  "AZURE_KEYVAULT_URL",
+		},
+	})
+}
+
+// Provider implements provider.Crypto for Azure Key Vault
+type Provider struct {
+	// The versioned key identifier
+	key string
+	// The data key, wrapped by `key`
+	wrappedKey string
+	dataKey    *datakey.Service
+	service    *azureService
+}
+
+// New creates a new azurekv.Provider and returns it
+func New(config map[string]interface{}) (pvd.Crypto, error) {
+	key, _ := config["key"].(string)
+	wrappedKey, _ := config["wrapped_key"].(string)
+
+	if key != "" {
+		log.Debugf("Inferring vault from key %s", key)
+		vaultURL, err := keyVault(key)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Initializing secure config with key %s in vault %s", key, vaultURL)
+	}
+
+	return &Provider{
+		key:        key,
+		wrappedKey: wrappedKey,
+		service:    newAzureService(),
+	}, nil
+}
+
+// Enabled tells whether the provider is ready to operate on secrets
+func (provider *Provider) Enabled() bool {
+	return provider.key != "" && provider.wrappedKey != ""
+}
+
+// Encrypt bytes
+func (provider *Provider) Encrypt(plainText []byte) (cipherText []byte, err error) {
+	if err = provider.readyForCrypto(); err == nil {
+		cipherText, err = provider.dataKey.Encrypt(plainText)
+	}
+	return
+}
+
+// Decrypt bytes
+func (provider *Provider) Decrypt(cipherText []byte) (plainText string, err error) {
+	if err = provider.readyForCrypto(); err == nil {
+		plainText, err = provider.dataKey.Decrypt(cipherText)
+	}
+	return
+}
+
+// Replace the data key with a new one, wrapped by a different key
+//
+// Will list the keys in `azure-vault`, and prompt the user to select one, unless `azure-key` is present in `args`
+func (provider *Provider) Replace(args map[string]interface{}) (err error) {
+	key, _ := args["azure-key"].(string)
+
+	if key == "" {
+		vault, _ := args["azure-vault"].(string)
+		if vault == "" {
+			vault = os.Getenv("AZURE_KEYVAULT_URL")
+		}
+		if vault == "" {
+			return fmt.Errorf("Unable to list keys without a vault, use --azure-vault or --azure-key")
+		}
+
+		keys, err := provider.service.ListKeys(vaultURL(vault))
+		if err != nil {
+			return fmt.Errorf("Failed to list keys: %s", err)
+		}
+
+		responses, err := input.SelectionFromList(keys, "azurekv", false)
+		if err != nil {
+			return err
+		}
+		key = responses[0]
+	}
+
+	if _, err = keyVault(key); err != nil {
+		return
+	}
+
+	dataKey, err := datakey.New()
+	if err != nil {
+		return
+	}
+
+	usedKey, wrappedKey, err := provider.service.WrapKey(strings.TrimRight(key, "/"), dataKey)
+	if err != nil {
+		return
+	}
+
+	provider.key = usedKey
+	provider.wrappedKey = wrappedKey
+	provider.dataKey = datakey.NewService(dataKey)
+	return
+}
+
+// Arguments returns the key to wrap new data keys with again, without listing keys
+func (provider *Provider) Arguments() map[string]interface{} {
+	return map[string]interface{}{"azure-key": provider.key}
+}
+
+// Serialize into a map of config for later hydration
+func (provider *Provider) Serialize() (serialized map[string]interface{}) {
+	serialized = make(map[string]interface{})
+	serialized["provider"] = "azurekv"
+	serialized["key"] = provider.key
+	serialized["wrapped_key"] = provider.wrappedKey
+	return
+}
+
+func (provider *Provider) readyForCrypto() error {
+	if provider.dataKey != nil {
+		return nil
+	}
+
+	if !provider.Enabled() {
+		return fmt.Errorf("No key found")
+	}
+
+	// a running agent might have unwrapped this key already
+	fingerprint := agent.Fingerprint([]byte(provider.key), []byte(provider.wrappedKey))
+	if dataKey, found := agent.Get(fingerprint); found {
+		provider.dataKey = datakey.NewService(dataKey)
+		return nil
+	}
+
+	dataKey, err := provider.service.UnwrapKey(provider.key, provider.wrappedKey)
+	if err != nil {
+		return err
+	}
+
+	provider.dataKey = datakey.NewService(dataKey)
+	agent.Put(fingerprint, dataKey)
+	return nil
+}
+
+// keyVault infers the vault URL from a key identifier
+func keyVault(key string) (string, error) {
+	// https://VAULT.vault.azure.net/keys/KEY[/VERSION]
+	parsed, err := url.Parse(key)
+	if err != nil {
+		return "", fmt.Errorf("Invalid Azure Key Vault key identifier <%s>: %s", key, err)
+	}
+
+	pieces := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if parsed.Host == "" || len(pieces) < 2 || len(pieces) > 3 || pieces[0] != "keys" {
+		return "", fmt.Errorf("Unable to infer vault from non fully-qualified Azure Key Vault key identifier <%s>", key)
+	}
+
+	if err = trustedVault(parsed); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host), nil
+}
+
+// vaultURL turns a vault name into its URL
+func vaultURL(vault string) string {
+	if strings.Contains(vault, "://") {
+		return strings.TrimRight(vault, "/")
+	}
+
+	return fmt.Sprintf("https://%s.%s", vault, strings.TrimPrefix(dnsSuffix(), "."))
+}
+
+// dnsSuffix is the domain vaults live in, `.vault.azure.net` unless AZURE_KEYVAULT_DNS_SUFFIX names another cloud's
+func dnsSuffix() string {
+	if suffix := os.Getenv("AZURE_KEYVAULT_DNS_SUFFIX"); suffix != "" {
+		return suffix
+	}
+
+	return defaultDNSSuffix
+}
+
+// trustedVault checks an address is a vault before it gets a token, since files choose their key identifiers
+func trustedVault(address *url.URL) error {
+	if address.Scheme != "https" || !strings.HasSuffix(address.Hostname(), dnsSuffix()) {
+		return fmt.Errorf("Refusing to send credentials to <%s://%s>, Azure Key Vault is only reached over https at *%s", address.Scheme, address.Host, dnsSuffix())
+	}
+
+	return nil
+}
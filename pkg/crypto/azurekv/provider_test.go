@@ -0,0 +1,168 @@
+package azurekv_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/azurekv"
+	log "github.com/sirupsen/logrus"
+)
+
+const testToken = "a-good-token"
+
+// keyVaultStandIn mimics Azure AD and Key Vault, "wrapping" keys by prefixing them with the versioned key identifier.
+// It's only reached over https, so the default transport is replaced by one trusting its certificate
+func keyVaultStandIn() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := func(status int, data interface{}) {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(data)
+		}
+
+		if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+			_ = r.ParseForm()
+			if r.Form.Get("client_id") == "client" && r.Form.Get("client_secret") == "secret" {
+				reply(200, map[string]string{"access_token": testToken})
+			} else {
+				reply(401, map[string]string{"error_description": "Invalid client secret provided."})
+			}
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+testToken || r.URL.Query().Get("api-version") == "" {
+			reply(401, map[string]interface{}{"error": map[string]string{"message": "Unauthorized"}})
+			return
+		}
+
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		kid := server.URL + "/keys/app/v2"
+		switch r.URL.Path {
+		case "/keys":
+			reply(200, map[string]interface{}{"value": []map[string]interface{}{
+				{"kid": server.URL + "/keys/app", "attributes": map[string]bool{"enabled": true}},
+			}})
+		case "/keys/app/wrapkey", "/keys/app/v2/wrapkey":
+			reply(200, map[string]string{"kid": kid, "value": "wrapped-" + body["value"]})
+		case "/keys/app/v2/unwrapkey":
+			if !strings.HasPrefix(body["value"], "wrapped-") {
+				reply(400, map[string]interface{}{"error": map[string]string{"message": "Unwrap failed"}})
+				return
+			}
+			reply(200, map[string]string{"kid": kid, "value": strings.TrimPrefix(body["value"], "wrapped-")})
+		default:
+			reply(404, map[string]interface{}{"error": map[string]string{"message": "Key not found"}})
+		}
+	}))
+
+	http.DefaultTransport = server.Client().Transport
+	return server
+}
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	os.Unsetenv("AZURE_KEYVAULT_URL")
+	os.Unsetenv("GCY_AGENT_SOCK")
+	// stand-ins listen on 127.0.0.1
+	os.Setenv("AZURE_KEYVAULT_DNS_SUFFIX", "127.0.0.1")
+	os.Setenv("AZURE_TENANT_ID", "tenant")
+	os.Setenv("AZURE_CLIENT_ID", "client")
+	os.Setenv("AZURE_CLIENT_SECRET", "secret")
+	os.Exit(m.Run())
+}
+
+func TestAzureProvider(t *testing.T) {
+	server := keyVaultStandIn()
+	defer server.Close()
+	os.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	defer os.Unsetenv("AZURE_AUTHORITY_HOST")
+
+	provider, err := azurekv.New(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Enabled() {
+		t.Fatal("Enabled without a key")
+	}
+
+	if err = provider.Replace(map[string]interface{}{"azure-key": server.URL + "/secrets/app"}); err == nil {
+		t.Fatal("Accepted an invalid key identifier")
+	}
+
+	if err = provider.Replace(map[string]interface{}{"azure-key": server.URL + "/keys/app"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cipherText, err := provider.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized := provider.Serialize()
+	if serialized["key"] != server.URL+"/keys/app/v2" || !strings.HasPrefix(serialized["wrapped_key"].(string), "wrapped-") {
+		t.Fatalf("Serialized wrong config: %v", serialized)
+	}
+
+	hydrated, err := azurekv.New(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText, err := hydrated.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+
+	serialized["wrapped_key"] = "tampered"
+	tampered, _ := azurekv.New(serialized)
+	if _, err = tampered.Decrypt(cipherText); err == nil || !strings.Contains(err.Error(), "Unwrap failed") {
+		t.Fatalf("Decrypted with a tampered key: %v", err)
+	}
+}
+
+func TestAzureAuth(t *testing.T) {
+	server := keyVaultStandIn()
+	defer server.Close()
+	os.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	defer os.Unsetenv("AZURE_AUTHORITY_HOST")
+
+	provider, _ := azurekv.New(map[string]interface{}{})
+	if err := provider.Replace(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "without a vault") {
+		t.Fatalf("Listed keys without a vault: %v", err)
+	}
+
+	os.Setenv("AZURE_CLIENT_SECRET", "wrong")
+	defer os.Setenv("AZURE_CLIENT_SECRET", "secret")
+	err := provider.Replace(map[string]interface{}{"azure-vault": server.URL})
+	if err == nil || !strings.Contains(err.Error(), "Invalid client secret") {
+		t.Fatalf("Logged in with a bad secret: %v", err)
+	}
+}
+
+func TestAzureUntrustedVault(t *testing.T) {
+	server := keyVaultStandIn()
+	defer server.Close()
+	os.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	defer os.Unsetenv("AZURE_AUTHORITY_HOST")
+
+	plain := "http" + strings.TrimPrefix(server.URL, "https")
+	for _, key := range []string{plain + "/keys/app/v2", "https://attacker.example.com/keys/app/v2"} {
+		if _, err := azurekv.New(map[string]interface{}{"key": key, "wrapped_key": "wrapped-a"}); err == nil || !strings.Contains(err.Error(), "Refusing to send credentials") {
+			t.Fatalf("Accepted key %s: %v", key, err)
+		}
+	}
+
+	os.Unsetenv("AZURE_KEYVAULT_DNS_SUFFIX")
+	defer os.Setenv("AZURE_KEYVAULT_DNS_SUFFIX", "127.0.0.1")
+	provider, _ := azurekv.New(map[string]interface{}{})
+	if err := provider.Replace(map[string]interface{}{"azure-key": server.URL + "/keys/app"}); err == nil || !strings.Contains(err.Error(), "*.vault.azure.net") {
+		t.Fatalf("Wrapped a key outside of Azure: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package azurekv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+)
+
+const (
+	apiVersion       = "7.4"
+	wrapAlgorithm    = "RSA-OAEP-256"
+	defaultAuthority = "https://login.microsoftonline.com"
+	vaultResource    = "https://vault.azure.net"
+	defaultDNSSuffix = ".vault.azure.net"
+	requestTimeout   = 30 * time.Second
+)
+
+type azureService struct {
+	client *http.Client
+	token  string
+}
+
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type keyOperation struct {
+	Kid   string `json:"kid,omitempty"`
+	Alg   string `json:"alg,omitempty"`
+	Value string `json:"value"`
+}
+
+func newAzureService() *azureService {
+	return &azureService{
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// WrapKey encrypts a data key with the key identified by `kid`, returning the versioned identifier of the key used
+func (svc *azureService) WrapKey(kid string, dataKey []byte) (usedKid string, wrapped string, err error) {
+	res := &keyOperation{}
+	err = svc.request(http.MethodPost, kid+"/wrapkey", &keyOperation{
+		Alg:   wrapAlgorithm,
+		Value: base64.RawURLEncoding.EncodeToString(dataKey),
+	}, res)
+
+	return res.Kid, res.Value, err
+}
+
+// UnwrapKey decrypts a data key with the key identified by `kid`
+func (svc *azureService) UnwrapKey(kid string, wrapped string) ([]byte, error) {
+	res := &keyOperation{}
+	err := svc.request(http.MethodPost, kid+"/unwrapkey", &keyOperation{
+		Alg:   wrapAlgorithm,
+		Value: wrapped,
+	}, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(res.Value, "="))
+}
+
+// ListKeys lists the enabled keys in the key vault at `vaultURL`
+func (svc *azureService) ListKeys(vaultURL string) (keys []string, err error) {
+	next := vaultURL + "/keys"
+	for next != "" {
+		page := struct {
+			Value []struct {
+				Kid        string `json:"kid"`
+				Attributes struct {
+					Enabled bool `json:"enabled"`
+				} `json:"attributes"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}{}
+
+		if err = svc.request(http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+
+		for _, key := range page.Value {
+			if key.Attributes.Enabled {
+				keys = append(keys, key.Kid)
+			}
+		}
+		next = page.NextLink
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("Could not find any enabled keys in %s", vaultURL)
+	}
+
+	return keys, nil
+}
+
+// login gets a token with the client credentials in AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET, or from the az CLI
+func (svc *azureService) login() (err error) {
+	if svc.token != "" {
+		return nil
+	}
+
+	tenant := os.Getenv("AZURE_TENANT_ID")
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	secret := os.Getenv("AZURE_CLIENT_SECRET")
+	if tenant != "" && clientID != "" && secret != "" {
+		authority := os.Getenv("AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = defaultAuthority
+		}
+
+		log.Debugf("Logging in to %s as %s", authority, clientID)
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {secret},
+			"scope":         {vaultResource + "/.default"},
+		}
+		resp, err := svc.client.PostForm(fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimRight(authority, "/"), tenant), form)
+		if err != nil {
+			return fmt.Errorf("Could not reach Azure AD at %s: %s", authority, err)
+		}
+		defer resp.Body.Close()
+
+		res := struct {
+			AccessToken      string `json:"access_token"`
+			ErrorDescription string `json:"error_description"`
+		}{}
+		if err = json.NewDecoder(resp.Body).Decode(&res); err != nil || res.AccessToken == "" {
+			return fmt.Errorf("Azure AD login failed: %s", res.ErrorDescription)
+		}
+
+		svc.token = res.AccessToken
+		return nil
+	}
+
+	// nolint:gosec
+	cmd := exec.Command("az", "account", "get-access-token", "--resource", vaultResource, "--query", "accessToken", "--output", "tsv")
+	cmd.Env = input.SanitizedEnv()
+	if out, err := cmd.Output(); err == nil {
+		log.Debug("Using token from az")
+		svc.token = strings.TrimSpace(string(out))
+		return nil
+	}
+
+	return errors.New("No Azure credentials found, set AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET or run `az login`")
+}
+
+func (svc *azureService) request(method string, address string, body interface{}, dest interface{}) (err error) {
+	if err = svc.login(); err != nil {
+		return
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return
+		}
+	}
+
+	target, err := url.Parse(address)
+	if err != nil {
+		return
+	}
+	if err = trustedVault(target); err != nil {
+		return
+	}
+	query := target.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", apiVersion)
+		target.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequest(method, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+svc.token)
+
+	log.Debugf("%s %s", method, target)
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Could not reach Azure Key Vault at %s: %s", target.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		msg := apiErr.Error.Message
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("Azure Key Vault denied %s (%d): %s", target.Path, resp.StatusCode, msg)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("Azure Key Vault replied with invalid JSON: %s", err)
+	}
+
+	return nil
+}
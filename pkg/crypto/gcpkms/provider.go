@@ -0,0 +1,145 @@
+// Copyright 2018 Blink Health LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+
+// Package gcpkms adds Google Cloud KMS support for go-config-yourself
+//
+// It uses the Cloud KMS (https://cloud.google.com/kms) service to encrypt every secret value.
+package gcpkms
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+)
+
+func init() {
+	pvd.RegisterProvider("gcpkms", New, []pvd.Argument{
+		{
+			Name:        "gcp-key",
+			Description: "The Google Cloud KMS crypto key resource name to use, like `projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY`",
+		},
+		{
+			Name:        "gcp-project",
+			Description: "The Google Cloud project to list keys from, when --gcp-key is omitted",
+			EnvVarName:  "GOOGLE_CLOUD_PROJECT",
+		},
+		{
+			Name:        "gcp-location",
+			Description: "The Google Cloud location to list keys from, when --gcp-key is omitted",
+			Default:     "global",
+		},
+	})
+}
+
+// Provider implements provider.Crypto for Google Cloud KMS
+type Provider struct {
+	key     string
+	service *gcpService
+}
+
+// New creates a new gcpkms.Provider and returns it
+func New(config map[string]interface{}) (pvd.Crypto, error) {
+	key, _ := config["key"].(string)
+
+	if key != "" {
+		if err := validKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debugf("Initializing secure config with key %s", key)
+	return &Provider{
+		key:     key,
+		service: newGCPService(),
+	}, nil
+}
+
+// Enabled tells whether the provider is ready to operate on secrets
+func (provider *Provider) Enabled() bool {
+	return provider.key != ""
+}
+
+// Encrypt bytes
+func (provider *Provider) Encrypt(plainText []byte) ([]byte, error) {
+	return provider.service.Encrypt(provider.key, plainText)
+}
+
+// Decrypt bytes
+func (provider *Provider) Decrypt(cipherText []byte) (string, error) {
+	return provider.service.Decrypt(provider.key, cipherText)
+}
+
+// Replace the key with a new one
+//
+// Will list the symmetric keys in `gcp-project` and `gcp-location`, and prompt the user to select one, unless
+// `gcp-key` is present in `args`
+func (provider *Provider) Replace(args map[string]interface{}) (err error) {
+	key, _ := args["gcp-key"].(string)
+
+	if key == "" {
+		project, _ := args["gcp-project"].(string)
+		if project == "" {
+			project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+		}
+		if project == "" {
+			return fmt.Errorf("Unable to list keys without a project, use --gcp-project or --gcp-key")
+		}
+
+		location, _ := args["gcp-location"].(string)
+		if location == "" {
+			location = "global"
+		}
+
+		keys, err := provider.service.ListKeys(project, location)
+		if err != nil {
+			return fmt.Errorf("Failed to list keys: %s", err)
+		}
+
+		responses, err := input.SelectionFromList(keys, "gcpkms", false)
+		if err != nil {
+			return err
+		}
+		key = responses[0]
+	}
+
+	if err = validKey(key); err != nil {
+		return
+	}
+
+	provider.key = key
+	return
+}
+
+// Arguments returns the key to use again without listing keys
+func (provider *Provider) Arguments() map[string]interface{} {
+	return map[string]interface{}{"gcp-key": provider.key}
+}
+
+// Serialize into a map of config for later hydration
+func (provider *Provider) Serialize() (serialized map[string]interface{}) {
+	serialized = make(map[string]interface{})
+	serialized["key"] = provider.key
+	serialized["provider"] = "gcpkms"
+	return
+}
+
+// validKey checks a key's resource name is fully-qualified, since its location is read from it
+func validKey(key string) error {
+	// projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY
+	pieces := strings.Split(key, "/")
+	if len(pieces) != 8 || pieces[0] != "projects" || pieces[2] != "locations" || pieces[4] != "keyRings" || pieces[6] != "cryptoKeys" {
+		return fmt.Errorf("Unable to infer location from non fully-qualified Google Cloud KMS key name <%s>", key)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package gcpkms_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/gcpkms"
+	log "github.com/sirupsen/logrus"
+)
+
+const testToken = "a-good-token"
+const testKey = "projects/app/locations/us-east1/keyRings/ring/cryptoKeys/app"
+const otherKey = "projects/app/locations/us-east1/keyRings/other/cryptoKeys/app"
+
+// kmsStandIn mimics Cloud KMS, "encrypting" plaintext by prefixing it with the key name
+func kmsStandIn() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string][]byte{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		reply := func(status int, data interface{}) {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(data)
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			reply(401, map[string]interface{}{"error": map[string]string{"message": "Request had invalid authentication credentials."}})
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case path == "projects/app/locations/us-east1/keyRings" && r.URL.Query().Get("pageToken") == "":
+			reply(200, map[string]interface{}{
+				"keyRings":      []map[string]string{{"name": "projects/app/locations/us-east1/keyRings/ring"}},
+				"nextPageToken": "page 2",
+			})
+		case path == "projects/app/locations/us-east1/keyRings" && r.URL.Query().Get("pageToken") == "page 2":
+			reply(200, map[string]interface{}{"keyRings": []map[string]string{{"name": "projects/app/locations/us-east1/keyRings/other"}}})
+		case path == "projects/app/locations/us-east1/keyRings/ring/cryptoKeys":
+			reply(200, map[string]interface{}{"cryptoKeys": []map[string]string{
+				{"name": testKey, "purpose": "ENCRYPT_DECRYPT"},
+				{"name": testKey + "-signing", "purpose": "ASYMMETRIC_SIGN"},
+			}})
+		case path == "projects/app/locations/us-east1/keyRings/other/cryptoKeys":
+			reply(200, map[string]interface{}{"cryptoKeys": []map[string]string{{"name": otherKey, "purpose": "ENCRYPT_DECRYPT"}}})
+		case strings.HasSuffix(path, ":encrypt"):
+			name := strings.TrimSuffix(path, ":encrypt")
+			reply(200, map[string]interface{}{"ciphertext": append([]byte(name+":"), body["plaintext"]...)})
+		case strings.HasSuffix(path, ":decrypt"):
+			name := strings.TrimSuffix(path, ":decrypt")
+			if !strings.HasPrefix(string(body["ciphertext"]), name+":") {
+				reply(400, map[string]interface{}{"error": map[string]string{"message": "Decryption failed"}})
+				return
+			}
+			reply(200, map[string]interface{}{"plaintext": body["ciphertext"][len(name)+1:]})
+		default:
+			reply(404, map[string]interface{}{"error": map[string]string{"message": "Not found"}})
+		}
+	}))
+}
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	os.Unsetenv("GCP_KMS_ENDPOINT")
+	os.Unsetenv("GOOGLE_CLOUD_PROJECT")
+	os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", testToken)
+	os.Exit(m.Run())
+}
+
+func TestGCPProvider(t *testing.T) {
+	server := kmsStandIn()
+	defer server.Close()
+	os.Setenv("GCP_KMS_ENDPOINT", server.URL)
+	defer os.Unsetenv("GCP_KMS_ENDPOINT")
+
+	provider, err := gcpkms.New(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Enabled() {
+		t.Fatal("Enabled without a key")
+	}
+
+	if err = provider.Replace(map[string]interface{}{"gcp-key": "projects/app/keyRings/ring"}); err == nil {
+		t.Fatal("Accepted a partial key name")
+	}
+
+	if err = provider.Replace(map[string]interface{}{"gcp-key": testKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	cipherText, err := provider.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cipherText) != testKey+":plaintext" {
+		t.Fatalf("Encrypted wrong value: %s", cipherText)
+	}
+
+	serialized := provider.Serialize()
+	if serialized["key"] != testKey {
+		t.Fatalf("Serialized wrong config: %v", serialized)
+	}
+
+	hydrated, err := gcpkms.New(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText, err := hydrated.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+
+	other, _ := gcpkms.New(map[string]interface{}{"key": testKey + "-other"})
+	if _, err = other.Decrypt(cipherText); err == nil || !strings.Contains(err.Error(), "Decryption failed") {
+		t.Fatalf("Decrypted with the wrong key: %v", err)
+	}
+}
+
+func TestGCPListKeys(t *testing.T) {
+	server := kmsStandIn()
+	defer server.Close()
+	os.Setenv("GCP_KMS_ENDPOINT", server.URL)
+	defer os.Unsetenv("GCP_KMS_ENDPOINT")
+
+	provider, _ := gcpkms.New(map[string]interface{}{})
+	if err := provider.Replace(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "without a project") {
+		t.Fatalf("Listed keys without a project: %v", err)
+	}
+
+	err := provider.Replace(map[string]interface{}{"gcp-project": "app", "gcp-location": "europe-west1"})
+	if err == nil || !strings.Contains(err.Error(), "Failed to list keys") {
+		t.Fatalf("Listed keys in a missing location: %v", err)
+	}
+
+	// the key ring holding the second key is on the second page, select it with ctrl-n
+	restoreStdin, err := fx.MockStdin("\x0e\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = provider.Replace(map[string]interface{}{"gcp-project": "app", "gcp-location": "us-east1"})
+	restoreStdin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Serialize()["key"] != otherKey {
+		t.Fatalf("Selected wrong key: %v", provider.Serialize())
+	}
+
+	os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "a-bad-token")
+	defer os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", testToken)
+	provider, _ = gcpkms.New(map[string]interface{}{"key": testKey})
+	if _, err := provider.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "invalid authentication") {
+		t.Fatalf("Encrypted with a bad token: %v", err)
+	}
+	if _, hasEndpoint := provider.Serialize()["endpoint"]; hasEndpoint {
+		t.Fatalf("Serialized the endpoint from the environment: %v", provider.Serialize())
+	}
+}
+
+func TestGCPEndpointInFile(t *testing.T) {
+	server := kmsStandIn()
+	defer server.Close()
+	os.Setenv("GCP_KMS_ENDPOINT", server.URL)
+	defer os.Unsetenv("GCP_KMS_ENDPOINT")
+
+	leaked := false
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = true
+	}))
+	defer elsewhere.Close()
+
+	provider, _ := gcpkms.New(map[string]interface{}{"endpoint": elsewhere.URL, "key": testKey})
+	if _, err := provider.Encrypt([]byte("plaintext")); err != nil {
+		t.Fatal(err)
+	}
+	if leaked {
+		t.Fatal("Sent the access token to the endpoint in the file")
+	}
+}
+
+func TestGCPCiphertextEncoding(t *testing.T) {
+	server := kmsStandIn()
+	defer server.Close()
+
+	os.Setenv("GCP_KMS_ENDPOINT", server.URL)
+	defer os.Unsetenv("GCP_KMS_ENDPOINT")
+
+	provider, _ := gcpkms.New(map[string]interface{}{"key": testKey})
+	// binary plaintexts survive the round trip through base64 JSON fields
+	raw := []byte{0, 1, 2, 255}
+	cipherText, err := provider.Encrypt(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText, err := provider.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base64.StdEncoding.EncodeToString([]byte(plainText)) != base64.StdEncoding.EncodeToString(raw) {
+		t.Fatalf("Decrypted wrong value: %v", []byte(plainText))
+	}
+}
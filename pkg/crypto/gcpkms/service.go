@@ -0,0 +1,224 @@
+package gcpkms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+)
+
+const (
+	defaultEndpoint = "https://cloudkms.googleapis.com"
+	// only reachable from within google cloud
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	requestTimeout   = 30 * time.Second
+)
+
+type gcpService struct {
+	endpoint string
+	client   *http.Client
+	token    string
+}
+
+type apiError struct {
+	Error struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// newGCPService talks to Cloud KMS, or GCP_KMS_ENDPOINT when set. Files can't choose the endpoint, since it receives
+// the user's access token
+func newGCPService() *gcpService {
+	endpoint := os.Getenv("GCP_KMS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	return &gcpService{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Encrypt plaintext with the crypto key `name`
+func (svc *gcpService) Encrypt(name string, plainText []byte) ([]byte, error) {
+	res := struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}{}
+
+	err := svc.request(http.MethodPost, fmt.Sprintf("v1/%s:encrypt", name), map[string]interface{}{
+		"plaintext": plainText,
+	}, &res)
+
+	return res.Ciphertext, err
+}
+
+// Decrypt a ciphertext with the crypto key `name`
+func (svc *gcpService) Decrypt(name string, cipherText []byte) (string, error) {
+	res := struct {
+		Plaintext []byte `json:"plaintext"`
+	}{}
+
+	err := svc.request(http.MethodPost, fmt.Sprintf("v1/%s:decrypt", name), map[string]interface{}{
+		"ciphertext": cipherText,
+	}, &res)
+
+	return string(res.Plaintext), err
+}
+
+// listPage is a page of key rings or crypto keys
+type listPage struct {
+	KeyRings []struct {
+		Name string `json:"name"`
+	} `json:"keyRings"`
+	CryptoKeys []struct {
+		Name    string `json:"name"`
+		Purpose string `json:"purpose"`
+	} `json:"cryptoKeys"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// listAll requests every page of the list at `path`, and returns them as one
+func (svc *gcpService) listAll(path string) (all *listPage, err error) {
+	all = &listPage{}
+	pageToken := ""
+	for {
+		page := &listPage{}
+		pagePath := path
+		if pageToken != "" {
+			pagePath = fmt.Sprintf("%s?pageToken=%s", path, url.QueryEscape(pageToken))
+		}
+		if err = svc.request(http.MethodGet, pagePath, nil, page); err != nil {
+			return nil, err
+		}
+
+		all.KeyRings = append(all.KeyRings, page.KeyRings...)
+		all.CryptoKeys = append(all.CryptoKeys, page.CryptoKeys...)
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// ListKeys lists the symmetric crypto keys of every key ring in `project` at `location`
+func (svc *gcpService) ListKeys(project string, location string) (keys []string, err error) {
+	parent := fmt.Sprintf("projects/%s/locations/%s", project, location)
+	rings, err := svc.listAll(fmt.Sprintf("v1/%s/keyRings", parent))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ring := range rings.KeyRings {
+		cryptoKeys, err := svc.listAll(fmt.Sprintf("v1/%s/cryptoKeys", ring.Name))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range cryptoKeys.CryptoKeys {
+			if key.Purpose == "ENCRYPT_DECRYPT" {
+				keys = append(keys, key.Name)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("Could not find any symmetric keys in %s", parent)
+	}
+
+	return keys, nil
+}
+
+// login finds an access token in GOOGLE_OAUTH_ACCESS_TOKEN, from the gcloud CLI, or from the metadata server
+func (svc *gcpService) login() error {
+	if svc.token != "" {
+		return nil
+	}
+
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		log.Debug("Using token from GOOGLE_OAUTH_ACCESS_TOKEN")
+		svc.token = token
+		return nil
+	}
+
+	// nolint:gosec
+	cmd := exec.Command("gcloud", "auth", "print-access-token")
+	cmd.Env = input.SanitizedEnv()
+	if out, err := cmd.Output(); err == nil {
+		log.Debug("Using token from gcloud")
+		svc.token = strings.TrimSpace(string(out))
+		return nil
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, metadataTokenURL, nil)
+	req.Header.Set("Metadata-Flavor", "Google")
+	client := &http.Client{Timeout: 2 * time.Second}
+	if resp, err := client.Do(req); err == nil {
+		defer resp.Body.Close()
+		res := struct {
+			AccessToken string `json:"access_token"`
+		}{}
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&res) == nil && res.AccessToken != "" {
+			log.Debug("Using token from the metadata server")
+			svc.token = res.AccessToken
+			return nil
+		}
+	}
+
+	return errors.New("No Google Cloud credentials found, set GOOGLE_OAUTH_ACCESS_TOKEN or run `gcloud auth login`")
+}
+
+func (svc *gcpService) request(method string, path string, body interface{}, dest interface{}) (err error) {
+	if err = svc.login(); err != nil {
+		return
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return
+		}
+	}
+
+	location := fmt.Sprintf("%s/%s", svc.endpoint, path)
+	req, err := http.NewRequest(method, location, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+svc.token)
+
+	log.Debugf("%s %s", method, location)
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Could not reach Google Cloud KMS at %s: %s", svc.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		msg := apiErr.Error.Message
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("Google Cloud KMS denied %s (%d): %s", path, resp.StatusCode, msg)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("Google Cloud KMS replied with invalid JSON: %s", err)
+	}
+
+	return nil
+}
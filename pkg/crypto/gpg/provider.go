@@ -142,11 +142,21 @@ func (provider *Provider) Serialize() (serialized map[string]interface{}) {
 	return
 }
 
+// Arguments returns the recipients to use again without prompting for them
+func (provider *Provider) Arguments() map[string]interface{} {
+	return map[string]interface{}{"public-key": provider.service.recipients}
+}
+
 func (provider *Provider) readyForCrypto() (err error) {
 	if provider.service.IsAvailable() {
 		// the gpgService has a decrypted key, continue
 		return
 	}
 
+	// a running agent might have unwrapped this key already
+	if provider.service.UnlockFromAgent() {
+		return
+	}
+
 	return provider.service.DecryptKey()
 }
@@ -7,6 +7,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/blinkhealth/go-config-yourself/internal/agent"
 	"github.com/blinkhealth/go-config-yourself/internal/datakey"
 	"github.com/proglottis/gpgme"
 )
@@ -117,10 +118,24 @@ func (svc *gpgService) DecryptKey() (err error) {
 	}
 
 	svc.dataKey = datakey.NewService(keyBuffer.Bytes())
+	agent.Put(agent.Fingerprint(*svc.encryptedKey), keyBuffer.Bytes())
 
 	return
 }
 
+// UnlockFromAgent fetches the decrypted key from a running agent, and tells if it succeeded
+func (svc *gpgService) UnlockFromAgent() bool {
+	if svc.encryptedKey == nil {
+		return false
+	}
+
+	dataKey, found := agent.Get(agent.Fingerprint(*svc.encryptedKey))
+	if found {
+		svc.dataKey = datakey.NewService(dataKey)
+	}
+	return found
+}
+
 // Decrypt plaintext
 func (svc *gpgService) Decrypt(encryptedBytes []byte) (plainText string, err error) {
 	return svc.dataKey.Decrypt(encryptedBytes)
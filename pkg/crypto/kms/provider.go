@@ -12,11 +12,16 @@
 package kms
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
 
 	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
 
+	"github.com/blinkhealth/go-config-yourself/internal/agent"
 	"github.com/blinkhealth/go-config-yourself/internal/input"
 	log "github.com/sirupsen/logrus"
 )
@@ -27,11 +32,68 @@ func init() {
 			Name:        "key",
 			Description: "The AWS KMS key ARN to use",
 		},
+		{
+			Name:        "kms-profile",
+			Description: "The AWS profile to get credentials from for this file, instead of AWS_PROFILE",
+		},
+		{
+			Name:        "kms-role-arn",
+			Description: "The ARN of an AWS IAM role to assume for this file, to use keys in other accounts",
+		},
+		{
+			Name:        "kms-external-id",
+			Description: "The external ID required to assume --kms-role-arn, if any",
+		},
+		{
+			Name:        "kms-mfa-serial",
+			Description: "The serial number or ARN of the MFA device required to assume --kms-role-arn, if any",
+		},
+		{
+			Name:        "kms-context",
+			Description: "A `key=value` pair for the KMS encryption context secrets are bound to, along with their keypath. Pass multiple times for multiple pairs",
+			Repeatable:  true,
+		},
+		{
+			Name:        "kms-replica",
+			Description: "The ARN of a multi-region replica of the key in another region, to decrypt with when the key's region is unavailable. Pass multiple times for multiple replicas",
+			Repeatable:  true,
+		},
+		{
+			Name:        "kms-region",
+			Description: "Only look for KMS keys in this AWS region when prompting for one. Pass multiple times for multiple regions",
+			Repeatable:  true,
+		},
+		{
+			Name:        "kms-alias-prefix",
+			Description: "Only offer KMS keys with aliases starting with this prefix when prompting for one, for example: alias/billing-",
+		},
 	})
 }
 
+// contextKeyPath is the encryption context key holding each secret's keypath
+const contextKeyPath = "keypath"
+
+// replicaTimeout is how long each region gets to decrypt a secret before falling back to the next replica
+var replicaTimeout = 5 * time.Second
+
 // Provider implements provider.Crypto for KMS
 type Provider struct {
+	key string
+	// The endpoint from `crypto.endpoint`, if any
+	endpoint string
+	// The encryption context from `crypto.context`, nil when secrets are not bound to one
+	context map[string]string
+	// The profile and role to get credentials from
+	session sessionOptions
+	service *kmsService
+	// Replicas of `key` in other regions, from `crypto.replicas`
+	replicas []replica
+	// Where the last secret was decrypted
+	served map[string]string
+}
+
+// replica is a multi-region replica of the primary key, to decrypt with when the primary's region is unavailable
+type replica struct {
 	key     string
 	service *kmsService
 }
@@ -39,6 +101,11 @@ type Provider struct {
 // New creates a new kms.Provider and returns it
 func New(config map[string]interface{}) (pvd.Crypto, error) {
 	key, _ := config["key"].(string)
+	endpoint, _ := config["endpoint"].(string)
+	context, err := contextFromConfig(config["context"])
+	if err != nil {
+		return nil, err
+	}
 
 	var region string
 	if key != "" {
@@ -55,13 +122,30 @@ func New(config map[string]interface{}) (pvd.Crypto, error) {
 		key = "initialization-temporary-key"
 	}
 
-	kmsSvc := newKMSService(region)
+	opts := sessionOptionsFromConfig(config)
+	kmsSvc, err := newKMSService(region, endpoint, opts)
+	if err != nil {
+		return nil, err
+	}
 	log.Debugf("Initializing secure config with key %s in region %s", key, region)
 
-	return &Provider{
-		key:     key,
-		service: kmsSvc,
-	}, nil
+	provider := &Provider{
+		key:      key,
+		endpoint: endpoint,
+		context:  context,
+		session:  opts,
+		service:  kmsSvc,
+	}
+
+	replicas, err := replicasFromConfig(config["replicas"])
+	if err != nil {
+		return nil, err
+	}
+	if err = provider.setReplicas(replicas); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
 }
 
 // Enabled tells whether the provider is ready to operate on secrets
@@ -71,20 +155,97 @@ func (provider *Provider) Enabled() bool {
 
 // Encrypt bytes
 func (provider *Provider) Encrypt(plainText []byte) ([]byte, error) {
-	return provider.service.Encrypt(provider.key, plainText)
+	return provider.EncryptAt("", plainText)
+}
+
+// EncryptAt encrypts bytes stored at `keyPath`, adding it to the encryption context if there's one
+func (provider *Provider) EncryptAt(keyPath string, plainText []byte) ([]byte, error) {
+	return provider.service.Encrypt(provider.key, plainText, provider.encryptionContext(keyPath))
 }
 
 // Decrypt bytes
 func (provider *Provider) Decrypt(encryptedBytes []byte) (string, error) {
-	return provider.service.Decrypt(encryptedBytes)
+	return provider.DecryptAt("", encryptedBytes)
+}
+
+// DecryptAt decrypts bytes stored at `keyPath`, with the same encryption context they were encrypted with
+//
+// KMS has no data key to unwrap, so a running agent caches the plaintext for each ciphertext instead
+func (provider *Provider) DecryptAt(keyPath string, encryptedBytes []byte) (string, error) {
+	context := provider.encryptionContext(keyPath)
+	fingerprint := agent.Fingerprint([]byte(provider.key), encryptedBytes, []byte(describeContext(context)))
+	if plainText, found := agent.Get(fingerprint); found {
+		provider.served = map[string]string{"cache": "gcy agent"}
+		return string(plainText), nil
+	}
+
+	plainText, err := provider.decrypt(encryptedBytes, context)
+	if err == nil {
+		agent.Put(fingerprint, []byte(plainText))
+	}
+	return plainText, err
+}
+
+// Report tells which key and region decrypted the last secret, or if it came from the agent
+func (provider *Provider) Report() map[string]string {
+	return provider.served
+}
+
+// decrypt tries the key's region first, then every replica in order, giving each replicaTimeout to answer
+func (provider *Provider) decrypt(encryptedBytes []byte, encryptionContext map[string]*string) (plainText string, err error) {
+	attempts := append([]replica{{key: provider.key, service: provider.service}}, provider.replicas...)
+	failures := []string{}
+	for index, attempt := range attempts {
+		region := *attempt.service.config.Region
+
+		ctx, cancel := aws.BackgroundContext(), func() {}
+		if len(provider.replicas) > 0 {
+			ctx, cancel = context.WithTimeout(ctx, replicaTimeout)
+		}
+		plainText, err = attempt.service.Decrypt(ctx, encryptedBytes, encryptionContext)
+		cancel()
+
+		if err == nil {
+			provider.served = map[string]string{"key": attempt.key, "region": region}
+			return plainText, nil
+		}
+
+		if _, rejected := err.(ciphertextError); rejected || len(provider.replicas) == 0 {
+			return "", err
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %s", region, err))
+		if index < len(attempts)-1 {
+			log.Warnf("Could not decrypt in %s, trying the replica in %s: %s", region, *attempts[index+1].service.config.Region, err)
+		}
+	}
+
+	return "", fmt.Errorf("Could not decrypt with the key or any of its replicas: %s", strings.Join(failures, "; "))
 }
 
 // Replace the key with a new one
 //
-// Will query every available AWS region and then prompt the user to select a key from it, unless `key` is present in `args`
+// Will query every available AWS region, or the ones in `kms-region`, and then prompt the user to select a key from it, unless `key` is
+// present in `args`. Only keys with aliases starting with `kms-alias-prefix` are offered, when set.
+// The encryption context is replaced when `kms-context` is present in `args`, and credentials are selected by
+// `kms-profile`, `kms-role-arn`, `kms-external-id` and `kms-mfa-serial`
 func (provider *Provider) Replace(args map[string]interface{}) (err error) {
 	var key string
 
+	if opts := provider.session.replace(args); opts != provider.session {
+		provider.session = opts
+		// list keys with the new credentials
+		if provider.service, err = newKMSService(*provider.service.config.Region, provider.endpoint, opts); err != nil {
+			return
+		}
+	}
+
+	if pairs, hasContext := args["kms-context"].([]string); hasContext {
+		if provider.context, err = contextFromPairs(pairs); err != nil {
+			return
+		}
+	}
+
 	if value, exists := args["key"]; exists {
 		if keyString, isString := value.(string); isString {
 			if err = validKey(keyString); err != nil {
@@ -95,25 +256,49 @@ func (provider *Provider) Replace(args map[string]interface{}) (err error) {
 	}
 
 	if key == "" {
-		keys, err := provider.service.ListKeys()
+		filter := keyFilter{}
+		filter.regions, _ = args["kms-region"].([]string)
+		filter.aliasPrefix, _ = args["kms-alias-prefix"].(string)
+
+		keys, err := provider.service.ListKeys(filter)
 		if err != nil {
-			return fmt.Errorf("Failed to list keys: %s", err)
+			if len(keys) == 0 {
+				return fmt.Errorf("Failed to list keys: %s", err)
+			}
+			log.Warn(err)
+		}
+
+		items := make([]input.Item, len(keys))
+		for index, key := range keys {
+			items[index] = key.item()
 		}
 
-		responses, err := input.SelectionFromList(keys, "kms", false)
+		responses, err := input.SelectionFromItems(items, "kms", false)
 		if err != nil {
 			return err
 		}
 		key = responses[0]
 	}
 
+	replicas := []string{}
+	for _, replica := range provider.replicas {
+		replicas = append(replicas, replica.key)
+	}
+	if keys, hasReplicas := args["kms-replica"].([]string); hasReplicas {
+		replicas = keys
+	} else if key != provider.key && len(replicas) > 0 {
+		log.Warnf("Dropping the replicas of %s, pass --kms-replica to set the replicas of %s", provider.key, key)
+		replicas = nil
+	}
+
 	provider.key = key
 	pieces := strings.Split(key, ":")
 	region := pieces[3]
-	kmsSvc := newKMSService(region)
-	provider.service = kmsSvc
+	if provider.service, err = newKMSService(region, provider.endpoint, provider.session); err != nil {
+		return
+	}
 
-	return
+	return provider.setReplicas(replicas)
 }
 
 // Serialize into a map of config for later hydration
@@ -121,9 +306,153 @@ func (provider *Provider) Serialize() (serialized map[string]interface{}) {
 	serialized = make(map[string]interface{})
 	serialized["key"] = provider.key
 	serialized["provider"] = "kms"
+	if provider.endpoint != "" {
+		serialized["endpoint"] = provider.endpoint
+	}
+	provider.session.serialize(serialized)
+	if len(provider.replicas) > 0 {
+		replicas := []interface{}{}
+		for _, replica := range provider.replicas {
+			replicas = append(replicas, replica.key)
+		}
+		serialized["replicas"] = replicas
+	}
+	if provider.context != nil {
+		context := map[string]interface{}{}
+		for key, value := range provider.context {
+			context[key] = value
+		}
+		serialized["context"] = context
+	}
 	return
 }
 
+// Arguments returns the key to use again without listing keys
+func (provider *Provider) Arguments() map[string]interface{} {
+	return map[string]interface{}{"key": provider.key}
+}
+
+// encryptionContext returns the context for a secret at `keyPath`, or nil if secrets aren't bound to one
+func (provider *Provider) encryptionContext(keyPath string) map[string]*string {
+	if provider.context == nil {
+		return nil
+	}
+
+	context := map[string]*string{}
+	for key, value := range provider.context {
+		context[key] = aws.String(value)
+	}
+
+	if keyPath != "" {
+		context[contextKeyPath] = aws.String(keyPath)
+	}
+
+	return context
+}
+
+// setReplicas validates replica `keys` and creates a service for each of their regions
+func (provider *Provider) setReplicas(keys []string) (err error) {
+	regions := map[string]string{}
+	if provider.key != "initialization-temporary-key" {
+		regions[*provider.service.config.Region] = provider.key
+	}
+
+	replicas := []replica{}
+	for _, key := range keys {
+		if err = validKey(key); err != nil {
+			return fmt.Errorf("Invalid replica: %s", err)
+		}
+
+		region := strings.Split(key, ":")[3]
+		if other, exists := regions[region]; exists {
+			return fmt.Errorf("Replica <%s> is in the same region as <%s>, replicas must be in other regions", key, other)
+		}
+		regions[region] = key
+
+		svc, err := newKMSService(region, provider.endpoint, provider.session)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, replica{key: key, service: svc})
+	}
+
+	provider.replicas = replicas
+	return nil
+}
+
+// replicasFromConfig reads `crypto.replicas`
+func replicasFromConfig(config interface{}) (keys []string, err error) {
+	switch values := config.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return values, nil
+	case []interface{}:
+		for _, value := range values {
+			key, isString := value.(string)
+			if !isString {
+				return nil, fmt.Errorf("Invalid crypto.replicas, expected a list of key ARNs but got <%v>", value)
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("Invalid crypto.replicas, expected a list of key ARNs but got <%v>", config)
+	}
+}
+
+// contextFromConfig reads `crypto.context`
+func contextFromConfig(config interface{}) (context map[string]string, err error) {
+	switch values := config.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		context = map[string]string{}
+		for key, value := range values {
+			if key == contextKeyPath {
+				return nil, fmt.Errorf("Invalid crypto.context, <%s> is reserved for the keypath of each secret", key)
+			}
+			context[key] = fmt.Sprintf("%v", value)
+		}
+		return context, nil
+	default:
+		return nil, fmt.Errorf("Invalid crypto.context, expected a map of strings but got <%v>", config)
+	}
+}
+
+// contextFromPairs reads `key=value` pairs from the command line
+func contextFromPairs(pairs []string) (map[string]string, error) {
+	context := map[string]interface{}{}
+	for _, pair := range pairs {
+		pieces := strings.SplitN(pair, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return nil, fmt.Errorf("Invalid encryption context pair <%s>, expected `key=value`", pair)
+		}
+		context[pieces[0]] = pieces[1]
+	}
+
+	return contextFromConfig(context)
+}
+
+// item shows a key's region, state and description when selecting one
+func (key kmsKey) item() input.Item {
+	state := "enabled"
+	if !key.enabled {
+		state = "disabled"
+	}
+
+	details := []string{key.region, state}
+	if key.description != "" {
+		details = append(details, key.description)
+	}
+
+	return input.Item{
+		Value:   key.arn,
+		Label:   key.alias,
+		Details: strings.Join(details, ", "),
+	}
+}
+
 func validKey(key string) (err error) {
 	if !strings.Contains(key, "arn:aws:kms:") {
 		err = fmt.Errorf("Unable to infer region from non fully-qualified KMS key ARN <%s>", key)
@@ -0,0 +1,210 @@
+package kms_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blinkhealth/go-config-yourself/internal/fakekms"
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/kms"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+var goodKey = fmt.Sprintf("arn:aws:kms:us-east-1:%s:key/00000000-0000-0000-0000-000000000000", fakekms.Account)
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	fx.MockAWS()
+	os.Unsetenv("AWS_KMS_ENDPOINT")
+	os.Unsetenv("GCY_AGENT_SOCK")
+	os.Exit(m.Run())
+}
+
+func TestKMSEndpoint(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+
+	provider, err := kms.New(map[string]interface{}{"key": goodKey, "endpoint": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cipherText, err := provider.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized := provider.Serialize()
+	if serialized["endpoint"] != server.URL {
+		t.Fatalf("Serialized wrong config: %v", serialized)
+	}
+
+	hydrated, _ := kms.New(serialized)
+	plainText, err := hydrated.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+
+	denied, _ := kms.New(map[string]interface{}{
+		"key":      strings.Replace(goodKey, fakekms.Account, fakekms.DeniedAccount, 1),
+		"endpoint": server.URL,
+	})
+	if _, err = denied.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "AWS denied access") {
+		t.Fatalf("Encrypted with a denied key: %v", err)
+	}
+}
+
+func TestKMSEndpointFromEnvironment(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+	os.Setenv("AWS_KMS_ENDPOINT", server.URL)
+	defer os.Unsetenv("AWS_KMS_ENDPOINT")
+
+	provider, _ := kms.New(map[string]interface{}{})
+	if err := provider.Replace(map[string]interface{}{"key": goodKey}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Encrypt([]byte("plaintext")); err != nil {
+		t.Fatal(err)
+	}
+	if _, hasEndpoint := provider.Serialize()["endpoint"]; hasEndpoint {
+		t.Fatalf("Serialized the endpoint from the environment: %v", provider.Serialize())
+	}
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "ABADACCESSKEYID")
+	defer fx.MockAWS()
+	provider, _ = kms.New(map[string]interface{}{"key": goodKey})
+	if _, err := provider.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "security token included in the request is invalid") {
+		t.Fatalf("Encrypted with bad credentials: %v", err)
+	}
+}
+
+func TestKMSEncryptionContext(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+
+	if _, err := kms.New(map[string]interface{}{"key": goodKey, "context": map[string]interface{}{"keypath": "a"}}); err == nil {
+		t.Fatal("Accepted a reserved context key")
+	}
+
+	crypto, err := kms.New(map[string]interface{}{
+		"key":      goodKey,
+		"endpoint": server.URL,
+		"context":  map[string]interface{}{"app": "billing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := crypto.(pvd.ContextualCrypto)
+
+	cipherText, err := provider.EncryptAt("db.password", []byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plainText, err := provider.DecryptAt("db.password", cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+
+	_, err = provider.DecryptAt("db.user", cipherText)
+	if err == nil || !strings.Contains(err.Error(), "{app: billing, keypath: db.user}") {
+		t.Fatalf("Decrypted with a different keypath: %v", err)
+	}
+
+	if err = crypto.Replace(map[string]interface{}{"key": goodKey, "kms-context": []string{"app"}}); err == nil {
+		t.Fatal("Accepted an invalid context pair")
+	}
+
+	if err = crypto.Replace(map[string]interface{}{"key": goodKey, "kms-context": []string{"app=payroll", "team=finance"}}); err != nil {
+		t.Fatal(err)
+	}
+	context, _ := crypto.Serialize()["context"].(map[string]interface{})
+	if len(context) != 2 || context["app"] != "payroll" || context["team"] != "finance" {
+		t.Fatalf("Serialized wrong context: %v", crypto.Serialize())
+	}
+}
+
+func TestKMSAssumeRole(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+	os.Setenv("AWS_STS_ENDPOINT", server.URL)
+	defer os.Unsetenv("AWS_STS_ENDPOINT")
+
+	roleKey := strings.Replace(goodKey, fakekms.Account, fakekms.RoleAccount, 1)
+	config := map[string]interface{}{"key": roleKey, "endpoint": server.URL}
+	provider, _ := kms.New(config)
+	if _, err := provider.Encrypt([]byte("plaintext")); err == nil {
+		t.Fatal("Encrypted without assuming a role")
+	}
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/app", fakekms.RoleAccount)
+	if err := provider.Replace(map[string]interface{}{"key": roleKey, "kms-role-arn": role}); err != nil {
+		t.Fatal(err)
+	}
+	cipherText, err := provider.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized := provider.Serialize()
+	if serialized["role_arn"] != role {
+		t.Fatalf("Serialized wrong config: %v", serialized)
+	}
+	hydrated, _ := kms.New(serialized)
+	if plainText, err := hydrated.Decrypt(cipherText); err != nil || plainText != "plaintext" {
+		t.Fatalf("Could not decrypt with assumed role: %s %v", plainText, err)
+	}
+
+	config["role_arn"] = fmt.Sprintf("arn:aws:iam::%s:role/external", fakekms.RoleAccount)
+	provider, _ = kms.New(config)
+	if _, err := provider.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "without its external id") {
+		t.Fatalf("Assumed a role without its external id: %v", err)
+	}
+
+	config["external_id"] = fakekms.ExternalID
+	provider, _ = kms.New(config)
+	if _, err := provider.Encrypt([]byte("plaintext")); err != nil {
+		t.Fatalf("Could not assume a role with its external id: %s", err)
+	}
+}
+
+func TestKMSProfile(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+
+	credentials, _ := ioutil.TempFile("", "gcy-aws-credentials")
+	defer os.Remove(credentials.Name())
+	fmt.Fprintf(credentials, "[gcy-test]\naws_access_key_id = %s\naws_secret_access_key = secret\n", fakekms.GoodAccessKey)
+	credentials.Close()
+
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials.Name())
+	os.Setenv("AWS_ACCESS_KEY_ID", "ABADACCESSKEYID")
+	defer fx.MockAWS()
+
+	config := map[string]interface{}{"key": goodKey, "endpoint": server.URL, "profile": "missing"}
+	missing, _ := kms.New(config)
+	if _, err := missing.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "for profile <missing>") {
+		t.Fatalf("Loaded a missing profile: %v", err)
+	}
+
+	// the file's profile wins over credentials in the environment
+	config["profile"] = "gcy-test"
+	provider, err := kms.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Encrypt([]byte("plaintext")); err != nil {
+		t.Fatalf("Could not encrypt with profile: %s", err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/sts"
@@ -33,132 +32,238 @@ type kmsService struct {
 	session *session.Session
 	config  *aws.Config
 	client  kmsiface.KMSAPI
+	opts    sessionOptions
 }
 
-func stdinTokenProvider() (string, error) {
-	var v string
-	fmt.Fprintf(os.Stderr, "Enter an MFA token code for profile %s: ", os.Getenv("AWS_PROFILE"))
-	_, err := fmt.Scanln(&v)
-
-	return v, err
-}
-
-func createAWSSession(region string) (sess *session.Session, config *aws.Config) {
-	config = aws.NewConfig().WithRegion(region)
-	sess = session.Must(session.NewSessionWithOptions(session.Options{
-		Config:                  *config,
-		AssumeRoleTokenProvider: stdinTokenProvider,
-		SharedConfigState:       session.SharedConfigEnable,
-	}))
-	return
-}
-
-func newKMSService(region string) (svc *kmsService) {
+// newKMSService creates a service for `region`, talking to `endpoint` or AWS_KMS_ENDPOINT instead of AWS when set
+func newKMSService(region string, endpoint string, opts sessionOptions) (svc *kmsService, err error) {
 	if region == "" {
 		region = "us-east-1"
 	}
-	awsSess, awsConfig := createAWSSession(region)
+	awsSess, awsConfig, err := createAWSSession(region, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_KMS_ENDPOINT")
+	}
+
+	if endpoint != "" {
+		log.Debugf("Using KMS endpoint %s", endpoint)
+		// only the kms client gets the endpoint, sts still talks to AWS
+		awsConfig = awsConfig.Copy().WithEndpoint(endpoint)
+	}
 
 	return &kmsService{
 		session: awsSess,
 		config:  awsConfig,
 		client:  newKMSClient(awsSess, awsConfig),
-	}
+		opts:    opts,
+	}, nil
 }
 
-// Encrypt a string with a kms key
-func (svc *kmsService) Encrypt(key string, plainText []byte) ([]byte, error) {
+// Encrypt a string with a kms key, binding it to `context` when not nil
+func (svc *kmsService) Encrypt(key string, plainText []byte, context map[string]*string) ([]byte, error) {
 	result, err := svc.client.Encrypt(&awsKMS.EncryptInput{
-		KeyId:     &key,
-		Plaintext: plainText,
+		KeyId:             &key,
+		Plaintext:         plainText,
+		EncryptionContext: context,
 	})
 
 	if err != nil {
-		return nil, catchBadCredentials(err, svc.session, key)
+		return nil, catchBadCredentials(err, svc, key)
 	}
 
 	return result.CiphertextBlob, nil
 }
 
-// Decrypt a some bytes
-func (svc *kmsService) Decrypt(encryptedBytes []byte) (string, error) {
-	out, err := svc.client.Decrypt(&awsKMS.DecryptInput{
-		CiphertextBlob: encryptedBytes,
+// Decrypt a some bytes, encrypted with `encryptionContext`
+//
+// Ciphertexts record the key they were encrypted with, and replicas of multi-region keys share its id, so any
+// region holding a replica can decrypt them
+func (svc *kmsService) Decrypt(ctx aws.Context, encryptedBytes []byte, encryptionContext map[string]*string) (string, error) {
+	out, err := svc.client.DecryptWithContext(ctx, &awsKMS.DecryptInput{
+		CiphertextBlob:    encryptedBytes,
+		EncryptionContext: encryptionContext,
 	})
 	if err != nil {
-		return "", catchBadCredentials(err, svc.session, "")
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvalidCiphertextException" {
+			if encryptionContext != nil {
+				// kms won't tell a tampered ciphertext from one encrypted with a different context
+				return "", ciphertextError(fmt.Sprintf("KMS rejected the ciphertext, it was either encrypted with a different context than %s or is corrupted", describeContext(encryptionContext)))
+			}
+			return "", ciphertextError(awsErr.Error())
+		}
+		return "", catchBadCredentials(err, svc, "")
 	}
 
 	return string(out.Plaintext), err
 }
 
-// ListKeys offers a list of kms keys on all regions
+// ciphertextError is returned when KMS rejects a ciphertext itself, which no other region would accept either
+type ciphertextError string
+
+func (err ciphertextError) Error() string {
+	return string(err)
+}
+
+// kmsKey describes a customer key found through one of its aliases
+type kmsKey struct {
+	// The alias ARN, stored as `crypto.key` when selected
+	arn         string
+	alias       string
+	region      string
+	description string
+	enabled     bool
+}
+
+// keyFilter narrows down key discovery
+type keyFilter struct {
+	// Regions to query, all of them when empty
+	regions []string
+	// Only list aliases starting with this prefix, with or without `alias/`
+	aliasPrefix string
+}
+
+// regionKeys are the results of listing keys in a region
+type regionKeys struct {
+	region string
+	keys   []kmsKey
+	err    error
+}
+
+// ListKeys offers a list of kms keys on all regions, or the ones in `filter`
+//
+// Regions are queried concurrently, and keys are returned along with an error describing every region that failed.
 // Calls that fail with UnrecognizedClientException will be ignored, since some regions might not be enabled
 // by default (https://docs.aws.amazon.com/general/latest/gr/rande.html).
-func (svc *kmsService) ListKeys() (keys []string, err error) {
-	finished := make(chan bool, 1)
-	listingErrors := make(chan error, 1)
-	var wg sync.WaitGroup
-
-	// Query every region for their known kms keys
-	for _, region := range listRegions() {
-		region := region
-		wg.Add(1)
-		go func(region string, listingErrors chan<- error) {
-			regionConfig := svc.config.Copy(&aws.Config{Region: &region})
-			client := newKMSClient(svc.session, regionConfig)
-			defer wg.Done()
-			log.Debugf("Querying for keys in %s", region)
-			regionKeys, err := fetchAllKeys(client, nil)
-			if err = catchBadCredentials(err, svc.session, region); err != nil {
-				if awsErr, ok := err.(awserr.Error); ok {
-					if awsErr.Code() != "UnrecognizedClientException" {
-						listingErrors <- err
-						return
-					}
-
-					log.Warningf("Unable to query possibly disabled region %s for keys, ignoring", region)
-				} else {
-					listingErrors <- fmt.Errorf("Error querying for keys in %s: %s", region, err)
-					return
-				}
+func (svc *kmsService) ListKeys(filter keyFilter) (keys []kmsKey, err error) {
+	regions := filter.regions
+	if len(regions) == 0 {
+		regions = listRegions()
+		if svc.config.Endpoint != nil {
+			// a custom endpoint serves a single region
+			regions = []string{*svc.config.Region}
+		}
+	} else if svc.config.Endpoint == nil {
+		known := listRegions()
+		sort.Strings(known)
+		for _, region := range regions {
+			if index := sort.SearchStrings(known, region); index == len(known) || known[index] != region {
+				return nil, fmt.Errorf("Unknown AWS region <%s>", region)
 			}
+		}
+	}
 
-			log.Debugf("Found %d customer keys on %s", len(regionKeys), region)
-
-			if len(regionKeys) > 0 {
-				keys = append(keys, regionKeys...)
-			}
-		}(region, listingErrors)
+	// every region reports back exactly once, so the channel never blocks
+	results := make(chan regionKeys, len(regions))
+	for _, region := range regions {
+		go func(region string) {
+			log.Debugf("Querying for keys in %s", region)
+			keys, err := svc.listRegionKeys(region, filter.aliasPrefix)
+			results <- regionKeys{region: region, keys: keys, err: err}
+		}(region)
 	}
 
-	go func() {
-		// Wait for all of querying to be done
-		wg.Wait()
-		close(finished)
-		close(listingErrors)
-	}()
+	failures := []string{}
+	for range regions {
+		result := <-results
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", result.region, result.err))
+			continue
+		}
+		log.Debugf("Found %d customer keys on %s", len(result.keys), result.region)
+		keys = append(keys, result.keys...)
+	}
 
-	select {
-	case <-finished:
-	case err = <-listingErrors:
-		if err != nil {
-			return keys, err
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].alias != keys[j].alias {
+			return keys[i].alias < keys[j].alias
 		}
+		return keys[i].region < keys[j].region
+	})
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return keys, fmt.Errorf("Could not list keys in %d region(s): %s", len(failures), strings.Join(failures, "; "))
 	}
 
 	if len(keys) < 1 {
 		err = errors.New("Could not find any KMS keys")
-		return
+		if filter.aliasPrefix != "" {
+			err = fmt.Errorf("Could not find any KMS keys with aliases starting with <%s>", filter.aliasPrefix)
+		}
 	}
 
-	sort.Strings(keys)
 	return
 }
 
-func fetchAllKeys(client kmsiface.KMSAPI, nextMarker *string) (result []string, err error) {
+// listRegionKeys describes the customer keys in `region` with aliases starting with `aliasPrefix`
+func (svc *kmsService) listRegionKeys(region string, aliasPrefix string) (keys []kmsKey, err error) {
+	regionConfig := svc.config.Copy(&aws.Config{Region: &region})
+	regionSvc := &kmsService{
+		session: svc.session,
+		config:  regionConfig,
+		client:  newKMSClient(svc.session, regionConfig),
+		opts:    svc.opts,
+	}
+
 	ctx, cancelFn := context.WithTimeout(context.Background(), keyListTimeout)
 	defer cancelFn()
+
+	aliases, err := fetchAllAliases(ctx, regionSvc.client, nil)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "UnrecognizedClientException" {
+			log.Warningf("Unable to query possibly disabled region %s for keys, ignoring", region)
+			return nil, nil
+		}
+		return nil, catchBadCredentials(err, regionSvc, "")
+	}
+
+	aliasPrefix = strings.TrimPrefix(aliasPrefix, "alias/")
+	descriptions := map[string]*awsKMS.KeyMetadata{}
+	for _, alias := range aliases {
+		name := strings.TrimPrefix(aws.StringValue(alias.AliasName), "alias/")
+		// filters out kms keys created by AWS, and aliases not pointing to a key
+		if strings.HasPrefix(name, "aws/") || alias.TargetKeyId == nil || !strings.HasPrefix(name, aliasPrefix) {
+			continue
+		}
+
+		metadata, described := descriptions[*alias.TargetKeyId]
+		if !described {
+			resp, err := regionSvc.client.DescribeKeyWithContext(ctx, &awsKMS.DescribeKeyInput{KeyId: alias.TargetKeyId})
+			if err != nil {
+				return nil, catchBadCredentials(err, regionSvc, *alias.AliasArn)
+			}
+			metadata = resp.KeyMetadata
+			descriptions[*alias.TargetKeyId] = metadata
+		}
+
+		keys = append(keys, kmsKey{
+			arn:         *alias.AliasArn,
+			alias:       aws.StringValue(alias.AliasName),
+			region:      region,
+			description: aws.StringValue(metadata.Description),
+			enabled:     aws.BoolValue(metadata.Enabled),
+		})
+	}
+
+	return keys, nil
+}
+
+// describeContext renders an encryption context for error messages
+func describeContext(context map[string]*string) string {
+	pairs := []string{}
+	for key, value := range context {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", key, *value))
+	}
+	sort.Strings(pairs)
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
+func fetchAllAliases(ctx aws.Context, client kmsiface.KMSAPI, nextMarker *string) (result []*awsKMS.AliasListEntry, err error) {
 	resp, err := client.ListAliasesWithContext(ctx, &awsKMS.ListAliasesInput{
 		Limit:  &keyListQueryLimit,
 		Marker: nextMarker,
@@ -168,21 +273,12 @@ func fetchAllKeys(client kmsiface.KMSAPI, nextMarker *string) (result []string,
 		return nil, err
 	}
 
-	customerKeys := make([]string, 0)
-	for _, key := range resp.Aliases {
-		// filters out kms keys created by AWS
-		if strings.Contains(*key.AliasArn, "alias/aws") {
-			continue
-		}
-		customerKeys = append(customerKeys, *key.AliasArn)
-	}
-
-	if *resp.Truncated {
-		nextSet, err := fetchAllKeys(client, resp.NextMarker)
-		return append(customerKeys, nextSet...), err
+	if aws.BoolValue(resp.Truncated) {
+		nextSet, err := fetchAllAliases(ctx, client, resp.NextMarker)
+		return append(resp.Aliases, nextSet...), err
 	}
 
-	return customerKeys, nil
+	return resp.Aliases, nil
 }
 
 func listRegions() (regions []string) {
@@ -194,28 +290,34 @@ func listRegions() (regions []string) {
 	return
 }
 
-func catchBadCredentials(err error, sess *session.Session, key string) error {
+func catchBadCredentials(err error, svc *kmsService, key string) error {
+	sess := svc.session
 	if awsErr, ok := err.(awserr.Error); ok {
 		code := awsErr.Code()
 		switch code {
 		case "AccessDeniedException":
-			msg := fmt.Sprintf("AWS denied access in region <%s>", *sess.Config.Region)
+			msg := fmt.Sprintf("AWS denied access in region <%s>", *svc.config.Region)
 			if key != "" {
 				msg = fmt.Sprintf("%s using key <%s>", msg, key)
 			}
 
-			stsClient := sts.New(sess)
-			result, stsErr := stsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
-			if stsErr == nil {
-				msg = fmt.Sprintf("%s for identity <%s>", msg, *result.Arn)
+			// stand-ins for kms can't vouch for identities
+			if svc.config.Endpoint == nil {
+				stsClient := newSTSClient(sess)
+				result, stsErr := stsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+				if stsErr == nil {
+					msg = fmt.Sprintf("%s for identity <%s>", msg, *result.Arn)
+				}
 			}
 
 			return fmt.Errorf("%s (%s)", msg, code)
 		case "NoCredentialProviders":
+			if svc.opts.profile != "" {
+				return fmt.Errorf("No AWS credentials found for profile <%s>", svc.opts.profile)
+			}
 			return errors.New("No AWS credentials found")
 		case "RequestCanceled":
-			log.Warnf("Timed out before being able to fetch keys from region <%s>", key)
-			return nil
+			return errors.New("Timed out waiting for KMS")
 		}
 	}
 
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -38,8 +39,13 @@ var mockKeys = []*mockKey{
 	},
 }
 
-// newKMSClient creates a mock KMSClient
+// newKMSClient creates a mock KMSClient, or a real one when talking to a stand-in endpoint
 func newKMSClient(sess *session.Session, config *aws.Config) kmsiface.KMSAPI {
+	if config.Endpoint != nil {
+		log.Debugf("Initializing KMS client for %s", *config.Endpoint)
+		return awsKMS.New(sess, config)
+	}
+
 	log.Debug("Initializing mock KMS client")
 	region := *config.Region
 	creds, err := sess.Config.Credentials.Get()
@@ -63,9 +69,10 @@ type mockKMSClient struct {
 }
 
 type mockSecret struct {
-	Key   string
-	Value []byte
-	Nonce string
+	Key     string
+	Value   []byte
+	Nonce   string
+	Context map[string]*string
 }
 
 func (m *mockKMSClient) Encrypt(input *awsKMS.EncryptInput) (*awsKMS.EncryptOutput, error) {
@@ -77,9 +84,10 @@ func (m *mockKMSClient) Encrypt(input *awsKMS.EncryptInput) (*awsKMS.EncryptOutp
 	}
 	o := &awsKMS.EncryptOutput{}
 	v, _ := json.Marshal(&mockSecret{
-		Key:   *input.KeyId,
-		Value: input.Plaintext,
-		Nonce: strconv.FormatInt(time.Now().UnixNano(), 10),
+		Key:     *input.KeyId,
+		Value:   input.Plaintext,
+		Nonce:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Context: input.EncryptionContext,
 	})
 
 	o.SetCiphertextBlob(v)
@@ -102,11 +110,19 @@ func (m *mockKMSClient) Decrypt(input *awsKMS.DecryptInput) (*awsKMS.DecryptOutp
 		return nil, err
 	}
 
+	if !reflect.DeepEqual(aws.StringValueMap(v.Context), aws.StringValueMap(input.EncryptionContext)) {
+		return nil, awserr.New("InvalidCiphertextException", "", nil)
+	}
+
 	o := &awsKMS.DecryptOutput{}
 	o.SetPlaintext(v.Value)
 	return o, nil
 }
 
+func (m *mockKMSClient) DecryptWithContext(context aws.Context, input *awsKMS.DecryptInput, opts ...request.Option) (*awsKMS.DecryptOutput, error) {
+	return m.Decrypt(input)
+}
+
 func (m *mockKMSClient) ListAliasesWithContext(context aws.Context, input *awsKMS.ListAliasesInput, opts ...request.Option) (*awsKMS.ListAliasesOutput, error) {
 	if !testValidAccessKey(m.accessKey) {
 		return nil, BadCreds
@@ -114,7 +130,9 @@ func (m *mockKMSClient) ListAliasesWithContext(context aws.Context, input *awsKM
 	regionalKey := strings.Replace(mockKeys[0].id, "us-east-1", m.region, 1)
 	aliases := []*awsKMS.AliasListEntry{
 		{
-			AliasArn: &regionalKey,
+			AliasArn:    &regionalKey,
+			AliasName:   aws.String("alias/gcy-test"),
+			TargetKeyId: &regionalKey,
 		},
 	}
 
@@ -125,6 +143,23 @@ func (m *mockKMSClient) ListAliasesWithContext(context aws.Context, input *awsKM
 	}, nil
 }
 
+func (m *mockKMSClient) DescribeKeyWithContext(context aws.Context, input *awsKMS.DescribeKeyInput, opts ...request.Option) (*awsKMS.DescribeKeyOutput, error) {
+	if !testValidAccessKey(m.accessKey) {
+		return nil, BadCreds
+	}
+	if err := testKeyValidity(*input.KeyId); err != nil {
+		return nil, err
+	}
+
+	return &awsKMS.DescribeKeyOutput{
+		KeyMetadata: &awsKMS.KeyMetadata{
+			Arn:         input.KeyId,
+			Description: aws.String("gcy test key"),
+			Enabled:     aws.Bool(true),
+		},
+	}, nil
+}
+
 func testKeyValidity(keyId string) (err error) {
 	if strings.Contains(keyId, ":000000000000:") {
 		return nil
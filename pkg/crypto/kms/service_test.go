@@ -0,0 +1,145 @@
+package kms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/internal/fakekms"
+)
+
+func TestListKeys(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+
+	svc, err := newKMSService("us-east-1", server.URL, sessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := svc.ListKeys(keyFilter{regions: []string{"us-west-2", "us-east-1", fakekms.OptInRegion}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := []string{}
+	for _, key := range keys {
+		found = append(found, fmt.Sprintf("%s %s %v", key.alias, key.region, key.enabled))
+	}
+	expected := "alias/gcy-old us-east-1 false, alias/gcy-old us-west-2 false, alias/gcy-test us-east-1 true, alias/gcy-test us-west-2 true"
+	if strings.Join(found, ", ") != expected {
+		t.Fatalf("Listed wrong keys: %v", found)
+	}
+
+	item := keys[2].item()
+	if item.Value != fmt.Sprintf("arn:aws:kms:us-east-1:%s:alias/gcy-test", fakekms.Account) || item.Details != "us-east-1, enabled, gcy test key" {
+		t.Fatalf("Offered wrong item: %v", item)
+	}
+
+	keys, err = svc.ListKeys(keyFilter{regions: []string{"us-east-1"}, aliasPrefix: "gcy-t"})
+	if err != nil || len(keys) != 1 || keys[0].alias != "alias/gcy-test" {
+		t.Fatalf("Listed wrong keys with a prefix: %v, %v", keys, err)
+	}
+
+	if _, err = svc.ListKeys(keyFilter{regions: []string{"us-east-1"}, aliasPrefix: "alias/billing-"}); err == nil || !strings.Contains(err.Error(), "starting with <alias/billing->") {
+		t.Fatalf("Found keys with a missing prefix: %v", err)
+	}
+}
+
+func TestListKeysErrors(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+
+	svc, err := newKMSService("us-east-1", server.URL, sessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := svc.ListKeys(keyFilter{regions: []string{"us-east-1", fakekms.DeniedRegion, fakekms.OptInRegion}})
+	if len(keys) != 2 {
+		t.Fatalf("Did not keep keys from working regions: %v", keys)
+	}
+	if err == nil || !strings.Contains(err.Error(), "in 1 region(s)") || !strings.Contains(err.Error(), fakekms.DeniedRegion+": AWS denied access in region <"+fakekms.DeniedRegion+">") {
+		t.Fatalf("Did not report failing regions: %v", err)
+	}
+
+	svc, _ = newKMSService("us-east-1", "", sessionOptions{})
+	if _, err = svc.ListKeys(keyFilter{regions: []string{"mars-north-1"}}); err == nil || !strings.Contains(err.Error(), "Unknown AWS region <mars-north-1>") {
+		t.Fatalf("Listed keys in an unknown region: %v", err)
+	}
+}
+
+func TestDecryptFromReplicas(t *testing.T) {
+	server := fakekms.Start()
+	defer server.Close()
+	defer func(timeout time.Duration) { replicaTimeout = timeout }(replicaTimeout)
+	replicaTimeout = 200 * time.Millisecond
+
+	multiRegionKey := func(region string) string {
+		return fmt.Sprintf("arn:aws:kms:%s:%s:key/mrk-00000000000000000000000000000000", region, fakekms.Account)
+	}
+
+	primary, err := New(map[string]interface{}{"key": multiRegionKey("us-east-1"), "endpoint": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText, err := primary.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the primary's region goes down
+	provider, err := New(map[string]interface{}{
+		"key":      multiRegionKey(fakekms.UnavailableRegion),
+		"endpoint": server.URL,
+		"replicas": []interface{}{multiRegionKey(fakekms.OptInRegion), multiRegionKey("us-west-2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plainText, err := provider.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+	served := provider.(*Provider).Report()
+	if served["region"] != "us-west-2" || served["key"] != multiRegionKey("us-west-2") {
+		t.Fatalf("Reported wrong region: %v", served)
+	}
+
+	replicas := provider.Serialize()["replicas"].([]interface{})
+	if len(replicas) != 2 || replicas[1] != multiRegionKey("us-west-2") {
+		t.Fatalf("Serialized wrong replicas: %v", replicas)
+	}
+
+	provider, _ = New(map[string]interface{}{
+		"key":      multiRegionKey(fakekms.UnavailableRegion),
+		"endpoint": server.URL,
+		"replicas": []interface{}{multiRegionKey(fakekms.OptInRegion)},
+	})
+	_, err = provider.Decrypt(cipherText)
+	if err == nil || !strings.Contains(err.Error(), fakekms.UnavailableRegion+": Timed out") || !strings.Contains(err.Error(), fakekms.OptInRegion+": ") {
+		t.Fatalf("Did not report every failing region: %v", err)
+	}
+
+	// rejected ciphertexts fail right away
+	contextual, _ := New(map[string]interface{}{
+		"key":      multiRegionKey(fakekms.UnavailableRegion),
+		"endpoint": server.URL,
+		"context":  map[string]interface{}{"app": "billing"},
+		"replicas": []interface{}{multiRegionKey("us-west-2")},
+	})
+	_, err = contextual.(*Provider).DecryptAt("secret", cipherText)
+	if _, rejected := err.(ciphertextError); !rejected {
+		t.Fatalf("Fell back after KMS rejected the ciphertext: %v", err)
+	}
+
+	_, err = New(map[string]interface{}{"key": multiRegionKey("us-east-1"), "replicas": []interface{}{multiRegionKey("us-east-1")}})
+	if err == nil || !strings.Contains(err.Error(), "same region") {
+		t.Fatalf("Accepted a replica in the same region: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package kms
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	log "github.com/sirupsen/logrus"
+)
+
+// sessionOptions select the credentials used for a file, from `crypto.profile`, `crypto.role_arn`,
+// `crypto.external_id` and `crypto.mfa_serial`
+type sessionOptions struct {
+	profile    string
+	roleArn    string
+	externalID string
+	mfaSerial  string
+}
+
+// credentials are cached for as long as gcy runs, so users enter MFA tokens once per file
+var (
+	credentialsCache     = map[sessionOptions]*credentials.Credentials{}
+	credentialsCacheLock sync.Mutex
+	// only one MFA prompt can read from stdin at a time
+	mfaPromptLock sync.Mutex
+)
+
+func sessionOptionsFromConfig(config map[string]interface{}) (opts sessionOptions) {
+	opts.profile, _ = config["profile"].(string)
+	opts.roleArn, _ = config["role_arn"].(string)
+	opts.externalID, _ = config["external_id"].(string)
+	opts.mfaSerial, _ = config["mfa_serial"].(string)
+	return
+}
+
+// replace overrides options with the ones present in `args`
+func (opts sessionOptions) replace(args map[string]interface{}) sessionOptions {
+	for name, option := range map[string]*string{
+		"kms-profile":     &opts.profile,
+		"kms-role-arn":    &opts.roleArn,
+		"kms-external-id": &opts.externalID,
+		"kms-mfa-serial":  &opts.mfaSerial,
+	} {
+		if value, ok := args[name].(string); ok && value != "" {
+			*option = value
+		}
+	}
+
+	return opts
+}
+
+func (opts sessionOptions) serialize(serialized map[string]interface{}) {
+	for name, value := range map[string]string{
+		"profile":     opts.profile,
+		"role_arn":    opts.roleArn,
+		"external_id": opts.externalID,
+		"mfa_serial":  opts.mfaSerial,
+	} {
+		if value != "" {
+			serialized[name] = value
+		}
+	}
+}
+
+func stdinTokenProvider(subject string) func() (string, error) {
+	return func() (string, error) {
+		mfaPromptLock.Lock()
+		defer mfaPromptLock.Unlock()
+
+		var v string
+		fmt.Fprintf(os.Stderr, "Enter an MFA token code for %s: ", subject)
+		_, err := fmt.Scanln(&v)
+
+		return v, err
+	}
+}
+
+func createAWSSession(region string, opts sessionOptions) (sess *session.Session, config *aws.Config, err error) {
+	profile := opts.profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+
+	config = aws.NewConfig().WithRegion(region)
+	cached := cachedCredentials(opts)
+	if cached != nil {
+		config = config.WithCredentials(cached)
+	}
+
+	sess, err = session.NewSessionWithOptions(session.Options{
+		Config:                  *config,
+		Profile:                 opts.profile,
+		AssumeRoleTokenProvider: stdinTokenProvider(fmt.Sprintf("profile %s", profile)),
+		SharedConfigState:       session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not load AWS profile <%s>: %s", profile, err)
+	}
+
+	if cached != nil || opts == (sessionOptions{}) {
+		// ambient credentials are cheap to resolve again, and might change between calls
+		return
+	}
+
+	creds := sess.Config.Credentials
+	if opts.roleArn != "" {
+		log.Debugf("Assuming role %s", opts.roleArn)
+		creds = stscreds.NewCredentialsWithClient(newSTSClient(sess), opts.roleArn, func(provider *stscreds.AssumeRoleProvider) {
+			provider.RoleSessionName = fmt.Sprintf("gcy-%d", time.Now().Unix())
+			if opts.externalID != "" {
+				provider.ExternalID = aws.String(opts.externalID)
+			}
+			if opts.mfaSerial != "" {
+				provider.SerialNumber = aws.String(opts.mfaSerial)
+				provider.TokenProvider = stdinTokenProvider(fmt.Sprintf("role %s", opts.roleArn))
+			}
+		})
+		sess = sess.Copy(&aws.Config{Credentials: creds})
+	}
+
+	credentialsCacheLock.Lock()
+	credentialsCache[opts] = creds
+	credentialsCacheLock.Unlock()
+
+	return
+}
+
+func cachedCredentials(opts sessionOptions) *credentials.Credentials {
+	credentialsCacheLock.Lock()
+	defer credentialsCacheLock.Unlock()
+
+	return credentialsCache[opts]
+}
+
+// newSTSClient talks to AWS_STS_ENDPOINT instead of AWS when set
+func newSTSClient(sess *session.Session) *sts.STS {
+	config := aws.NewConfig()
+	if endpoint := os.Getenv("AWS_STS_ENDPOINT"); endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+
+	return sts.New(sess, config)
+}
@@ -0,0 +1,219 @@
+package password
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	kdfArgon2id = "argon2id"
+	kdfScrypt   = "scrypt"
+
+	// Recommended by RFC 9106 for memory-constrained environments
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+
+	// tuning stops at these, to prevent runaway benchmarks from locking files forever
+	argon2MaxTime = 64
+	scryptMaxCost = 1 << 24
+
+	derivedKeySize = 32
+)
+
+// KDFList enumerates the supported key derivation functions
+var KDFList = []string{kdfArgon2id, kdfScrypt}
+
+// kdf derives keys from passwords with a given algorithm and its parameters
+type kdf struct {
+	Name string
+	// argon2id parameters
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	// scrypt parameters
+	Cost int
+	R    int
+	P    int
+}
+
+// legacyKDF returns the scrypt parameters used before they were persisted in `crypto.kdf`
+func legacyKDF() *kdf {
+	return &kdf{Name: kdfScrypt, Cost: scryptCost, R: scryptR, P: scryptP}
+}
+
+// newKDF returns a kdf with default parameters for `name`
+func newKDF(name string) (*kdf, error) {
+	switch name {
+	case "", kdfArgon2id:
+		return &kdf{Name: kdfArgon2id, Time: argon2Time, Memory: argon2Memory, Threads: argon2Threads}, nil
+	case kdfScrypt:
+		return legacyKDF(), nil
+	}
+
+	return nil, fmt.Errorf("Unknown key derivation function <%s>, use one of %v", name, KDFList)
+}
+
+// kdfFromConfig reads `crypto.kdf`, defaulting to legacy scrypt parameters when absent
+func kdfFromConfig(config interface{}) (k *kdf, err error) {
+	if config == nil {
+		log.Debug("No crypto.kdf found, using legacy scrypt parameters")
+		return legacyKDF(), nil
+	}
+
+	params, isMap := config.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("Could not load password service, crypto.kdf is not a map")
+	}
+
+	name, _ := params["name"].(string)
+	if k, err = newKDF(name); err != nil {
+		return nil, err
+	}
+
+	// params missing from the file keep their defaults
+	number := func(key string) (int, error) {
+		value, exists := params[key]
+		if !exists {
+			return 0, nil
+		}
+		n, isInt := value.(int)
+		if !isInt || n < 1 {
+			return 0, fmt.Errorf("Could not load password service, crypto.kdf.%s must be a positive integer", key)
+		}
+		return n, nil
+	}
+
+	switch k.Name {
+	case kdfArgon2id:
+		var t, m, p int
+		if t, err = number("time"); err != nil {
+			return
+		}
+		if m, err = number("memory"); err != nil {
+			return
+		}
+		if p, err = number("threads"); err != nil {
+			return
+		}
+		if t > 0 {
+			k.Time = uint32(t)
+		}
+		if m > 0 {
+			k.Memory = uint32(m)
+		}
+		if p > 0 {
+			if p > 255 {
+				return nil, fmt.Errorf("Could not load password service, crypto.kdf.threads must be at most 255")
+			}
+			k.Threads = uint8(p)
+		}
+	case kdfScrypt:
+		var n, r, p int
+		if n, err = number("cost"); err != nil {
+			return
+		}
+		if r, err = number("r"); err != nil {
+			return
+		}
+		if p, err = number("p"); err != nil {
+			return
+		}
+		if n > 0 {
+			k.Cost = n
+		}
+		if r > 0 {
+			k.R = r
+		}
+		if p > 0 {
+			k.P = p
+		}
+	}
+
+	return k, nil
+}
+
+// Key derives a key from `password` and `salt`
+func (k *kdf) Key(password []byte, salt []byte) ([]byte, error) {
+	if k.Name == kdfArgon2id {
+		return argon2.IDKey(password, salt, k.Time, k.Memory, k.Threads, derivedKeySize), nil
+	}
+
+	return scrypt.Key(password, salt, k.Cost, k.R, k.P, derivedKeySize)
+}
+
+// Tune raises the cost of this kdf until deriving a key takes about `target` on this machine
+//
+// argon2id keeps its memory and threads, and scales its number of passes; scrypt doubles its cost. Parameters never go
+// below the defaults, however fast this machine is.
+func (k *kdf) Tune(target time.Duration) (err error) {
+	if target <= 0 {
+		return fmt.Errorf("Invalid kdf target <%s>, must be greater than zero", target)
+	}
+
+	password := []byte("benchmark")
+	salt := make([]byte, saltSize)
+	measure := func() (time.Duration, error) {
+		start := time.Now()
+		_, err := k.Key(password, salt)
+		return time.Since(start), err
+	}
+
+	switch k.Name {
+	case kdfArgon2id:
+		k.Time = 1
+		var elapsed time.Duration
+		if elapsed, err = measure(); err != nil {
+			return
+		}
+		// argon2 scales linearly with the number of passes
+		passes := uint32(target / elapsed)
+		if passes < argon2Time {
+			passes = argon2Time
+		}
+		if passes > argon2MaxTime {
+			passes = argon2MaxTime
+		}
+		k.Time = passes
+		log.Debugf("Tuned argon2id to %d passes (%s per pass) for a %s target", k.Time, elapsed, target)
+	case kdfScrypt:
+		k.Cost = scryptCost
+		for k.Cost < scryptMaxCost {
+			var elapsed time.Duration
+			if elapsed, err = measure(); err != nil {
+				return
+			}
+			// doubling the cost doubles the time it takes
+			if elapsed*2 > target {
+				break
+			}
+			k.Cost *= 2
+		}
+		log.Debugf("Tuned scrypt to N=%d for a %s target", k.Cost, target)
+	}
+
+	return nil
+}
+
+// Serialize renders the kdf parameters for `crypto.kdf`
+func (k *kdf) Serialize() map[string]interface{} {
+	if k.Name == kdfArgon2id {
+		return map[string]interface{}{
+			"name":    k.Name,
+			"time":    int(k.Time),
+			"memory":  int(k.Memory),
+			"threads": int(k.Threads),
+		}
+	}
+
+	return map[string]interface{}{
+		"name": k.Name,
+		"cost": k.Cost,
+		"r":    k.R,
+		"p":    k.P,
+	}
+}
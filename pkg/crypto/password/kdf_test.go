@@ -0,0 +1,92 @@
+package password
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+const testPassword = "correct horse battery staple"
+
+func TestPasswordKDF(t *testing.T) {
+	tests := []struct {
+		name          string
+		kdf           string
+		target        string
+		shouldExplode bool
+	}{
+		{"default", "", "", false},
+		{"argon2id", "argon2id", "", false},
+		{"scrypt", "scrypt", "", false},
+		{"argon2id-tuned", "argon2id", "10ms", false},
+		{"scrypt-tuned", "scrypt", "10ms", false},
+		{"unknown", "md5", "", true},
+		{"bad-target", "argon2id", "fast", true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			args := map[string]interface{}{
+				"password": testPassword,
+				"kdf":      tst.kdf,
+			}
+			if tst.target != "" {
+				args["kdf-target"] = tst.target
+			}
+
+			provider, err := New(map[string]interface{}{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = provider.Replace(args)
+			if tst.shouldExplode {
+				if err == nil {
+					t.Fatal("Expected to explode, did not")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Unable to create: %v", err)
+			}
+
+			serialized := provider.Serialize()
+			name := serialized["kdf"].(map[string]interface{})["name"]
+			if tst.kdf != "" && name != tst.kdf {
+				t.Fatalf("Created with wrong kdf: want %s, got: %s", tst.kdf, name)
+			}
+
+			cipherText, err := provider.Encrypt([]byte("asdf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			os.Setenv("CONFIG_PASSWORD", testPassword)
+			defer os.Unsetenv("CONFIG_PASSWORD")
+			loaded, err := New(serialized)
+			if err != nil {
+				t.Fatalf("Unable to load: %v", err)
+			}
+			value, err := loaded.Decrypt(cipherText)
+			if err != nil {
+				t.Fatalf("Unable to decrypt: %v", err)
+			}
+			if value != "asdf" {
+				t.Errorf("Decrypted wrong value: %v", value)
+			}
+		})
+	}
+}
+
+func TestTuneKeepsDefaults(t *testing.T) {
+	for _, name := range KDFList {
+		tuned, _ := newKDF(name)
+		if err := tuned.Tune(time.Nanosecond); err != nil {
+			t.Fatal(err)
+		}
+
+		defaults, _ := newKDF(name)
+		if tuned.Time < defaults.Time || tuned.Cost < defaults.Cost {
+			t.Fatalf("Tuned %s below its defaults: %v", name, tuned)
+		}
+	}
+}
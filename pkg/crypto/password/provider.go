@@ -15,6 +15,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/muesli/crunchy"
 	log "github.com/sirupsen/logrus"
@@ -42,23 +44,55 @@ func init() {
 			IsSwitch:    true,
 			Description: "Skips password validation, potentially making encrypted secrets easier to crack.",
 		},
+		{
+			Name:        "password-file",
+			Description: "Read the password from the first line of a file, instead of prompting for it",
+		},
+		{
+			Name:        "password-fd",
+			Description: "Read the password from an open file descriptor, instead of prompting for it",
+		},
+		{
+			Name:        "password-command",
+			Description: "Read the password from the output of a shell command, instead of prompting for it, for example: `pass show team/gcy`",
+		},
+		{
+			Name:        "kdf",
+			Description: fmt.Sprintf("The key derivation function used to turn the password into a key (one of: %s)", strings.Join(KDFList, ", ")),
+		},
+		{
+			Name:        "kdf-target",
+			Description: "Tunes the key derivation cost so deriving a key takes about this long on this machine, for example: `250ms`",
+		},
 	})
 }
 
 // Provider implements provider.Crypto for passwords
 type Provider struct {
 	service *passwordService
+	source  *passwordSource
+	// The password set by Replace, if any
+	password string
 }
 
 // New creates a new password.Provider and returns it
 func New(config map[string]interface{}) (pvd.Crypto, error) {
 	var service *passwordService
 
+	source, err := sourceFromArgs(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// we might be creating this provider from an existing file with a key
 	if stringKey, isString := config["key"].(string); isString {
-		var err error
-		log.Debug("Creating password service from key")
-		service, err = passwordServiceFromKey(stringKey)
+		keyDerivation, err := kdfFromConfig(config["kdf"])
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debugf("Creating password service from key, using %s", keyDerivation.Name)
+		service, err = passwordServiceFromKey(stringKey, keyDerivation)
 		if err != nil {
 			return nil, err
 		}
@@ -67,34 +101,68 @@ func New(config map[string]interface{}) (pvd.Crypto, error) {
 	// if flags are available, user is providing password from the tty during rekey
 	if flags, hasFlags := config["flags"]; hasFlags {
 		if password, hasPassword := flags.(map[string]interface{})["password"]; hasPassword {
-			var err error
 			passwordString, isString := password.(string)
 			if !isString {
 				return nil, fmt.Errorf("Unable to parse password as string")
 			}
 
 			log.Debug("Creating password service from password")
-			service, err = newPasswordService(passwordString)
+			keyDerivation, _ := newKDF(kdfArgon2id)
+			service, err = newPasswordService(passwordString, keyDerivation)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	return &Provider{service: service}, nil
+	return &Provider{service: service, source: source}, nil
 }
 
 // Replace the current data key with a new one, encrypting it with a different password
 //
-// Will prompt for a `password` unless present in `args` or is set as `CONFIG_PASSWORD` in the environment
+// Will prompt for a `password` unless present in `args`, readable from `password-file`, `password-fd` or
+// `password-command`, or is set as `CONFIG_PASSWORD` in the environment. The
+// key derivation function is selected by `kdf`, and tuned to take about `kdf-target` to run when present in `args`.
+// `kdf` may also hold the parameters returned by Arguments, to use them again without tuning
 func (provider *Provider) Replace(args map[string]interface{}) (err error) {
+	var keyDerivation *kdf
+	if params, isMap := args["kdf"].(map[string]interface{}); isMap {
+		keyDerivation, err = kdfFromConfig(params)
+	} else {
+		kdfName, _ := args["kdf"].(string)
+		keyDerivation, err = newKDF(kdfName)
+	}
+	if err != nil {
+		return
+	}
+
+	if target, hasTarget := args["kdf-target"].(string); hasTarget && target != "" {
+		var duration time.Duration
+		if duration, err = time.ParseDuration(target); err != nil {
+			return fmt.Errorf("Invalid kdf target <%s>: %s", target, err)
+		}
+
+		log.Infof("Tuning %s to take about %s", keyDerivation.Name, duration)
+		if err = keyDerivation.Tune(duration); err != nil {
+			return
+		}
+	}
+
 	var password string
 	if passwordKey, exists := args["password"]; exists {
 		password, _ = passwordKey.(string)
 	}
 
 	if password == "" {
-		password, err = getPassword("Enter the new password")
+		var source *passwordSource
+		if source, err = sourceFromArgs(args); err != nil {
+			return
+		}
+		if source == nil {
+			source = provider.source
+		}
+
+		password, err = getPassword("Enter the new password", source)
 		if err != nil {
 			return
 		}
@@ -110,8 +178,9 @@ func (provider *Provider) Replace(args map[string]interface{}) (err error) {
 		}
 	}
 
-	svc, err := newPasswordService(password)
+	svc, err := newPasswordService(password, keyDerivation)
 	provider.service = svc
+	provider.password = password
 	return err
 }
 
@@ -136,6 +205,15 @@ func (provider *Provider) Decrypt(data []byte) (plainText string, err error) {
 	return
 }
 
+// Arguments returns the new password and key derivation parameters, to use again without prompting or tuning
+func (provider *Provider) Arguments() map[string]interface{} {
+	args := map[string]interface{}{"password": provider.password}
+	if provider.service != nil {
+		args["kdf"] = provider.service.kdf.Serialize()
+	}
+	return args
+}
+
 // Serialize into a map of config for later hydration
 func (provider *Provider) Serialize() (serialized map[string]interface{}) {
 	serialized = make(map[string]interface{})
@@ -143,6 +221,8 @@ func (provider *Provider) Serialize() (serialized map[string]interface{}) {
 	if provider.service != nil {
 		// Serialize the service key
 		serialized["key"] = provider.service.Serialize()
+		// and the parameters needed to derive the key from the password again
+		serialized["kdf"] = provider.service.kdf.Serialize()
 	}
 	return
 }
@@ -153,9 +233,14 @@ func (provider *Provider) readyForCrypto() (err error) {
 		return
 	}
 
+	// a running agent might have unwrapped this key already
+	if provider.service.UnlockFromAgent() {
+		return
+	}
+
 	// get a password to decrypt the passwordService.key
 	var password string
-	password, err = getPassword("Please enter this file's password")
+	password, err = getPassword("Please enter this file's password", provider.source)
 	if err != nil {
 		return err
 	}
@@ -163,7 +248,11 @@ func (provider *Provider) readyForCrypto() (err error) {
 	return provider.service.DecryptKey(password)
 }
 
-func getPassword(promptText string) (password string, err error) {
+func getPassword(promptText string, source *passwordSource) (password string, err error) {
+	if source != nil {
+		return source.Read()
+	}
+
 	password, passwordInEnv := os.LookupEnv("CONFIG_PASSWORD")
 	if !passwordInEnv {
 		var secretBytes []byte
@@ -4,8 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 
-	"golang.org/x/crypto/scrypt"
-
+	"github.com/blinkhealth/go-config-yourself/internal/agent"
 	"github.com/blinkhealth/go-config-yourself/internal/datakey"
 )
 
@@ -21,6 +20,8 @@ const (
 	// > The recommended parameters for interactive logins as of 2017 are N=32768, r=8 and p=1.
 	// > The parameters N, r, and p should be increased as memory latency and CPU parallelism increases;
 	// > consider setting N to the highest power of 2 you can derive within 100 milliseconds.
+	//
+	// These were never persisted, so files without a `crypto.kdf` were created with them
 )
 
 type passwordService struct {
@@ -30,14 +31,12 @@ type passwordService struct {
 	dataKey *datakey.Service
 	// The salt used to hash this password
 	salt *[]byte
-}
-
-func keyFromPassword(password []byte, salt []byte) ([]byte, error) {
-	return scrypt.Key(password, salt, scryptCost, scryptR, scryptP, 32)
+	// The key derivation function used to hash this password
+	kdf *kdf
 }
 
 // Create a new password service from a password in plain-text
-func newPasswordService(passwordString string) (svc *passwordService, err error) {
+func newPasswordService(passwordString string, keyDerivation *kdf) (svc *passwordService, err error) {
 	var encryptedKey []byte
 	salt := make([]byte, saltSize)
 	if err = datakey.RandomBytes(&salt); err != nil {
@@ -45,7 +44,7 @@ func newPasswordService(passwordString string) (svc *passwordService, err error)
 	}
 
 	var secretKey []byte
-	secretKey, err = keyFromPassword([]byte(passwordString), salt)
+	secretKey, err = keyDerivation.Key([]byte(passwordString), salt)
 	if err != nil {
 		return nil, err
 	}
@@ -67,13 +66,14 @@ func newPasswordService(passwordString string) (svc *passwordService, err error)
 		encryptedKey: &encryptedKey,
 		dataKey:      datakey.NewService(fileKey),
 		salt:         &salt,
+		kdf:          keyDerivation,
 	}
 
 	return
 }
 
 // Hydrate a service from a persisted key
-func passwordServiceFromKey(key string) (svc *passwordService, err error) {
+func passwordServiceFromKey(key string, keyDerivation *kdf) (svc *passwordService, err error) {
 	var keyBytes []byte
 	if keyBytes, err = base64.StdEncoding.DecodeString(key); err != nil {
 		return svc, fmt.Errorf("Could not load password service, crypto.key is not valid base64. %s", err)
@@ -84,6 +84,7 @@ func passwordServiceFromKey(key string) (svc *passwordService, err error) {
 	svc = &passwordService{
 		encryptedKey: &encryptedKey,
 		salt:         &salt,
+		kdf:          keyDerivation,
 	}
 	return
 }
@@ -113,7 +114,7 @@ func (svc *passwordService) DecryptKey(password string) (err error) {
 	}
 
 	var secretKey []byte
-	secretKey, err = keyFromPassword([]byte(password), *svc.salt)
+	secretKey, err = svc.kdf.Key([]byte(password), *svc.salt)
 	if err != nil {
 		return err
 	}
@@ -124,9 +125,27 @@ func (svc *passwordService) DecryptKey(password string) (err error) {
 	}
 
 	svc.dataKey = datakey.NewService(dataKey)
+	agent.Put(svc.fingerprint(), dataKey)
 	return
 }
 
+// UnlockFromAgent fetches the decrypted key from a running agent, and tells if it succeeded
+func (svc *passwordService) UnlockFromAgent() bool {
+	if svc.encryptedKey == nil {
+		return false
+	}
+
+	dataKey, found := agent.Get(svc.fingerprint())
+	if found {
+		svc.dataKey = datakey.NewService(dataKey)
+	}
+	return found
+}
+
+func (svc *passwordService) fingerprint() string {
+	return agent.Fingerprint(*svc.salt, *svc.encryptedKey)
+}
+
 // Decrypt plaintext
 func (svc *passwordService) Decrypt(encryptedBytes []byte) (plainText string, err error) {
 	return svc.dataKey.Decrypt(encryptedBytes)
@@ -0,0 +1,118 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+)
+
+// passwordSource reads a password without prompting the user
+type passwordSource struct {
+	// A file containing the password
+	file string
+	// An open file descriptor to read the password from
+	fd int
+	// A shell command that prints the password to stdout
+	command string
+	// The password, once read, since file descriptors can only be read once
+	password *string
+}
+
+// sourceFromArgs returns a passwordSource for `password-file`, `password-fd` or `password-command`, if any are present.
+// A `password` that was already read, like one from a file descriptor shared by many files, is used as it is
+func sourceFromArgs(args map[string]interface{}) (src *passwordSource, err error) {
+	if password, isString := args["password"].(string); isString && password != "" {
+		return &passwordSource{fd: -1, password: &password}, nil
+	}
+
+	src = &passwordSource{fd: -1}
+	src.file, _ = args["password-file"].(string)
+	src.command, _ = args["password-command"].(string)
+
+	switch fd := args["password-fd"].(type) {
+	case int:
+		src.fd = fd
+	case string:
+		if fd != "" {
+			if src.fd, err = strconv.Atoi(fd); err != nil || src.fd < 0 {
+				return nil, fmt.Errorf("Invalid password file descriptor <%s>", fd)
+			}
+		}
+	}
+
+	sources := 0
+	for _, isSet := range []bool{src.file != "", src.fd >= 0, src.command != ""} {
+		if isSet {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return nil, fmt.Errorf("Only one of password-file, password-fd or password-command may be specified")
+	}
+
+	if sources == 0 {
+		return nil, nil
+	}
+
+	return src, nil
+}
+
+// ReadFD reads a password from the open file descriptor `fd`, and closes it. Since it can't be read again, share the
+// password with everything that needs it instead of the descriptor
+func ReadFD(fd int) (string, error) {
+	return (&passwordSource{fd: fd}).Read()
+}
+
+// Read returns the password from this source, without its trailing newline. Only the first line of files is read
+func (src *passwordSource) Read() (password string, err error) {
+	if src.password != nil {
+		return *src.password, nil
+	}
+
+	var raw []byte
+	switch {
+	case src.file != "":
+		log.Debugf("Reading password from file %s", src.file)
+		if raw, err = ioutil.ReadFile(src.file); err != nil {
+			return "", fmt.Errorf("Could not read password file: %s", err)
+		}
+		raw = bytes.SplitN(raw, []byte("\n"), 2)[0]
+	case src.fd >= 0:
+		log.Debugf("Reading password from file descriptor %d", src.fd)
+		fd := os.NewFile(uintptr(src.fd), fmt.Sprintf("password-fd-%d", src.fd))
+		if fd == nil {
+			return "", fmt.Errorf("Invalid password file descriptor <%d>", src.fd)
+		}
+		defer fd.Close()
+		if raw, err = ioutil.ReadAll(fd); err != nil {
+			return "", fmt.Errorf("Could not read password from file descriptor %d: %s", src.fd, err)
+		}
+	case src.command != "":
+		log.Debugf("Reading password from command `%s`", src.command)
+		// nolint:gosec
+		cmd := exec.Command("/bin/sh", "-c", src.command)
+		cmd.Env = input.SanitizedEnv()
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+		if raw, err = cmd.Output(); err != nil {
+			return "", fmt.Errorf("Password command failed: %s", err)
+		}
+	}
+
+	raw = bytes.TrimRight(raw, "\r\n")
+	if len(raw) == 0 {
+		return "", fmt.Errorf("No password supplied")
+	}
+
+	password = string(raw)
+	src.password = &password
+	return password, nil
+}
@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+)
+
+const (
+	opDescribe  = "describe"
+	opNew       = "new"
+	opReplace   = "replace"
+	opSerialize = "serialize"
+	opEncrypt   = "encrypt"
+	opDecrypt   = "decrypt"
+)
+
+// how long a plugin may take to describe itself, since that happens every time gcy starts
+var describeTimeout = 5 * time.Second
+
+// how long a plugin may take to exit once its stdin is closed
+var stopTimeout = 5 * time.Second
+
+// request is written to a plugin's stdin as a single line of JSON
+type request struct {
+	Op     string                 `json:"op"`
+	Config map[string]interface{} `json:"config,omitempty"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+	Data   []byte                 `json:"data,omitempty"`
+}
+
+// response is read from a plugin's stdout as a single line of JSON
+type response struct {
+	Flags   []pvd.Argument         `json:"flags,omitempty"`
+	Config  map[string]interface{} `json:"config,omitempty"`
+	Enabled bool                   `json:"enabled"`
+	Data    []byte                 `json:"data,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// process talks to a running plugin executable, starting it when needed
+type process struct {
+	path      string
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdoutEnd io.ReadCloser
+	stdout    *bufio.Reader
+}
+
+// start the plugin. An `isolated` plugin runs in its own process group, so it can be killed along with anything it
+// started, but it can't prompt on the terminal
+func (p *process) start(isolated bool) (err error) {
+	// nolint:gosec
+	cmd := exec.Command(p.path)
+	cmd.Env = input.SanitizedEnv()
+	cmd.Stderr = os.Stderr
+	if isolated {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+
+	if p.stdin, err = cmd.StdinPipe(); err != nil {
+		return
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+
+	log.Debugf("Starting plugin %s", p.path)
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("Could not start plugin %s: %s", p.path, err)
+	}
+
+	p.cmd = cmd
+	p.stdoutEnd = stdout
+	p.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+// call sends a request to the plugin and waits for its response
+func (p *process) call(req *request) (res *response, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil {
+		if err = p.start(false); err != nil {
+			return
+		}
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode %s request for plugin %s: %s", req.Op, p.path, err)
+	}
+
+	if _, err = p.stdin.Write(append(payload, '\n')); err != nil {
+		p.stop()
+		return nil, fmt.Errorf("Could not send %s request to plugin %s: %s", req.Op, p.path, err)
+	}
+
+	line, err := p.stdout.ReadBytes('\n')
+	if err != nil {
+		p.stop()
+		return nil, fmt.Errorf("Plugin %s exited during %s: %s", p.path, req.Op, err)
+	}
+
+	res = &response{}
+	if err = json.Unmarshal(line, res); err != nil {
+		return nil, fmt.Errorf("Plugin %s replied to %s with invalid JSON: %s", p.path, req.Op, err)
+	}
+
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+
+	return res, nil
+}
+
+// describe asks the plugin for its flags, giving up after describeTimeout
+func (p *process) describe() (res *response, err error) {
+	p.mu.Lock()
+	err = p.start(true)
+	cmd, stdout := p.cmd, p.stdoutEnd
+	p.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		res, err = p.call(&request{Op: opDescribe})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(describeTimeout):
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		// whatever the plugin started may have escaped its group, and still hold its stdout open
+		stdout.Close()
+		<-done
+		err = fmt.Errorf("Plugin %s took longer than %s to describe itself", p.path, describeTimeout)
+	}
+
+	// the plugin will be started again when a file needs it
+	p.mu.Lock()
+	p.stop()
+	p.mu.Unlock()
+	return
+}
+
+// stop closes the plugin's stdin, so it exits, and kills it if it doesn't within stopTimeout
+func (p *process) stop() {
+	if p.cmd == nil {
+		return
+	}
+
+	p.stdin.Close()
+	p.stdoutEnd.Close()
+
+	exited := make(chan struct{})
+	go func(cmd *exec.Cmd) {
+		_ = cmd.Wait()
+		close(exited)
+	}(p.cmd)
+
+	select {
+	case <-exited:
+	case <-time.After(stopTimeout):
+		log.Debugf("Plugin %s did not exit, killing it", p.path)
+		if p.cmd.SysProcAttr != nil && p.cmd.SysProcAttr.Setpgid {
+			_ = syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+		} else {
+			_ = p.cmd.Process.Kill()
+		}
+		<-exited
+	}
+	p.cmd = nil
+}
@@ -0,0 +1,24 @@
+package plugin
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescribeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { describeTimeout = timeout }(describeTimeout)
+	describeTimeout = 100 * time.Millisecond
+	defer os.Setenv("GCY_TEST_PLUGIN", os.Getenv("GCY_TEST_PLUGIN"))
+	os.Setenv("GCY_TEST_PLUGIN", "hang")
+
+	started := time.Now()
+	_, err := (&process{path: os.Args[0]}).describe()
+	if err == nil || !strings.Contains(err.Error(), "took longer than 100ms") {
+		t.Fatalf("Did not time out: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("Waited %s for the plugin and its children", elapsed)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 Blink Health LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+
+// Package plugin adds support for external providers to go-config-yourself
+//
+// Any executable named `gcy-provider-NAME` found in PATH can be registered as the provider `NAME`, either by Find
+// when a file uses a provider that isn't registered, or by DiscoverPATH. gcy talks to it with one line of JSON per
+// request on its stdin, and expects one line of JSON per response on its stdout.
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+)
+
+// Prefix is what plugin executables' names start with
+const Prefix = "gcy-provider-"
+
+// flags plugins can't define, since commands define them already
+var reservedFlags = map[string]bool{"provider": true, "help": true}
+
+// serializes registering plugins found on demand
+var finding sync.Mutex
+
+// disabled tells whether plugins are turned off with GCY_DISABLE_PLUGINS
+func disabled() bool {
+	return os.Getenv("GCY_DISABLE_PLUGINS") != ""
+}
+
+// DiscoverPATH registers every plugin found in PATH, running each of them to describe its flags
+func DiscoverPATH() {
+	if disabled() {
+		return
+	}
+
+	Discover(filepath.SplitList(os.Getenv("PATH")))
+}
+
+// Find registers the plugin for provider `name` if it's found in PATH, and tells whether `name` is registered
+func Find(name string) bool {
+	finding.Lock()
+	defer finding.Unlock()
+
+	if _, exists := pvd.Providers[name]; exists {
+		return true
+	}
+	if disabled() || name == "" || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return false
+	}
+
+	if err = Register(name, path); err != nil {
+		log.Warnf("Could not register plugin %s: %s", path, err)
+		return false
+	}
+	return true
+}
+
+// Discover registers every plugin found in `dirs`. Built-in providers and plugins found earlier take precedence
+func Discover(dirs []string) {
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, f := range files {
+			name := strings.TrimPrefix(f.Name(), Prefix)
+			if name == f.Name() || name == "" || f.IsDir() || f.Mode()&0111 == 0 {
+				continue
+			}
+
+			if _, exists := pvd.Providers[name]; exists {
+				log.Debugf("Ignoring plugin %s, provider %s is already registered", f.Name(), name)
+				continue
+			}
+
+			if err := Register(name, filepath.Join(dir, f.Name())); err != nil {
+				log.Warnf("Could not register plugin %s: %s", f.Name(), err)
+			}
+		}
+	}
+}
+
+// Register the plugin executable at `path` as the provider `name`, unless a provider with that name exists already
+func Register(name string, path string) error {
+	if _, exists := pvd.Providers[name]; exists {
+		return fmt.Errorf("Provider %s is already registered", name)
+	}
+
+	plugin := &process{path: path}
+	description, err := plugin.describe()
+	if err != nil {
+		return err
+	}
+
+	flags := []pvd.Argument{}
+	for _, flag := range description.Flags {
+		if reservedFlags[flag.Name] {
+			log.Warnf("Ignoring flag --%s from plugin %s, it's reserved by gcy", flag.Name, name)
+			continue
+		}
+		flags = append(flags, flag)
+	}
+
+	log.Debugf("Registering plugin %s with %d flags", name, len(flags))
+	pvd.RegisterProvider(name, func(config map[string]interface{}) (pvd.Crypto, error) {
+		return newProvider(name, plugin, config)
+	}, flags)
+	return nil
+}
+
+// Provider implements provider.Crypto for plugins
+type Provider struct {
+	name    string
+	plugin  *process
+	config  map[string]interface{}
+	enabled bool
+}
+
+func newProvider(name string, plugin *process, config map[string]interface{}) (pvd.Crypto, error) {
+	provider := &Provider{name: name, plugin: plugin}
+	res, err := plugin.call(&request{Op: opNew, Config: config})
+	if err != nil {
+		return nil, err
+	}
+
+	provider.update(res)
+	return provider, nil
+}
+
+// Enabled tells whether the provider is ready to operate on secrets
+func (provider *Provider) Enabled() bool {
+	return provider.enabled
+}
+
+// Replace asks the plugin for a new config with `args`
+func (provider *Provider) Replace(args map[string]interface{}) error {
+	res, err := provider.plugin.call(&request{Op: opReplace, Config: provider.config, Args: args})
+	if err != nil {
+		return err
+	}
+
+	provider.update(res)
+	return nil
+}
+
+// Serialize into a map of config for later hydration
+func (provider *Provider) Serialize() (serialized map[string]interface{}) {
+	serialized = provider.config
+	if res, err := provider.plugin.call(&request{Op: opSerialize, Config: provider.config}); err == nil {
+		serialized = res.Config
+	} else {
+		log.Warnf("Plugin %s could not serialize, using its last config: %s", provider.name, err)
+	}
+
+	if serialized == nil {
+		serialized = map[string]interface{}{}
+	}
+	serialized["provider"] = provider.name
+	return
+}
+
+// Encrypt bytes
+func (provider *Provider) Encrypt(plainText []byte) ([]byte, error) {
+	res, err := provider.plugin.call(&request{Op: opEncrypt, Config: provider.config, Data: plainText})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Data, nil
+}
+
+// Decrypt bytes
+func (provider *Provider) Decrypt(cipherText []byte) (string, error) {
+	res, err := provider.plugin.call(&request{Op: opDecrypt, Config: provider.config, Data: cipherText})
+	if err != nil {
+		return "", err
+	}
+
+	return string(res.Data), nil
+}
+
+func (provider *Provider) update(res *response) {
+	if res.Config != nil {
+		provider.config = res.Config
+	}
+	provider.enabled = res.Enabled
+}
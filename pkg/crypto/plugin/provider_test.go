@@ -0,0 +1,207 @@
+package plugin_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/plugin"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+// TestMain doubles as a plugin that xors values with `crypto.key`, when started by the tests below
+func TestMain(m *testing.M) {
+	switch os.Getenv("GCY_TEST_PLUGIN") {
+	case "1":
+		servePlugin()
+		os.Exit(0)
+	case "hang":
+		// a child holding stdout open outlives its parent, unless the whole group is killed
+		child := exec.Command("sleep", "30")
+		child.Stdout = os.Stdout
+		_ = child.Start()
+		time.Sleep(30 * time.Second)
+		os.Exit(0)
+	}
+
+	log.SetLevel(log.DebugLevel)
+	os.Setenv("GCY_TEST_PLUGIN", "1")
+	os.Exit(m.Run())
+}
+
+func servePlugin() {
+	scanner := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		req := map[string]interface{}{}
+		res := map[string]interface{}{}
+		_ = json.Unmarshal(scanner.Bytes(), &req)
+		config, _ := req["config"].(map[string]interface{})
+		if config == nil {
+			config = map[string]interface{}{}
+		}
+
+		switch req["op"] {
+		case "describe":
+			res["flags"] = []map[string]interface{}{
+				{"name": "xor-key", "description": "The key to xor values with"},
+				{"name": "provider", "description": "Should be ignored"},
+			}
+		case "new", "serialize":
+			res["config"] = config
+			res["enabled"] = config["key"] != nil
+		case "replace":
+			args, _ := req["args"].(map[string]interface{})
+			if args["xor-key"] == nil {
+				res["error"] = "xor-key is required"
+				break
+			}
+			config["key"] = args["xor-key"]
+			res["config"] = config
+			res["enabled"] = true
+		case "encrypt", "decrypt":
+			var data []byte
+			rawData, _ := json.Marshal(req["data"])
+			_ = json.Unmarshal(rawData, &data)
+			key := config["key"].(string)
+			for i := range data {
+				data[i] ^= key[i%len(key)]
+			}
+			res["data"] = data
+		}
+		_ = enc.Encode(res)
+	}
+}
+
+func TestPluginProvider(t *testing.T) {
+	if err := plugin.Register("xor", os.Args[0]); err != nil {
+		t.Fatalf("Could not register: %s", err)
+	}
+
+	registration, found := pvd.Providers["xor"]
+	if !found {
+		t.Fatal("Plugin was not registered")
+	}
+
+	if len(registration.Flags) != 1 || registration.Flags[0].Name != "xor-key" {
+		t.Fatalf("Registered wrong flags: %v", registration.Flags)
+	}
+
+	provider, err := registration.New(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if provider.Enabled() {
+		t.Fatal("Enabled without a key")
+	}
+
+	if err = provider.Replace(map[string]interface{}{}); err == nil {
+		t.Fatal("Replaced without required arguments")
+	}
+
+	if err = provider.Replace(map[string]interface{}{"xor-key": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cipherText, err := provider.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(cipherText) == "plaintext" {
+		t.Fatal("Value was not encrypted")
+	}
+
+	serialized := provider.Serialize()
+	if serialized["provider"] != "xor" || serialized["key"] != "secret" {
+		t.Fatalf("Serialized wrong config: %v", serialized)
+	}
+
+	// hydrate another provider from the serialized config
+	hydrated, err := registration.New(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plainText, err := hydrated.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+}
+
+func TestPluginDiscovery(t *testing.T) {
+	if err := plugin.Register("broken", "/non-existent-plugin"); err == nil {
+		t.Fatal("Registered a missing executable")
+	}
+
+	dir, err := ioutil.TempDir("", "gcy-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"discovered", "password", "not-a-plugin"} {
+		target := filepath.Join(dir, plugin.Prefix+name)
+		if name == "not-a-plugin" {
+			target = filepath.Join(dir, name)
+		}
+		if err = os.Symlink(os.Args[0], target); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pvd.RegisterProvider("password", nil, nil)
+	before := len(pvd.ProviderList)
+	plugin.Discover([]string{"/non-existent-dir", dir})
+	if len(pvd.ProviderList) != before+1 {
+		t.Fatalf("Registered the wrong plugins: %v", pvd.ProviderList)
+	}
+
+	if _, found := pvd.Providers["discovered"]; !found {
+		t.Fatal("Did not register plugin found in PATH")
+	}
+
+	if pvd.Providers["password"].New != nil {
+		t.Fatal("Plugin replaced an existing provider")
+	}
+
+	if err = plugin.Register("password", os.Args[0]); err == nil {
+		t.Fatal("Registered a plugin over an existing provider")
+	}
+}
+
+func TestPluginFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = os.Symlink(os.Args[0], filepath.Join(dir, plugin.Prefix+"found")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+
+	if plugin.Find("missing") {
+		t.Fatal("Found a plugin that's not in PATH")
+	}
+
+	if !plugin.Find("found") {
+		t.Fatal("Did not find plugin in PATH")
+	}
+	if _, found := pvd.Providers["found"]; !found {
+		t.Fatal("Did not register plugin found in PATH")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2018 Blink Health LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+
+// Package vault adds HashiCorp Vault support for go-config-yourself
+//
+// It uses the transit secrets engine (https://www.vaultproject.io/docs/secrets/transit) to encrypt every secret value.
+package vault
+
+import (
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/blinkhealth/go-config-yourself/internal/input"
+	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
+)
+
+const defaultMount = "transit"
+
+func init() {
+	pvd.RegisterProvider("vault", New, []pvd.Argument{
+		{
+			Name:        "vault-address",
+			Description: "The address of the vault server",
+			EnvVarName:  "VAULT_ADDR",
+		},
+		{
+			Name:        "vault-mount",
+			Description: "The path the transit secrets engine is mounted at",
+			Default:     defaultMount,
+		},
+		{
+			Name:        "vault-key",
+			Description: "The name of the transit key to use. Omit it and `gcy` prompts you to select one from the mount",
+		},
+		{
+			Name:        "vault-key-version",
+			Description: "The version of the transit key to encrypt with, defaults to the latest one",
+		},
+	})
+}
+
+// Provider implements provider.Crypto for vault
+type Provider struct {
+	key     string
+	version int
+	service *vaultService
+}
+
+// New creates a new vault.Provider and returns it
+func New(config map[string]interface{}) (pvd.Crypto, error) {
+	address, _ := config["address"].(string)
+	mount, _ := config["mount"].(string)
+	key, _ := config["key"].(string)
+
+	version := 0
+	if v, exists := config["key_version"]; exists {
+		var isInt bool
+		if version, isInt = v.(int); !isInt || version < 0 {
+			return nil, fmt.Errorf("Invalid config, crypto.key_version must be a positive integer")
+		}
+	}
+
+	log.Debugf("Initializing vault provider with key %s at %s/%s", key, address, mount)
+	return &Provider{
+		key:     key,
+		version: version,
+		service: newVaultService(address, mount),
+	}, nil
+}
+
+// Enabled tells whether the provider is ready to operate on secrets
+func (provider *Provider) Enabled() bool {
+	return provider.key != ""
+}
+
+// Encrypt bytes
+func (provider *Provider) Encrypt(plainText []byte) ([]byte, error) {
+	return provider.service.Encrypt(provider.key, provider.version, plainText)
+}
+
+// Decrypt bytes
+func (provider *Provider) Decrypt(cipherText []byte) (string, error) {
+	return provider.service.Decrypt(provider.key, cipherText)
+}
+
+// Replace the key with a new one
+//
+// Will list the keys in the transit mount and prompt the user to select one, unless `vault-key` is present in
+// `args`. Secrets will be encrypted with `vault-key-version` if present, or the latest version of the key otherwise
+func (provider *Provider) Replace(args map[string]interface{}) (err error) {
+	address, _ := args["vault-address"].(string)
+	if address == "" {
+		address = provider.service.address
+	}
+
+	mount, _ := args["vault-mount"].(string)
+	if mount == "" {
+		mount = provider.service.mount
+	}
+
+	service := newVaultService(address, mount)
+	key, _ := args["vault-key"].(string)
+	if key == "" {
+		keys, err := service.ListKeys()
+		if err != nil {
+			return fmt.Errorf("Failed to list keys: %s", err)
+		}
+
+		responses, err := input.SelectionFromList(keys, "vault", false)
+		if err != nil {
+			return err
+		}
+		key = responses[0]
+	}
+
+	version := 0
+	if versionString, _ := args["vault-key-version"].(string); versionString != "" {
+		if version, err = strconv.Atoi(versionString); err != nil || version < 1 {
+			return fmt.Errorf("Invalid key version <%s>", versionString)
+		}
+	} else if provider.version > 0 {
+		// files pinned to a version move to the latest one on rekey
+		if version, err = service.LatestVersion(key); err != nil {
+			return err
+		}
+	}
+
+	provider.key = key
+	provider.version = version
+	provider.service = service
+	return
+}
+
+// Arguments returns the server and key to use again without listing keys
+func (provider *Provider) Arguments() map[string]interface{} {
+	args := map[string]interface{}{
+		"vault-address": provider.service.address,
+		"vault-mount":   provider.service.mount,
+		"vault-key":     provider.key,
+	}
+	if provider.version > 0 {
+		args["vault-key-version"] = strconv.Itoa(provider.version)
+	}
+	return args
+}
+
+// Serialize into a map of config for later hydration
+func (provider *Provider) Serialize() (serialized map[string]interface{}) {
+	serialized = map[string]interface{}{
+		"provider": "vault",
+		"address":  provider.service.address,
+		"mount":    provider.service.mount,
+		"key":      provider.key,
+	}
+
+	if provider.version > 0 {
+		serialized["key_version"] = provider.version
+	}
+	return
+}
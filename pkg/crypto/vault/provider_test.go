@@ -0,0 +1,187 @@
+package vault_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/vault"
+	log "github.com/sirupsen/logrus"
+)
+
+const testToken = "a-good-token"
+
+// transitStandIn mimics vault's transit engine, "encrypting" plaintext by tagging it with the key and its version
+func transitStandIn() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		reply := func(status int, data map[string]interface{}) {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(data)
+		}
+
+		if r.URL.Path == "/v1/auth/approle/login" {
+			if body["role_id"] == "role" && body["secret_id"] == "secret" {
+				reply(200, map[string]interface{}{"auth": map[string]interface{}{"client_token": testToken}})
+			} else {
+				reply(400, map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+			}
+			return
+		}
+
+		if r.Header.Get("X-Vault-Token") != testToken {
+			reply(403, map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+
+		path := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+		switch {
+		case r.Method == "LIST" && len(path) == 2 && path[1] == "keys":
+			reply(200, map[string]interface{}{"data": map[string]interface{}{"keys": []string{"app"}}})
+		case r.Method == "GET" && len(path) == 3 && path[1] == "keys":
+			reply(200, map[string]interface{}{"data": map[string]interface{}{"latest_version": 2}})
+		case len(path) == 3 && path[1] == "encrypt":
+			version := 2
+			if v, ok := body["key_version"].(float64); ok {
+				version = int(v)
+			}
+			cipherText := fmt.Sprintf("vault:v%d:%s:%s", version, path[2], body["plaintext"])
+			reply(200, map[string]interface{}{"data": map[string]interface{}{"ciphertext": cipherText}})
+		case len(path) == 3 && path[1] == "decrypt":
+			pieces := strings.SplitN(body["ciphertext"].(string), ":", 4)
+			if len(pieces) != 4 || pieces[2] != path[2] {
+				reply(400, map[string]interface{}{"errors": []string{"invalid ciphertext"}})
+				return
+			}
+			reply(200, map[string]interface{}{"data": map[string]interface{}{"plaintext": pieces[3]}})
+		default:
+			reply(404, map[string]interface{}{"errors": []string{}})
+		}
+	}))
+}
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	os.Unsetenv("VAULT_ADDR")
+	os.Unsetenv("VAULT_TOKEN")
+	os.Setenv("HOME", "/non-existent-home")
+	os.Exit(m.Run())
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := transitStandIn()
+	defer server.Close()
+	os.Setenv("VAULT_TOKEN", testToken)
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	provider, err := vault.New(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Enabled() {
+		t.Fatal("Enabled without a key")
+	}
+
+	err = provider.Replace(map[string]interface{}{
+		"vault-address": server.URL,
+		"vault-key":     "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cipherText, err := provider.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "vault:v2:app:" + base64.StdEncoding.EncodeToString([]byte("plaintext"))
+	if string(cipherText) != expected {
+		t.Fatalf("Encrypted wrong value: %s", cipherText)
+	}
+
+	serialized := provider.Serialize()
+	if serialized["address"] != server.URL || serialized["mount"] != "transit" || serialized["key"] != "app" {
+		t.Fatalf("Serialized wrong config: %v", serialized)
+	}
+
+	hydrated, err := vault.New(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText, err := hydrated.Decrypt(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainText != "plaintext" {
+		t.Fatalf("Decrypted wrong value: %s", plainText)
+	}
+}
+
+func TestVaultKeyVersions(t *testing.T) {
+	server := transitStandIn()
+	defer server.Close()
+	os.Setenv("VAULT_TOKEN", testToken)
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	provider, _ := vault.New(map[string]interface{}{"address": server.URL, "key": "app"})
+	if err := provider.Replace(map[string]interface{}{"vault-key-version": "1"}); err == nil {
+		t.Fatal("Replaced without a key or a selection")
+	}
+
+	err := provider.Replace(map[string]interface{}{"vault-key": "app", "vault-key-version": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText, _ := provider.Encrypt([]byte("plaintext"))
+	if !strings.HasPrefix(string(cipherText), "vault:v1:") {
+		t.Fatalf("Did not encrypt with pinned version: %s", cipherText)
+	}
+	if provider.Serialize()["key_version"] != 1 {
+		t.Fatalf("Did not serialize pinned version: %v", provider.Serialize())
+	}
+
+	// pinned files move to the latest version on rekey
+	if err = provider.Replace(map[string]interface{}{"vault-key": "app"}); err != nil {
+		t.Fatal(err)
+	}
+	if provider.Serialize()["key_version"] != 2 {
+		t.Fatalf("Did not move to latest version: %v", provider.Serialize())
+	}
+
+	if err = provider.Replace(map[string]interface{}{"vault-key": "app", "vault-key-version": "latest"}); err == nil {
+		t.Fatal("Accepted an invalid key version")
+	}
+}
+
+func TestVaultAuth(t *testing.T) {
+	server := transitStandIn()
+	defer server.Close()
+	config := map[string]interface{}{"address": server.URL, "key": "app"}
+
+	provider, _ := vault.New(config)
+	if _, err := provider.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "No vault token found") {
+		t.Fatalf("Encrypted without a token: %v", err)
+	}
+
+	os.Setenv("VAULT_TOKEN", "a-bad-token")
+	provider, _ = vault.New(config)
+	if _, err := provider.Encrypt([]byte("plaintext")); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("Encrypted with a bad token: %v", err)
+	}
+	os.Unsetenv("VAULT_TOKEN")
+
+	os.Setenv("VAULT_ROLE_ID", "role")
+	os.Setenv("VAULT_SECRET_ID", "secret")
+	defer os.Unsetenv("VAULT_ROLE_ID")
+	defer os.Unsetenv("VAULT_SECRET_ID")
+	provider, _ = vault.New(config)
+	if _, err := provider.Encrypt([]byte("plaintext")); err != nil {
+		t.Fatalf("Could not encrypt with AppRole: %s", err)
+	}
+}
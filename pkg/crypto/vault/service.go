@@ -0,0 +1,239 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// enough for a round trip to a remote vault cluster, while not hanging forever
+const requestTimeout = 30 * time.Second
+
+type vaultService struct {
+	address string
+	mount   string
+	client  *http.Client
+	token   string
+}
+
+// vaultResponse is the envelope for every vault API response
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Auth   *vaultAuth             `json:"auth"`
+	Errors []string               `json:"errors"`
+}
+
+type vaultAuth struct {
+	ClientToken string `json:"client_token"`
+}
+
+func newVaultService(address string, mount string) *vaultService {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+
+	if mount == "" {
+		mount = defaultMount
+	}
+
+	return &vaultService{
+		address: strings.TrimRight(address, "/"),
+		mount:   strings.Trim(mount, "/"),
+		client:  &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Encrypt plaintext with the transit key `key`, at `version`, or the latest version if 0
+func (svc *vaultService) Encrypt(key string, version int, plainText []byte) ([]byte, error) {
+	body := map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plainText),
+	}
+	if version > 0 {
+		body["key_version"] = version
+	}
+
+	res, err := svc.request(http.MethodPost, fmt.Sprintf("%s/encrypt/%s", svc.mount, key), body)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, isString := res.Data["ciphertext"].(string)
+	if !isString {
+		return nil, errors.New("Vault did not return a ciphertext")
+	}
+
+	return []byte(cipherText), nil
+}
+
+// Decrypt a ciphertext with the transit key `key`
+func (svc *vaultService) Decrypt(key string, cipherText []byte) (string, error) {
+	res, err := svc.request(http.MethodPost, fmt.Sprintf("%s/decrypt/%s", svc.mount, key), map[string]interface{}{
+		"ciphertext": string(cipherText),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoded, isString := res.Data["plaintext"].(string)
+	if !isString {
+		return "", errors.New("Vault did not return a plaintext")
+	}
+
+	plainText, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("Vault returned invalid base64: %s", err)
+	}
+
+	return string(plainText), nil
+}
+
+// LatestVersion returns the latest version of the transit key `key`
+func (svc *vaultService) LatestVersion(key string) (version int, err error) {
+	res, err := svc.request(http.MethodGet, fmt.Sprintf("%s/keys/%s", svc.mount, key), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	latest, isNumber := res.Data["latest_version"].(float64)
+	if !isNumber {
+		return 0, fmt.Errorf("Vault did not return a version for key <%s>", key)
+	}
+
+	return int(latest), nil
+}
+
+// ListKeys lists the transit keys in this service's mount
+func (svc *vaultService) ListKeys() (keys []string, err error) {
+	res, err := svc.request("LIST", fmt.Sprintf("%s/keys", svc.mount), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list, _ := res.Data["keys"].([]interface{})
+	for _, key := range list {
+		if name, isString := key.(string); isString {
+			keys = append(keys, name)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("Could not find any keys in %s/%s", svc.address, svc.mount)
+	}
+
+	return keys, nil
+}
+
+// login finds a token in VAULT_TOKEN, by logging in with AppRole using VAULT_ROLE_ID and VAULT_SECRET_ID, or in ~/.vault-token
+func (svc *vaultService) login() (err error) {
+	if svc.token != "" {
+		return nil
+	}
+
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		log.Debug("Using token from VAULT_TOKEN")
+		svc.token = token
+		return nil
+	}
+
+	if roleID := os.Getenv("VAULT_ROLE_ID"); roleID != "" {
+		mount := os.Getenv("VAULT_APPROLE_MOUNT")
+		if mount == "" {
+			mount = "approle"
+		}
+
+		log.Debugf("Logging in with AppRole at auth/%s", mount)
+		res, err := svc.do(http.MethodPost, fmt.Sprintf("auth/%s/login", mount), map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": os.Getenv("VAULT_SECRET_ID"),
+		})
+		if err != nil {
+			return fmt.Errorf("Could not log in with AppRole: %s", err)
+		}
+
+		if res.Auth == nil || res.Auth.ClientToken == "" {
+			return errors.New("Could not log in with AppRole: vault did not return a token")
+		}
+
+		svc.token = res.Auth.ClientToken
+		return nil
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		if token, err := ioutil.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+			log.Debug("Using token from ~/.vault-token")
+			svc.token = strings.TrimSpace(string(token))
+			return nil
+		}
+	}
+
+	return errors.New("No vault token found, set VAULT_TOKEN, VAULT_ROLE_ID and VAULT_SECRET_ID, or run `vault login`")
+}
+
+func (svc *vaultService) request(method string, path string, body map[string]interface{}) (*vaultResponse, error) {
+	if err := svc.login(); err != nil {
+		return nil, err
+	}
+
+	return svc.do(method, path, body)
+}
+
+func (svc *vaultService) do(method string, path string, body map[string]interface{}) (res *vaultResponse, err error) {
+	if svc.address == "" {
+		return nil, errors.New("No vault address found, set crypto.address or VAULT_ADDR")
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return
+		}
+	}
+
+	url := fmt.Sprintf("%s/v1/%s", svc.address, path)
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if svc.token != "" {
+		req.Header.Set("X-Vault-Token", svc.token)
+	}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	log.Debugf("%s %s", method, url)
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not reach vault at %s: %s", svc.address, err)
+	}
+	defer resp.Body.Close()
+
+	res = &vaultResponse{}
+	if resp.StatusCode != http.StatusNoContent {
+		if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+			return nil, fmt.Errorf("Vault replied with invalid JSON (%d): %s", resp.StatusCode, err)
+		}
+	}
+
+	if resp.StatusCode >= 400 {
+		msg := strings.Join(res.Errors, ", ")
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("Vault denied %s %s (%d): %s", method, path, resp.StatusCode, msg)
+	}
+
+	return res, nil
+}
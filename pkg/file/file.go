@@ -13,6 +13,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/blinkhealth/go-config-yourself/internal/yaml"
 	"github.com/blinkhealth/go-config-yourself/pkg/provider"
@@ -20,6 +21,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// now tells the time secrets are set at
+var now = time.Now
+
 // ConfigFile wraps parsed yaml data and provides an interface to interact with it
 type ConfigFile struct {
 	// A yaml tree
@@ -28,6 +32,8 @@ type ConfigFile struct {
 	crypto provider.Crypto
 	// The name of this config file's provider, one of `kms`, `gpg`, or `password`
 	Provider string
+	// The lock held on the file since it was loaded, if any
+	lock *fileLock
 }
 
 // HasCrypto tells whether this file has a crypto provider or not
@@ -47,7 +53,7 @@ func (cfg *ConfigFile) GetAll() (tree map[string]interface{}, err error) {
 	}
 
 	for k, value := range allValues {
-		decrypted, err := decryptNode(value, cfg.crypto)
+		decrypted, err := decryptNode(value, cfg.crypto, k)
 		if err != nil {
 			return tree, err
 		}
@@ -70,21 +76,30 @@ func (cfg *ConfigFile) Get(keyPath string) (value interface{}, err error) {
 
 	// nodes can be nil when the key exists and its value is nil
 	if node != nil {
-		value, err = decryptNode(node, cfg.crypto)
+		value, err = decryptNode(node, cfg.crypto, keyPath)
 	}
 	return
 }
 
 // Rekey creates a copy of this file, initializing its crypto provider with given arguments, and reencrypts all secrets. The original ConfigFile will not be modified.
 //
+// When re-keying with the same provider, settings in `crypto` (like endpoints) carry over unless replaced by `providerArgs`.
 // The user may be prompted for details if connected to a TTY and these are not provided by `providerArgs`
 func (cfg *ConfigFile) Rekey(providerName string, providerArgs map[string]interface{}) (newFile *ConfigFile, err error) {
 	if !cfg.HasCrypto() {
 		return newFile, errors.New("Cannot re-key a config without existing crypto provider")
 	}
 
+	config := providerArgs
+	if providerName == cfg.Provider {
+		config = cfg.crypto.Serialize()
+		for name, value := range providerArgs {
+			config[name] = value
+		}
+	}
+
 	log.Debugf("Creating copy for %s, %v", providerName, providerArgs)
-	newFile, err = Create(providerName, providerArgs)
+	newFile, err = create(providerName, config, providerArgs)
 	if err != nil {
 		return
 	}
@@ -112,7 +127,12 @@ func (cfg *ConfigFile) Rekey(providerName string, providerArgs map[string]interf
 			return nil, err
 		}
 
-		if err = newFile.Set(keyPath, []byte(fmt.Sprintf("%s", value))); err != nil {
+		var metadata *Metadata
+		if metadata, err = cfg.GetMetadata(keyPath); err != nil {
+			return nil, err
+		}
+
+		if err = newFile.setSecret(keyPath, []byte(fmt.Sprintf("%s", value)), metadata); err != nil {
 			log.Debugf("Failed to set secret at <%s>", keyPath)
 			return nil, err
 		}
@@ -123,17 +143,37 @@ func (cfg *ConfigFile) Rekey(providerName string, providerArgs map[string]interf
 }
 
 // Set into `keyPath` the encrypted value for `plainText`
-func (cfg *ConfigFile) Set(keyPath string, plainText []byte) (err error) {
+//
+// The secret is stamped with the time it was set, and keeps the description and owner of the secret it replaces
+// unless `options` change them. Its expiry is only kept when passed again with WithExpiry, since it belonged to the
+// old value
+func (cfg *ConfigFile) Set(keyPath string, plainText []byte, options ...SetOption) (err error) {
+	metadata := &Metadata{}
+	if previous, err := cfg.GetMetadata(keyPath); err == nil {
+		metadata.Description = previous.Description
+		metadata.Owner = previous.Owner
+	}
+	metadata.Created = now().UTC().Truncate(time.Second)
+	for _, option := range options {
+		option(metadata)
+	}
+
+	return cfg.setSecret(keyPath, plainText, metadata)
+}
+
+// setSecret stores the encrypted value for `plainText` at `keyPath`, along with exactly these `metadata`
+func (cfg *ConfigFile) setSecret(keyPath string, plainText []byte, metadata *Metadata) (err error) {
 	log.Debugf("Setting secret value for %s", keyPath)
 
 	if !cfg.HasCrypto() {
 		return errors.New("Cannot encrypt, provider is not enabled for encryption. See logs")
 	}
-	var data interface{}
-	data, err = encryptCipherText(plainText, cfg.crypto)
+	var data map[string]interface{}
+	data, err = encryptCipherText(plainText, cfg.crypto, keyPath)
 	if err != nil {
 		return
 	}
+	metadata.serialize(data)
 
 	return cfg.data.Set(keyPath, data)
 }
@@ -159,6 +199,55 @@ func (cfg *ConfigFile) ListSecrets() []string {
 	return secretsForNode(cfg.data, "")
 }
 
+// ListTypes returns the type of every value in this config file by keyPath, outside of `crypto` and without
+// decrypting secrets. Types are `null`, `boolean`, `integer`, `number`, `string` or `secret`, and lists and empty maps
+// are single values of type `list` and `map`
+func (cfg *ConfigFile) ListTypes() map[string]string {
+	types := map[string]string{}
+	if cfg.data != nil && cfg.data.Node != nil {
+		typesForNode(cfg.data.Node, "", types)
+	}
+	return types
+}
+
+// Info describes a ConfigFile's crypto settings and secrets
+type Info struct {
+	// The name of this file's provider
+	Provider string
+	// The settings stored in `crypto`
+	Crypto map[string]interface{}
+	// The keypaths of every secret
+	Secrets []string
+	// Details reported by the provider about decrypting the first secret, like the region that served it
+	Served map[string]string
+}
+
+// Info describes this file, decrypting its first secret to check the provider can still do so
+func (cfg *ConfigFile) Info() (info *Info, err error) {
+	info = &Info{
+		Provider: cfg.Provider,
+		Crypto:   map[string]interface{}{},
+		Secrets:  cfg.ListSecrets(),
+	}
+
+	if err = cfg.data.Get("crypto", &info.Crypto); err != nil {
+		return info, nil
+	}
+
+	if len(info.Secrets) == 0 || !cfg.HasCrypto() {
+		return
+	}
+
+	if _, err = cfg.Get(info.Secrets[0]); err != nil {
+		return info, fmt.Errorf("Failed to decrypt %s: %s", info.Secrets[0], err)
+	}
+
+	if reporter, reports := cfg.crypto.(provider.Reporter); reports {
+		info.Served = reporter.Report()
+	}
+	return
+}
+
 // Serialize the config into YAML
 func (cfg *ConfigFile) Serialize() ([]byte, error) {
 	return cfg.data.Serialize()
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -372,6 +373,45 @@ func TestGetAll(t *testing.T) {
 	os.Unsetenv("CONFIG_PASSWORD")
 }
 
+func TestGetAllLazily(t *testing.T) {
+	c := fx.LoadFile("encrypted.kms", t)
+	data, err := c.GetAllLazily()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, isSecret := data["secret"].(*file.Secret)
+	if !isSecret || secret.KeyPath != "secret" {
+		t.Fatalf("Did not keep secret encrypted: %v", data["secret"])
+	}
+	if value, err := secret.Value(); err != nil || value != "asdf" {
+		t.Fatalf("Could not decrypt secret: %v, %v", value, err)
+	}
+	if object, _ := data["object"].(map[string]interface{}); object["key"] != "value" {
+		t.Fatalf("Did not decode plaintext values: %v", data["object"])
+	}
+
+	if err = c.Set("list.3", []byte(testSecret)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = c.GetAllLazily(); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := data["list"].([]interface{})
+	if secret, isSecret := list[3].(*file.Secret); len(list) != 4 || list[0] != "a" || !isSecret || secret.KeyPath != "list.3" {
+		t.Fatalf("Did not keep secrets in lists encrypted: %v", data["list"])
+	}
+
+	_ = c.VeryInsecurelySetPlaintext("broken", []byte(`{"encrypted": true, "ciphertext": "bm90IGNpcGhlcnRleHQ="}`))
+	if data, err = c.GetAllLazily(); err != nil {
+		t.Fatalf("Decrypted secrets while loading lazily: %s", err)
+	}
+	secret, _ = data["broken"].(*file.Secret)
+	if secret.String() != "" || secret.Err() == nil {
+		t.Fatalf("Did not fail to decrypt a bad secret: %v", secret.Err())
+	}
+}
+
 func TestListAllSecrets(t *testing.T) {
 	os.Setenv("CONFIG_PASSWORD", "password")
 	for _, provider := range []string{"kms", "gpg", "password"} {
@@ -394,6 +434,30 @@ func TestListAllSecrets(t *testing.T) {
 	os.Unsetenv("CONFIG_PASSWORD")
 }
 
+func TestListTypes(t *testing.T) {
+	c := fx.LoadFile("encrypted.kms", t)
+	_ = c.VeryInsecurelySetPlaintext("nothing", nil)
+	_ = c.VeryInsecurelySetPlaintext("decimal", []byte("1.5"))
+	_ = c.VeryInsecurelySetPlaintext("empty", []byte("{}"))
+
+	expected := map[string]string{
+		"boolean":    "boolean",
+		"decimal":    "number",
+		"empty":      "map",
+		"empty-list": "list",
+		"list":       "list",
+		"nestedList": "list",
+		"nothing":    "null",
+		"number":     "integer",
+		"object.key": "string",
+		"secret":     "secret",
+		"string":     "string",
+	}
+	if types := c.ListTypes(); !reflect.DeepEqual(types, expected) {
+		t.Fatalf("Listed wrong types: %v", types)
+	}
+}
+
 func kmsKeyArgs(key string) map[string]interface{} {
 	return map[string]interface{}{"key": key}
 }
@@ -506,3 +570,71 @@ func TestSetBadSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestKMSEncryptionContext(t *testing.T) {
+	fx.MockAWS()
+	args := map[string]interface{}{"key": string(fx.MockKMSKey), "kms-context": []string{"app=billing"}}
+	c, err := file.Create("kms", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Set("db.password", []byte(testSecret)); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := c.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all["db"].(map[string]interface{})["password"] != testSecret {
+		t.Fatalf("Decrypted wrong value: %v", all)
+	}
+
+	// the context carries over when re-keying with the same provider
+	otherKey := strings.Replace(string(fx.MockKMSKey), "us-east-1", "us-west-1", 1)
+	newFile, err := c.Rekey("kms", kmsKeyArgs(otherKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := newFile.Get("crypto.context.app"); value != "billing" {
+		t.Fatalf("Context did not carry over: %v", value)
+	}
+
+	if value, err := newFile.Get("db.password"); err != nil || value != testSecret {
+		t.Fatalf("Could not decrypt re-keyed secret: %v %s", value, err)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	fx.MockAWS()
+	c, err := file.Create("kms", kmsKeyArgs(string(fx.MockKMSKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := c.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Provider != "kms" || info.Crypto["key"] != string(fx.MockKMSKey) || len(info.Secrets) != 0 || info.Served != nil {
+		t.Fatalf("Described wrong info: %v", info)
+	}
+
+	if err = c.Set("secret", []byte(testSecret)); err != nil {
+		t.Fatal(err)
+	}
+	info, err = c.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Secrets) != 1 || info.Served["region"] != "us-east-1" {
+		t.Fatalf("Described wrong info: %v", info)
+	}
+
+	info, err = fx.LoadFile("bad/crypto.nil", t).Info()
+	if err != nil || info.Provider != "" || len(info.Crypto) != 0 {
+		t.Fatalf("Described crypto for a file without it: %v %s", info, err)
+	}
+}
@@ -0,0 +1,116 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+)
+
+// Secret is an encrypted value that's only decrypted when first used
+type Secret struct {
+	// The dot-delimited path to the secret
+	KeyPath string
+	config  *ConfigFile
+	once    sync.Once
+	value   interface{}
+	err     error
+}
+
+// Value decrypts the secret, or returns the error decrypting it failed with
+func (secret *Secret) Value() (interface{}, error) {
+	secret.once.Do(func() {
+		secret.value, secret.err = secret.config.Get(secret.KeyPath)
+	})
+	return secret.value, secret.err
+}
+
+// Err returns the error decrypting the secret failed with, or nil if it succeeded or it was never used
+func (secret *Secret) Err() error {
+	return secret.err
+}
+
+// String decrypts the secret, or returns an empty string if it fails. Check Err afterwards
+func (secret *Secret) String() string {
+	value, err := secret.Value()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// MarshalJSON implements json.Marshaler, decrypting the secret
+func (secret *Secret) MarshalJSON() ([]byte, error) {
+	value, err := secret.Value()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// GetAllLazily returns the file as a map like GetAll, but without decrypting anything: secrets are *Secret values
+// that decrypt themselves when used
+func (cfg *ConfigFile) GetAllLazily() (tree map[string]interface{}, err error) {
+	allValues := map[string]*yaml.Tree{}
+	if err = cfg.data.Decode(&allValues); err != nil {
+		return nil, err
+	}
+
+	tree = map[string]interface{}{}
+	for key, value := range allValues {
+		if tree[key], err = cfg.lazyNode(value, key); err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// lazyNode decodes `node`, found at `keyPath`, replacing secrets with *Secret values
+func (cfg *ConfigFile) lazyNode(node *yaml.Tree, keyPath string) (interface{}, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	if node.IsSlice() {
+		items := []*yaml.Tree{}
+		if err := node.Decode(&items); err != nil {
+			return nil, err
+		}
+
+		values := make([]interface{}, len(items))
+		for index, item := range items {
+			value, err := cfg.lazyNode(item, fmt.Sprintf("%s.%d", keyPath, index))
+			if err != nil {
+				return nil, err
+			}
+			values[index] = value
+		}
+		return values, nil
+	}
+
+	if !node.IsMap() {
+		var value interface{}
+		err := node.Decode(&value)
+		return value, err
+	}
+
+	if node.IsEncrypted() {
+		return &Secret{KeyPath: keyPath, config: cfg}, nil
+	}
+
+	children := map[string]*yaml.Tree{}
+	if err := node.Decode(&children); err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	for key, child := range children {
+		value, err := cfg.lazyNode(child, fmt.Sprintf("%s.%s", keyPath, key))
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -1,28 +1,129 @@
 package file
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/plugin"
 	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
 
+	// register azurekv
+	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/azurekv"
+	// register gcpkms
+	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/gcpkms"
 	// register gpg
 	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/gpg"
 	// register kms
 	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/kms"
 	// register password
 	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/password"
+	// register vault
+	_ "github.com/blinkhealth/go-config-yourself/pkg/crypto/vault"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Option configures how a ConfigFile is loaded
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	// arguments handed to the file's provider, unless the file defines them already
+	providerArgs map[string]interface{}
+	// lock the file before reading it
+	lock bool
+	// how long Watch waits for the file to stop changing
+	debounce time.Duration
+	// how often Watch checks the file when it can't be notified of changes
+	pollInterval time.Duration
+}
+
+// WithLock takes an exclusive advisory lock on the file before reading it, held until the ConfigFile is closed. Other
+// processes loading the file WithLock wait for it, so changes saved in between aren't lost
+func WithLock() Option {
+	return func(opts *loadOptions) {
+		opts.lock = true
+	}
+}
+
+// WithPassword decrypts files using the `password` provider with `password`, instead of prompting for it
+func WithPassword(password string) Option {
+	return withProviderArg("password", password)
+}
+
+// WithPasswordFile reads the password for files using the `password` provider from the file at `path`
+func WithPasswordFile(path string) Option {
+	return withProviderArg("password-file", path)
+}
+
+// WithPasswordFD reads the password for files using the `password` provider from the open file descriptor `fd`. It can
+// only be read once, so files loaded after the first one need WithPassword and the password read by password.ReadFD.
+// Watch does this on its own
+func WithPasswordFD(fd int) Option {
+	return withProviderArg("password-fd", fd)
+}
+
+// WithPasswordCommand reads the password for files using the `password` provider from the output of the shell `command`
+func WithPasswordCommand(command string) Option {
+	return withProviderArg("password-command", command)
+}
+
+// passwordArgs are the provider arguments set by the WithPassword options, which are ignored in files' crypto property
+var passwordArgs = []string{"password", "password-file", "password-fd", "password-command"}
+
+func withProviderArg(name string, value interface{}) Option {
+	return func(opts *loadOptions) {
+		opts.providerArgs[name] = value
+	}
+}
+
+func withoutProviderArg(name string) Option {
+	return func(opts *loadOptions) {
+		delete(opts.providerArgs, name)
+	}
+}
+
 // Create a new ConfigFile, initializing its crypto provider with given arguments.
 //
 // The user may be prompted for details if connected to a TTY and these are not provided by `providerArgs`
 func Create(providerName string, providerArgs map[string]interface{}) (config *ConfigFile, err error) {
+	return create(providerName, providerArgs, providerArgs)
+}
+
+// CollectArguments initializes a provider with `providerArgs` once, prompting the user for anything missing, and returns
+// arguments that initialize it the same way again without prompting. Use these to Create or Rekey many files at once.
+//
+// Arguments are returned unchanged for providers that can't tell what was chosen
+func CollectArguments(providerName string, providerArgs map[string]interface{}) (args map[string]interface{}, err error) {
+	template, err := Create(providerName, providerArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	args = map[string]interface{}{}
+	for name, value := range providerArgs {
+		args[name] = value
+	}
+
+	if reusable, isReusable := template.crypto.(pvd.Reusable); isReusable {
+		for name, value := range reusable.Arguments() {
+			args[name] = value
+		}
+	}
+	// the template was tuned already, every file gets its parameters
+	delete(args, "kdf-target")
+
+	return args, nil
+}
+
+// create initializes a provider with `providerConfig`, then replaces its keys using `providerArgs`
+func create(providerName string, providerConfig map[string]interface{}, providerArgs map[string]interface{}) (config *ConfigFile, err error) {
 	cfgMap := make(map[string]interface{})
-	pvd, err := initializeProvider(providerName, providerArgs)
+	pvd, err := initializeProvider(providerName, providerConfig)
 
 	if err != nil {
 		return nil, err
@@ -46,12 +147,74 @@ func Create(providerName string, providerArgs map[string]interface{}) (config *C
 }
 
 // Load a file at a give path and return a ConfigFile
-func Load(path string) (config *ConfigFile, err error) {
+func Load(path string, options ...Option) (config *ConfigFile, err error) {
+	opts := newLoadOptions(options)
+
+	var lock *fileLock
+	if opts.lock {
+		if lock, err = lockFile(path); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				lock.release()
+			}
+		}()
+	}
+
 	data, err := yaml.FromPathname(path)
 	if err != nil {
 		return nil, fmt.Errorf("Could not parse YAML: %s", err)
 	}
 
+	if config, err = fromTree(data, opts); err != nil {
+		return nil, err
+	}
+	config.lock = lock
+	return config, nil
+}
+
+// LoadBytes returns a ConfigFile for the YAML in `contents`, like one embedded in a program or fetched from elsewhere.
+// Since there's no file to lock, it can't be loaded WithLock
+func LoadBytes(contents []byte, options ...Option) (config *ConfigFile, err error) {
+	opts := newLoadOptions(options)
+	if opts.lock {
+		return nil, errors.New("Cannot lock a config that is not loaded from a file")
+	}
+
+	// like empty files, empty contents are an empty config
+	if len(contents) == 0 {
+		contents = []byte("{}")
+	}
+
+	data, err := yaml.FromBytes(contents)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse YAML: %s", err)
+	}
+
+	return fromTree(data, opts)
+}
+
+// LoadReader returns a ConfigFile for the YAML read from `reader` until EOF, see LoadBytes
+func LoadReader(reader io.Reader, options ...Option) (config *ConfigFile, err error) {
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read config: %s", err)
+	}
+
+	return LoadBytes(contents, options...)
+}
+
+func newLoadOptions(options []Option) *loadOptions {
+	opts := &loadOptions{providerArgs: map[string]interface{}{}}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// fromTree returns a ConfigFile for parsed YAML `data`, initializing the provider in its `crypto` property
+func fromTree(data *yaml.Tree, opts *loadOptions) (config *ConfigFile, err error) {
 	var provider pvd.Crypto
 	var providerName string
 
@@ -65,6 +228,14 @@ func Load(path string) (config *ConfigFile, err error) {
 			providerName = iProvider.(string)
 		}
 
+		// how to read passwords is up to whoever loads the file, never the file itself
+		for _, name := range passwordArgs {
+			delete(cryptoMap, name)
+		}
+		for name, value := range opts.providerArgs {
+			cryptoMap[name] = value
+		}
+
 		log.Debugf("Initializing secure config with provider: %s", providerName)
 
 		provider, err = initializeProvider(providerName, cryptoMap)
@@ -91,6 +262,9 @@ func Load(path string) (config *ConfigFile, err error) {
 
 func initializeProvider(providerName string, config map[string]interface{}) (pvd.Crypto, error) {
 	provider, ok := pvd.Providers[providerName]
+	if !ok && plugin.Find(providerName) {
+		provider, ok = pvd.Providers[providerName]
+	}
 	if !ok {
 		return nil, fmt.Errorf("Unknown provider <%s>", providerName)
 	}
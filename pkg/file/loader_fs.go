@@ -0,0 +1,18 @@
+// +build go1.16
+
+package file
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// LoadFS returns a ConfigFile for the file `name` in `fsys`, like an embed.FS, see LoadBytes
+func LoadFS(fsys fs.FS, name string, options ...Option) (config *ConfigFile, err error) {
+	contents, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read file %s: %s", name, err)
+	}
+
+	return LoadBytes(contents, options...)
+}
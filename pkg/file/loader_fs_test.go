@@ -0,0 +1,34 @@
+// +build go1.16
+
+package file_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+func TestLoadFS(t *testing.T) {
+	fx.MockAWS()
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.kms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"config/production.yml": {Data: contents}}
+
+	cfg, err := file.LoadFS(fsys, "config/production.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cfg.Get("secret"); value != "asdf" {
+		t.Fatalf("Could not decrypt secret: %v", value)
+	}
+
+	if _, err = file.LoadFS(fsys, "config/staging.yml"); err == nil || !strings.Contains(err.Error(), "Could not read file config/staging.yml") {
+		t.Fatalf("Loaded a missing file: %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package file_test
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -156,3 +159,188 @@ func TestCreateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordSources(t *testing.T) {
+	passwordFile, err := ioutil.TempFile("", "gcy-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(passwordFile.Name())
+	// only the first line holds the password
+	if _, err = passwordFile.WriteString("password\nnot the password\n"); err != nil {
+		t.Fatal(err)
+	}
+	passwordFile.Close()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keeps the reader from being closed by the garbage collector
+	defer reader.Close()
+	_, _ = writer.WriteString("password\n")
+	writer.Close()
+
+	// the command only succeeds when CONFIG_PASSWORD is kept from it
+	os.Setenv("CONFIG_PASSWORD", "not-the-password")
+	defer os.Unsetenv("CONFIG_PASSWORD")
+
+	tests := []struct {
+		name          string
+		options       []file.Option
+		shouldExplode bool
+	}{
+		{"file", []file.Option{file.WithPasswordFile(passwordFile.Name())}, false},
+		{"fd", []file.Option{file.WithPasswordFD(int(reader.Fd()))}, false},
+		{"command", []file.Option{file.WithPasswordCommand(`test -z "$CONFIG_PASSWORD" && echo password`)}, false},
+		{"resolved", []file.Option{file.WithPassword("password")}, false},
+		{"missing-file", []file.Option{file.WithPasswordFile("/non-existent-file")}, true},
+		{"failing-command", []file.Option{file.WithPasswordCommand("exit 1")}, true},
+		{"env", nil, true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			cfg, err := file.Load(fx.Path("encrypted.password"), tst.options...)
+			if err != nil {
+				t.Fatalf("Unable to load: %v", err)
+			}
+
+			value, err := cfg.Get("secret")
+			if tst.shouldExplode {
+				if err == nil {
+					t.Fatalf("Expected to explode, did not, %v", value)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Unable to decrypt: %v", err)
+			}
+
+			if value != testSecret {
+				t.Errorf("Decrypted wrong value: %v", value)
+			}
+		})
+	}
+
+	_, err = file.Load(fx.Path("encrypted.password"), file.WithPasswordFile("a"), file.WithPasswordCommand("b"))
+	if err == nil {
+		t.Error("Loaded with conflicting password sources")
+	}
+}
+
+func TestPasswordSourcesInFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(dir, "ran")
+	injected := fmt.Sprintf("crypto:\n  password-command: touch %s; echo password\n  password-file: /non-existent-file\n", marker)
+	contents = []byte(strings.Replace(string(contents), "crypto:\n", injected, 1))
+
+	for _, options := range [][]file.Option{{file.WithPassword("password")}, nil} {
+		cfg, err := file.LoadBytes(contents, options...)
+		if err != nil {
+			t.Fatalf("Unable to load: %v", err)
+		}
+
+		value, err := cfg.Get("secret")
+		if options != nil && (err != nil || value != testSecret) {
+			t.Fatalf("Did not use the password given to Load: %v, %v", value, err)
+		}
+		if _, err = os.Stat(marker); err == nil {
+			t.Fatal("Ran the password command from the file")
+		}
+	}
+}
+
+func TestCollectArguments(t *testing.T) {
+	password := "correct horse battery staple"
+	restoreStdin, err := fx.MockStdin(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := file.CollectArguments("password", map[string]interface{}{"kdf": "scrypt", "kdf-target": "10ms"})
+	restoreStdin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, tunesAgain := args["kdf-target"]; args["password"] != password || tunesAgain {
+		t.Fatalf("Collected wrong arguments: %v", args)
+	}
+	kdf, isMap := args["kdf"].(map[string]interface{})
+	if !isMap || kdf["name"] != "scrypt" {
+		t.Fatalf("Collected wrong kdf: %v", args["kdf"])
+	}
+
+	// stdin is restored, so creating files with the collected arguments must not prompt
+	for index := 0; index < 2; index++ {
+		cfg, err := file.Create("password", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cost, _ := cfg.Get("crypto.kdf.cost"); cost != kdf["cost"] {
+			t.Fatalf("Created with different kdf parameters: %v, collected %v", cost, kdf["cost"])
+		}
+		if err = cfg.Set("secret", []byte(testSecret)); err != nil {
+			t.Fatal(err)
+		}
+		if value, err := cfg.Get("secret"); err != nil || value != testSecret {
+			t.Fatalf("Could not decrypt %v: %v", value, err)
+		}
+	}
+}
+
+func TestLoadBytes(t *testing.T) {
+	fx.MockAWS()
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.kms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := file.LoadBytes(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cfg.Get("secret"); value != "asdf" {
+		t.Fatalf("Could not decrypt secret: %v", value)
+	}
+
+	// what WriteTo writes loads again
+	var written bytes.Buffer
+	if _, err = cfg.WriteTo(&written); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = file.LoadReader(&written); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cfg.Get("secret"); value != "asdf" {
+		t.Fatalf("Could not decrypt secret after a round trip: %v", value)
+	}
+
+	if cfg, err = file.LoadBytes(nil); err != nil || cfg.HasCrypto() {
+		t.Fatalf("Did not load empty contents as an empty config: %v", err)
+	}
+
+	tests := []struct {
+		contents string
+		options  []file.Option
+		err      string
+	}{
+		{"crypto: [", nil, "Could not parse YAML"},
+		{"crypto: a-string", nil, "Invalid config"},
+		{"a: b", []file.Option{file.WithLock()}, "Cannot lock"},
+	}
+	for _, tst := range tests {
+		if _, err = file.LoadBytes([]byte(tst.contents), tst.options...); err == nil || !strings.Contains(err.Error(), tst.err) {
+			t.Fatalf("Loaded <%s> with error %v, expected %s", tst.contents, err, tst.err)
+		}
+	}
+}
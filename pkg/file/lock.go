@@ -0,0 +1,52 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fileLock is an advisory lock held on a config file, so other processes loading it WithLock wait for us to be done
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile takes an exclusive advisory lock on the file at `path`, waiting for other holders to release it
+func lockFile(path string) (*fileLock, error) {
+	for {
+		locked, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fd := int(locked.Fd())
+		if err = syscall.Flock(fd, syscall.LOCK_EX|syscall.LOCK_NB); err == syscall.EWOULDBLOCK {
+			log.Infof("Waiting for another process to finish with %s", path)
+			err = syscall.Flock(fd, syscall.LOCK_EX)
+		}
+		if err != nil {
+			locked.Close()
+			return nil, fmt.Errorf("Could not lock %s: %s", path, err)
+		}
+
+		// whoever held the lock might have saved a new file in its place, lock that one instead
+		lockedInfo, err := locked.Stat()
+		if err != nil {
+			locked.Close()
+			return nil, err
+		}
+		if currentInfo, err := os.Stat(path); err == nil && os.SameFile(lockedInfo, currentInfo) {
+			return &fileLock{file: locked}, nil
+		}
+
+		log.Debugf("%s was replaced while waiting for its lock, trying again", path)
+		locked.Close()
+	}
+}
+
+// release the lock, closing the file releases it too
+func (lock *fileLock) release() error {
+	return lock.file.Close()
+}
@@ -0,0 +1,203 @@
+package file
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// a value at a keypath, and whether it's a secret
+type mergeLeaf struct {
+	value  interface{}
+	secret bool
+	// the file this value was taken from
+	source *ConfigFile
+}
+
+// Merge does a three-way merge of the decrypted values in `ours` and `theirs`, both changed from `base`
+//
+// The result is encrypted with ours' crypto settings, or theirs' if only they changed them. Values changed on both
+// sides to something different are conflicts: the result keeps ours, and their keypaths are returned in `conflicts`.
+// Unchanged secrets keep their ciphertext when the crypto settings they were encrypted with are kept
+func Merge(base, ours, theirs *ConfigFile) (merged *ConfigFile, conflicts []string, err error) {
+	baseCrypto, oursCrypto, theirsCrypto := base.cryptoSettings(), ours.cryptoSettings(), theirs.cryptoSettings()
+
+	primary := ours
+	switch {
+	case reflect.DeepEqual(oursCrypto, theirsCrypto):
+	case reflect.DeepEqual(oursCrypto, baseCrypto):
+		log.Debug("Only theirs changed crypto settings, using them")
+		primary = theirs
+	case !reflect.DeepEqual(theirsCrypto, baseCrypto):
+		conflicts = append(conflicts, "crypto")
+	}
+
+	baseLeaves, err := base.leaves()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not decrypt the common ancestor: %s", err)
+	}
+	oursLeaves, err := ours.leaves()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not decrypt ours: %s", err)
+	}
+	theirsLeaves, err := theirs.leaves()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not decrypt theirs: %s", err)
+	}
+
+	keyPaths := []string{}
+	seen := map[string]bool{}
+	for _, leaves := range []map[string]*mergeLeaf{baseLeaves, oursLeaves, theirsLeaves} {
+		for keyPath := range leaves {
+			if !seen[keyPath] {
+				seen[keyPath] = true
+				keyPaths = append(keyPaths, keyPath)
+			}
+		}
+	}
+	sort.Strings(keyPaths)
+
+	// keep our comments, and anything else a rebuilt tree would lose
+	merged = &ConfigFile{data: ours.data.Copy(), crypto: primary.crypto, Provider: primary.Provider}
+	if primary != ours {
+		crypto := &yaml.Tree{}
+		if err = primary.data.Get("crypto", &crypto); err == nil && crypto != nil {
+			err = merged.data.Set("crypto", crypto.Copy().Node)
+		} else {
+			err = merged.data.Delete("crypto")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	changed := map[string]*mergeLeaf{}
+	for _, keyPath := range keyPaths {
+		inBase, inOurs, inTheirs := baseLeaves[keyPath], oursLeaves[keyPath], theirsLeaves[keyPath]
+
+		leaf := inOurs
+		switch {
+		case inOurs.equals(inTheirs), inTheirs.equals(inBase):
+		case inOurs.equals(inBase):
+			leaf = inTheirs
+		default:
+			conflicts = append(conflicts, keyPath)
+		}
+
+		if leaf == nil {
+			if inOurs != nil {
+				log.Debugf("Removed %s", keyPath)
+				merged.removeLeaf(keyPath)
+			}
+			continue
+		}
+
+		// our values are there already, unless their secrets must be encrypted again
+		if leaf != inOurs || primary != ours {
+			changed[keyPath] = leaf
+		}
+	}
+
+	// values are set once everything removed is gone, since they may take the place of removed maps
+	for _, keyPath := range keyPaths {
+		if leaf, isChanged := changed[keyPath]; isChanged {
+			if err = merged.setLeaf(keyPath, leaf); err != nil {
+				return nil, nil, fmt.Errorf("Could not set %s: %s", keyPath, err)
+			}
+		}
+	}
+
+	return merged, conflicts, nil
+}
+
+// cryptoSettings returns the `crypto` property of this file, or nil if there's none
+func (cfg *ConfigFile) cryptoSettings() map[string]interface{} {
+	settings := map[string]interface{}{}
+	if err := cfg.data.Get("crypto", &settings); err != nil {
+		return nil
+	}
+	return settings
+}
+
+// leaves decrypts this file and flattens it into values by keypath, outside of `crypto`. Lists and empty maps are
+// single values
+func (cfg *ConfigFile) leaves() (leaves map[string]*mergeLeaf, err error) {
+	tree, err := cfg.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	delete(tree, "crypto")
+
+	secrets := map[string]bool{}
+	for _, keyPath := range cfg.ListSecrets() {
+		secrets[keyPath] = true
+	}
+
+	leaves = map[string]*mergeLeaf{}
+	var flatten func(prefix string, value interface{})
+	flatten = func(prefix string, value interface{}) {
+		if children, isMap := value.(map[string]interface{}); isMap && (len(children) > 0 || prefix == "") {
+			for key, child := range children {
+				keyPath := key
+				if prefix != "" {
+					keyPath = prefix + "." + key
+				}
+				flatten(keyPath, child)
+			}
+			return
+		}
+		leaves[prefix] = &mergeLeaf{value: value, secret: secrets[prefix], source: cfg}
+	}
+	flatten("", tree)
+
+	return leaves, nil
+}
+
+// removeLeaf removes a value from a merged file, along with the maps it leaves empty
+func (cfg *ConfigFile) removeLeaf(keyPath string) {
+	for {
+		// it may be gone along with its parent already
+		_ = cfg.data.Delete(keyPath)
+
+		dot := strings.LastIndex(keyPath, ".")
+		if dot < 0 {
+			return
+		}
+		keyPath = keyPath[:dot]
+		parent := map[string]interface{}{}
+		if err := cfg.data.Get(keyPath, &parent); err != nil || len(parent) > 0 {
+			return
+		}
+	}
+}
+
+// equals tells whether both leaves hold the same value, the same way, or are both missing
+func (leaf *mergeLeaf) equals(other *mergeLeaf) bool {
+	if leaf == nil || other == nil {
+		return leaf == other
+	}
+	return leaf.secret == other.secret && reflect.DeepEqual(leaf.value, other.value)
+}
+
+// setLeaf sets a merged value, copying it as it was in its source file, except for secrets that must be encrypted
+// with different crypto settings
+func (cfg *ConfigFile) setLeaf(keyPath string, leaf *mergeLeaf) error {
+	if leaf.secret && !reflect.DeepEqual(leaf.source.cryptoSettings(), cfg.cryptoSettings()) {
+		metadata, err := leaf.source.GetMetadata(keyPath)
+		if err != nil {
+			return err
+		}
+		return cfg.setSecret(keyPath, []byte(fmt.Sprintf("%v", leaf.value)), metadata)
+	}
+
+	node := &yaml.Tree{}
+	if err := leaf.source.data.Get(keyPath, &node); err != nil || node == nil {
+		return cfg.data.Set(keyPath, leaf.value)
+	}
+	return cfg.data.Set(keyPath, node.Node)
+}
@@ -0,0 +1,177 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+// saveAndLoad writes `contents` to `name` in `dir` and loads it back
+func saveAndLoad(t *testing.T, dir string, name string, contents string) *file.ConfigFile {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := file.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestMerge(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base, err := file.Create("kms", kmsKeyArgs(string(fx.MockKMSKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = base.Set("secret", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	for keyPath, value := range map[string]string{"plain": "a", "other": "x", "gone": "removed", "conflict": "base"} {
+		if err = base.VeryInsecurelySetPlaintext(keyPath, []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serialized, _ := base.Serialize()
+	base = saveAndLoad(t, dir, "base.yml", string(serialized))
+
+	ours := saveAndLoad(t, dir, "ours.yml", strings.Replace(string(serialized), "plain: a\n", "# a comment\nplain: a # of ours\n", 1))
+	_ = ours.VeryInsecurelySetPlaintext("plain", []byte("b"))
+	_ = ours.VeryInsecurelySetPlaintext("conflict", []byte("ours"))
+	_ = ours.VeryInsecurelySetPlaintext("nested.ours", []byte("added"))
+
+	theirs := saveAndLoad(t, dir, "theirs.yml", strings.Replace(string(serialized), "gone: removed\n", "", 1))
+	_ = theirs.Set("secret", []byte("two"))
+	_ = theirs.VeryInsecurelySetPlaintext("other", []byte("z"))
+	_ = theirs.VeryInsecurelySetPlaintext("conflict", []byte("theirs"))
+	_ = theirs.VeryInsecurelySetPlaintext("nested.theirs", []byte("added"))
+	if _, err = theirs.Get("gone"); err == nil {
+		t.Fatal("Did not remove a value from theirs")
+	}
+
+	merged, conflicts, err := file.Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conflicts, []string{"conflict"}) {
+		t.Fatalf("Found wrong conflicts: %v", conflicts)
+	}
+
+	values, err := merged.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(values, "crypto")
+	expected := map[string]interface{}{
+		"secret":   "two",
+		"plain":    "b",
+		"other":    "z",
+		"conflict": "ours",
+		"nested":   map[string]interface{}{"ours": "added", "theirs": "added"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("Merged wrong values: %v", values)
+	}
+
+	mergedContents, _ := merged.Serialize()
+	if !strings.Contains(string(mergedContents), "# a comment\nplain: b\n") {
+		t.Fatalf("Lost our comments: %s", mergedContents)
+	}
+
+	theirCiphertext, _ := theirs.Get("secret.ciphertext")
+	mergedCiphertext, _ := merged.Get("secret.ciphertext")
+	if theirCiphertext == nil || mergedCiphertext != theirCiphertext {
+		t.Fatal("Re-encrypted a secret that could be copied")
+	}
+}
+
+func TestReveal(t *testing.T) {
+	fx.MockAWS()
+	cfg, err := file.Create("kms", kmsKeyArgs(string(fx.MockKMSKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cfg.Set("nested.secret", []byte(testSecret)); err != nil {
+		t.Fatal(err)
+	}
+
+	revealed, err := cfg.Reveal(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(revealed), "secret: "+testSecret+" # encrypted\n") || strings.Contains(string(revealed), "ciphertext") {
+		t.Fatalf("Did not reveal secrets: %s", revealed)
+	}
+
+	hash, _ := cfg.Get("nested.secret.hash")
+	revealed, err = cfg.Reveal(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(revealed), "secret: "+hash.(string)+" # encrypted, hash of the value\n") || strings.Contains(string(revealed), testSecret) {
+		t.Fatalf("Did not show hashes: %s", revealed)
+	}
+
+	if value, _ := cfg.Get("nested.secret"); value != testSecret {
+		t.Fatalf("Revealing changed the file: %v", value)
+	}
+}
+
+func TestMergeCrypto(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base, err := file.Create("kms", kmsKeyArgs(string(fx.MockKMSKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = base.Set("secret", []byte(testSecret)); err != nil {
+		t.Fatal(err)
+	}
+	serialized, _ := base.Serialize()
+	base = saveAndLoad(t, dir, "base.yml", string(serialized))
+	ours := saveAndLoad(t, dir, "ours.yml", string(serialized))
+	_ = ours.Set("added", []byte("ours"))
+
+	otherKey := strings.Replace(string(fx.MockKMSKey), "us-east-1", "us-west-2", 1)
+	theirs, err := base.Rekey("kms", kmsKeyArgs(otherKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, conflicts, err := file.Merge(base, ours, theirs)
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("Could not merge: %v, %v", conflicts, err)
+	}
+	if key, _ := merged.Get("crypto.key"); key != otherKey {
+		t.Fatalf("Did not take their crypto settings: %v", key)
+	}
+	if value, err := merged.Get("added"); err != nil || value != "ours" {
+		t.Fatalf("Did not re-encrypt our secret: %v, %v", value, err)
+	}
+	if len(merged.ListSecrets()) != 2 {
+		t.Fatalf("Lost secrets: %v", merged.ListSecrets())
+	}
+
+	ours, _ = base.Rekey("kms", kmsKeyArgs(strings.Replace(string(fx.MockKMSKey), "us-east-1", "eu-west-1", 1)))
+	if _, conflicts, _ = file.Merge(base, ours, theirs); !reflect.DeepEqual(conflicts, []string{"crypto"}) {
+		t.Fatalf("Did not report conflicting crypto settings: %v", conflicts)
+	}
+}
@@ -0,0 +1,98 @@
+package file
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+)
+
+// Metadata describes a secret, and is stored in plaintext next to its ciphertext
+type Metadata struct {
+	// What the secret is for
+	Description string
+	// Who to ask about the secret, like a team or a person
+	Owner string
+	// When the secret's value was last set, zero if unknown
+	Created time.Time
+	// When the secret's value stops being valid, zero if it doesn't expire
+	Expires time.Time
+}
+
+// SetOption configures how ConfigFile.Set stores a secret
+type SetOption func(*Metadata)
+
+// WithDescription describes what the secret is for
+func WithDescription(description string) SetOption {
+	return func(metadata *Metadata) { metadata.Description = description }
+}
+
+// WithOwner records who to ask about the secret
+func WithOwner(owner string) SetOption {
+	return func(metadata *Metadata) { metadata.Owner = owner }
+}
+
+// WithExpiry records when the secret's value stops being valid
+func WithExpiry(expires time.Time) SetOption {
+	return func(metadata *Metadata) { metadata.Expires = expires }
+}
+
+// how metadata is serialized, next to `ciphertext`, `encrypted` and `hash`
+type serializedMetadata struct {
+	Description string `yaml:"description,omitempty"`
+	Owner       string `yaml:"owner,omitempty"`
+	Created     string `yaml:"created,omitempty"`
+	Expires     string `yaml:"expires,omitempty"`
+}
+
+// GetMetadata returns the metadata for the secret at `keyPath`, empty if it has none
+func (cfg *ConfigFile) GetMetadata(keyPath string) (metadata *Metadata, err error) {
+	node := &yaml.Tree{}
+	if err = cfg.data.Get(keyPath, &node); err != nil {
+		return nil, err
+	}
+	if node == nil || !node.IsEncrypted() {
+		return nil, fmt.Errorf("There is no secret at %s", keyPath)
+	}
+
+	serialized := &serializedMetadata{}
+	if err = node.Decode(serialized); err != nil {
+		return nil, err
+	}
+
+	metadata = &Metadata{Description: serialized.Description, Owner: serialized.Owner}
+	if metadata.Created, err = parseTime(serialized.Created); err != nil {
+		return nil, fmt.Errorf("Invalid %s.created: %s", keyPath, err)
+	}
+	if metadata.Expires, err = parseTime(serialized.Expires); err != nil {
+		return nil, fmt.Errorf("Invalid %s.expires: %s", keyPath, err)
+	}
+	return metadata, nil
+}
+
+// serialize adds these metadata's fields to an encrypted node's `fields`, leaving out empty ones
+func (metadata *Metadata) serialize(fields map[string]interface{}) {
+	if metadata.Description != "" {
+		fields["description"] = metadata.Description
+	}
+	if metadata.Owner != "" {
+		fields["owner"] = metadata.Owner
+	}
+	if !metadata.Created.IsZero() {
+		fields["created"] = metadata.Created.UTC().Format(time.RFC3339)
+	}
+	if !metadata.Expires.IsZero() {
+		fields["expires"] = metadata.Expires.UTC().Format(time.RFC3339)
+	}
+}
+
+// parseTime reads a timestamp in RFC 3339 format, or a date like `2021-01-31`
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
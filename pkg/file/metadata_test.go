@@ -0,0 +1,100 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+func TestMetadata(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, err := file.Create("kms", kmsKeyArgs(string(fx.MockKMSKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	expires := time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)
+	err = cfg.Set("db.password", []byte("hunter2"), file.WithDescription("The database password"), file.WithOwner("team-db"), file.WithExpiry(expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cfg.Set("bare", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	serialized, _ := cfg.Serialize()
+	cfg = saveAndLoad(t, dir, "config.yml", string(serialized))
+
+	metadata, err := cfg.GetMetadata("db.password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Description != "The database password" || metadata.Owner != "team-db" || !metadata.Expires.Equal(expires) {
+		t.Fatalf("Read wrong metadata: %+v", metadata)
+	}
+	if metadata.Created.Before(before) || metadata.Created.After(time.Now()) {
+		t.Fatalf("Stamped the wrong creation time: %s", metadata.Created)
+	}
+
+	bare, err := cfg.GetMetadata("bare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bare.Description != "" || bare.Owner != "" || !bare.Expires.IsZero() || bare.Created.IsZero() {
+		t.Fatalf("Read wrong metadata for a bare secret: %+v", bare)
+	}
+
+	if _, err = cfg.GetMetadata("missing"); err == nil {
+		t.Fatal("Read metadata for a missing secret")
+	}
+
+	t.Run("rekey keeps metadata", func(t *testing.T) {
+		rekeyed, err := cfg.Rekey("kms", kmsKeyArgs(string(fx.MockKMSKey)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept, err := rekeyed.GetMetadata("db.password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *kept != *metadata {
+			t.Fatalf("Changed metadata when re-keying: %+v, expected %+v", kept, metadata)
+		}
+	})
+
+	t.Run("set keeps description and owner", func(t *testing.T) {
+		if err := cfg.Set("db.password", []byte("correct horse")); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := cfg.GetMetadata("db.password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Description != metadata.Description || updated.Owner != metadata.Owner {
+			t.Fatalf("Lost description or owner: %+v", updated)
+		}
+		if !updated.Expires.IsZero() {
+			t.Fatalf("Kept the old value's expiry: %s", updated.Expires)
+		}
+	})
+
+	t.Run("plaintext values have no metadata", func(t *testing.T) {
+		if err := cfg.VeryInsecurelySetPlaintext("plain", []byte("text")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cfg.GetMetadata("plain"); err == nil {
+			t.Fatal("Read metadata for a plaintext value")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package file
+
+import (
+	"fmt"
+
+	"github.com/blinkhealth/go-config-yourself/internal/yaml"
+
+	log "github.com/sirupsen/logrus"
+	yml "gopkg.in/yaml.v3"
+)
+
+// Reveal returns this file as YAML with every secret's ciphertext replaced by its plaintext, marked with an
+// `# encrypted` comment, to review changes to it
+//
+// If `decrypt` is false, or any secret fails to decrypt, secrets show the hash of their plaintext instead. Hashes
+// change along with crypto settings, but tell whether a value changed without revealing it
+func (cfg *ConfigFile) Reveal(decrypt bool) ([]byte, error) {
+	serialized, err := cfg.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	// work on a copy, leaving this file's secrets encrypted
+	revealed, err := yaml.FromBytes(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := cfg.ListSecrets()
+	values := map[string]string{}
+	if decrypt && cfg.HasCrypto() {
+		for _, keyPath := range secrets {
+			value, err := cfg.Get(keyPath)
+			if err != nil {
+				log.Warnf("Could not decrypt %s, showing hashes instead: %s", keyPath, err)
+				values = map[string]string{}
+				break
+			}
+			values[keyPath] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	for _, keyPath := range secrets {
+		node := &yaml.Tree{}
+		if err = revealed.Get(keyPath, &node); err != nil {
+			return nil, err
+		}
+
+		value, decrypted := values[keyPath]
+		comment := "# encrypted"
+		if !decrypted {
+			if err = revealed.Get(keyPath+".hash", &value); err != nil {
+				return nil, err
+			}
+			comment = "# encrypted, hash of the value"
+		}
+
+		*node.Node = yml.Node{
+			Kind:        yml.ScalarNode,
+			Tag:         "!!str",
+			Value:       value,
+			LineComment: comment,
+		}
+	}
+
+	return revealed.Serialize()
+}
@@ -0,0 +1,143 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SaveOption configures how a ConfigFile is saved
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	// keep the previous contents of the file next to it
+	backup bool
+}
+
+// WithBackup keeps the previous contents of a file being saved at its path with a `.bak` suffix
+func WithBackup() SaveOption {
+	return func(opts *saveOptions) {
+		opts.backup = true
+	}
+}
+
+// WriteTo writes the config as YAML to `w`, implementing io.WriterTo
+func (cfg *ConfigFile) WriteTo(w io.Writer) (n int64, err error) {
+	serialized, err := cfg.Serialize()
+	if err != nil {
+		return 0, err
+	}
+	return bytes.NewReader(serialized).WriteTo(w)
+}
+
+// Save writes the config as YAML to `path`, atomically
+//
+// The YAML is written to a temporary file next to `path` and renamed over it, so readers see either the old or new
+// contents and never half of a file. An existing file keeps its permissions and, when allowed, its owner. Save does
+// not lock `path` by itself: Load the file WithLock to keep other processes from changing it in the meantime
+func (cfg *ConfigFile) Save(path string, options ...SaveOption) (err error) {
+	opts := &saveOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	serialized, err := cfg.Serialize()
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	var existing os.FileInfo
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		// replace the file a symlink points to, not the symlink
+		path = resolved
+		if existing, err = os.Stat(path); err != nil {
+			return err
+		}
+		mode = existing.Mode().Perm()
+	}
+
+	if opts.backup && existing != nil {
+		previous, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Could not back up %s: %s", path, err)
+		}
+		if err = writeAtomically(path+".bak", previous, mode, existing); err != nil {
+			return fmt.Errorf("Could not back up %s: %s", path, err)
+		}
+		log.Debugf("Backed up %s", path)
+	}
+
+	if err = writeAtomically(path, serialized, mode, existing); err != nil {
+		return fmt.Errorf("Unable to write configuration to %s: %s", path, err)
+	}
+	return nil
+}
+
+// writeAtomically replaces the file at `path` with `contents` through a temporary file, owned like `owner` if not nil
+func writeAtomically(path string, contents []byte, mode os.FileMode, owner os.FileInfo) (err error) {
+	temp, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s.*.tmp", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err = temp.Write(contents); err != nil {
+		return err
+	}
+	if err = temp.Chmod(mode); err != nil {
+		return err
+	}
+	if owner != nil {
+		if err = keepOwner(temp, owner); err != nil {
+			log.Warnf("Could not keep the owner of %s: %s", path, err)
+		}
+	}
+	if err = temp.Sync(); err != nil {
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// keepOwner gives `temp` the owner and group of `owner`, if they differ
+func keepOwner(temp *os.File, owner os.FileInfo) error {
+	wanted, ok := owner.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	info, err := temp.Stat()
+	if err != nil {
+		return err
+	}
+	if current, ok := info.Sys().(*syscall.Stat_t); ok && current.Uid == wanted.Uid && current.Gid == wanted.Gid {
+		return nil
+	}
+
+	return temp.Chown(int(wanted.Uid), int(wanted.Gid))
+}
+
+// Close releases the lock taken by loading this file WithLock, if any
+func (cfg *ConfigFile) Close() error {
+	if cfg.lock == nil {
+		return nil
+	}
+	err := cfg.lock.release()
+	cfg.lock = nil
+	return err
+}
@@ -0,0 +1,116 @@
+package file_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	original, err := ioutil.ReadFile(fx.Path("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.yml")
+	if err = os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := file.Load(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cfg.VeryInsecurelySetPlaintext("saved", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err = cfg.Save(link, file.WithBackup()); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Replaced the symlink: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Did not keep permissions: %v, %v", info.Mode(), err)
+	}
+	if backup, _ := ioutil.ReadFile(path + ".bak"); !bytes.Equal(backup, original) {
+		t.Fatalf("Backed up wrong contents: %s", backup)
+	}
+
+	saved, _ := ioutil.ReadFile(path)
+	written := &bytes.Buffer{}
+	if _, err = cfg.WriteTo(written); err != nil || !bytes.Equal(written.Bytes(), saved) {
+		t.Fatalf("Wrote different contents than saved: %s, %v", written, err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 3 {
+		t.Fatalf("Left temporary files behind: %d files", len(files))
+	}
+}
+
+func TestLoadWithLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	if err = ioutil.WriteFile(path, []byte("count: first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := file.Load(path, file.WithLock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := make(chan *file.ConfigFile)
+	go func() {
+		waiting, err := file.Load(path, file.WithLock())
+		if err != nil {
+			t.Error(err)
+		}
+		loaded <- waiting
+	}()
+
+	select {
+	case <-loaded:
+		t.Fatal("Loaded a locked file")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err = cfg.VeryInsecurelySetPlaintext("count", []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if err = cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err = cfg.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	waiting := <-loaded
+	defer waiting.Close()
+	if value, _ := waiting.Get("count"); value != "second" {
+		t.Fatalf("Read the file before it was saved: %v", value)
+	}
+}
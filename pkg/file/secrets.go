@@ -6,11 +6,13 @@ import (
 	"encoding/base64"
 	"encoding/gob"
 	"fmt"
+	"strings"
 
 	"github.com/blinkhealth/go-config-yourself/internal/yaml"
 	pvd "github.com/blinkhealth/go-config-yourself/pkg/provider"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/scrypt"
+	yml "gopkg.in/yaml.v3"
 )
 
 const (
@@ -26,13 +28,20 @@ const (
 	// > consider setting N to the highest power of 2 you can derive within 100 milliseconds.
 )
 
+func init() {
+	// providers may serialize nested maps (like `crypto.kdf`) or lists (like `crypto.replicas`)
+	// and gob needs to know about them before hashing with them
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
 type cryptoDisabledError struct{}
 
 func (cryptoDisabledError) Error() string {
 	return "Unable to decrypt, config file has no `crypto` property, or the crypto provider is not enabled"
 }
 
-func decryptNode(node *yaml.Tree, provider pvd.Crypto) (interface{}, error) {
+func decryptNode(node *yaml.Tree, provider pvd.Crypto, keyPath string) (interface{}, error) {
 	if node == nil {
 		return nil, nil
 	}
@@ -43,7 +52,13 @@ func decryptNode(node *yaml.Tree, provider pvd.Crypto) (interface{}, error) {
 				return nil, cryptoDisabledError{}
 			}
 
-			plainText, err := provider.Decrypt(*node.Secret)
+			var plainText string
+			var err error
+			if contextual, ok := provider.(pvd.ContextualCrypto); ok {
+				plainText, err = contextual.DecryptAt(keyPath, *node.Secret)
+			} else {
+				plainText, err = provider.Decrypt(*node.Secret)
+			}
 
 			if err != nil {
 				return nil, err
@@ -59,7 +74,7 @@ func decryptNode(node *yaml.Tree, provider pvd.Crypto) (interface{}, error) {
 		}
 
 		for key, value := range outerMap {
-			decryptedValue, err := decryptNode(value, provider)
+			decryptedValue, err := decryptNode(value, provider, fmt.Sprintf("%s.%s", keyPath, key))
 			if err != nil {
 				return nil, err
 			}
@@ -74,9 +89,15 @@ func decryptNode(node *yaml.Tree, provider pvd.Crypto) (interface{}, error) {
 	return value, err
 }
 
-func encryptCipherText(plainText []byte, provider pvd.Crypto) (map[string]interface{}, error) {
+func encryptCipherText(plainText []byte, provider pvd.Crypto, keyPath string) (map[string]interface{}, error) {
 	log.Debugf("encrypting %d bytes", len(plainText))
-	encryptedBytes, err := provider.Encrypt(plainText)
+	var encryptedBytes []byte
+	var err error
+	if contextual, ok := provider.(pvd.ContextualCrypto); ok {
+		encryptedBytes, err = contextual.EncryptAt(keyPath, plainText)
+	} else {
+		encryptedBytes, err = provider.Encrypt(plainText)
+	}
 
 	if err != nil {
 		return nil, err
@@ -136,3 +157,47 @@ func secretsForNode(node *yaml.Tree, parent string) []string {
 
 	return secrets
 }
+
+// the names ListTypes uses for yaml tags
+var typeNames = map[string]string{
+	"!!null":  "null",
+	"!!bool":  "boolean",
+	"!!int":   "integer",
+	"!!float": "number",
+	"!!str":   "string",
+}
+
+func typesForNode(node *yml.Node, parent string, types map[string]string) {
+	switch node.Kind {
+	case yml.AliasNode:
+		typesForNode(node.Alias, parent, types)
+	case yml.SequenceNode:
+		types[parent] = "list"
+	case yml.MappingNode:
+		tree := &yaml.Tree{}
+		if err := tree.UnmarshalYAML(node); err == nil && tree.IsEncrypted() {
+			types[parent] = "secret"
+			return
+		}
+		if len(node.Content) == 0 && parent != "" {
+			types[parent] = "map"
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := node.Content[i].Value
+			if parent == "" && path == "crypto" {
+				continue
+			}
+			if parent != "" {
+				path = fmt.Sprintf("%s.%s", parent, path)
+			}
+			typesForNode(node.Content[i+1], path, types)
+		}
+	default:
+		name, known := typeNames[node.ShortTag()]
+		if !known {
+			name = strings.TrimPrefix(node.ShortTag(), "!!")
+		}
+		types[parent] = name
+	}
+}
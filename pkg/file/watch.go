@@ -0,0 +1,232 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/blinkhealth/go-config-yourself/pkg/crypto/password"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDebounce is how long Watch waits for a file to stop changing before loading it again
+const DefaultDebounce = 100 * time.Millisecond
+
+// DefaultPollInterval is how often Watch checks files for changes when the system can't notify it
+const DefaultPollInterval = time.Second
+
+// WithDebounce makes Watch wait until a file stops changing for `wait` before loading it again, so editors saving it in
+// several steps cause a single reload
+func WithDebounce(wait time.Duration) Option {
+	return func(opts *loadOptions) {
+		opts.debounce = wait
+	}
+}
+
+// WithPollInterval makes Watch check for changes every `interval` when the system can't notify it of them
+func WithPollInterval(interval time.Duration) Option {
+	return func(opts *loadOptions) {
+		opts.pollInterval = interval
+	}
+}
+
+// Watcher holds the latest good version of a config file, loading it again whenever it changes
+type Watcher struct {
+	path    string
+	options []Option
+	opts    *loadOptions
+
+	mu      sync.RWMutex
+	current *ConfigFile
+	info    os.FileInfo
+
+	updates chan *ConfigFile
+	errors  chan error
+	done    chan struct{}
+	stopped chan struct{}
+	close   sync.Once
+}
+
+// Watch loads the file at `path` with `options`, and keeps loading it again when it changes. Changes are noticed
+// through inotify on Linux, and by checking the file every DefaultPollInterval elsewhere
+//
+// A new version is only used once all of its secrets decrypt. When a change can't be loaded, the error is sent to
+// Errors and Current keeps returning the last good version. Files can't be watched WithLock, and a password given
+// WithPasswordFD is read once and used for every reload
+func Watch(path string, options ...Option) (watcher *Watcher, err error) {
+	opts := newLoadOptions(append([]Option{WithDebounce(DefaultDebounce), WithPollInterval(DefaultPollInterval)}, options...))
+	if opts.lock {
+		return nil, errors.New("Cannot watch a file WithLock, it would never be unlocked")
+	}
+
+	if fd, hasFD := opts.providerArgs["password-fd"].(int); hasFD {
+		pass, err := password.ReadFD(fd)
+		if err != nil {
+			return nil, err
+		}
+		options = append(append([]Option{}, options...), withoutProviderArg("password-fd"), WithPassword(pass))
+	}
+
+	watcher = &Watcher{
+		path:    path,
+		options: options,
+		opts:    opts,
+		updates: make(chan *ConfigFile, 1),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if watcher.info, err = os.Stat(path); err != nil {
+		return nil, err
+	}
+	if watcher.current, err = watcher.load(); err != nil {
+		return nil, err
+	}
+
+	events, stop, err := notifications(path)
+	if err != nil {
+		log.Debugf("Polling %s for changes every %s: %s", path, opts.pollInterval, err)
+		events, stop = nil, func() error { return nil }
+	}
+
+	go watcher.run(events, stop)
+	return watcher, nil
+}
+
+// Current returns the latest version of the file that loaded
+func (watcher *Watcher) Current() *ConfigFile {
+	watcher.mu.RLock()
+	defer watcher.mu.RUnlock()
+	return watcher.current
+}
+
+// Updates receives every new version of the file that loads. Versions nobody received yet are replaced by newer ones,
+// and the channel is closed by Close
+func (watcher *Watcher) Updates() <-chan *ConfigFile {
+	return watcher.updates
+}
+
+// Errors receives why a change to the file couldn't be loaded. Errors nobody received yet are dropped
+func (watcher *Watcher) Errors() <-chan error {
+	return watcher.errors
+}
+
+// Close stops watching the file
+func (watcher *Watcher) Close() error {
+	watcher.close.Do(func() {
+		close(watcher.done)
+		<-watcher.stopped
+	})
+	return nil
+}
+
+func (watcher *Watcher) run(events <-chan struct{}, stop func() error) {
+	defer close(watcher.stopped)
+	defer close(watcher.updates)
+	defer stop()
+
+	var poll <-chan time.Time
+	var ticker *time.Ticker
+	startPolling := func() {
+		ticker = time.NewTicker(watcher.opts.pollInterval)
+		poll = ticker.C
+	}
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+	if events == nil {
+		startPolling()
+	}
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-watcher.done:
+			return
+		case _, open := <-events:
+			if !open {
+				log.Debugf("Stopped receiving notifications for %s, polling it instead", watcher.path)
+				events = nil
+				startPolling()
+				continue
+			}
+			settled = time.After(watcher.opts.debounce)
+		case <-poll:
+			if watcher.changed() {
+				settled = time.After(watcher.opts.debounce)
+			}
+		case <-settled:
+			settled = nil
+			watcher.reload()
+		}
+	}
+}
+
+// changed tells whether the file is different from the last version seen
+func (watcher *Watcher) changed() bool {
+	info, err := os.Stat(watcher.path)
+	if err != nil {
+		// it may be getting replaced, check again later
+		return false
+	}
+
+	watcher.mu.RLock()
+	defer watcher.mu.RUnlock()
+	return !os.SameFile(info, watcher.info) || !info.ModTime().Equal(watcher.info.ModTime()) || info.Size() != watcher.info.Size()
+}
+
+func (watcher *Watcher) reload() {
+	if !watcher.changed() {
+		return
+	}
+
+	info, err := os.Stat(watcher.path)
+	if err != nil {
+		return
+	}
+
+	config, err := watcher.load()
+	watcher.mu.Lock()
+	// don't try this version again until it changes
+	watcher.info = info
+	if err == nil {
+		watcher.current = config
+	}
+	watcher.mu.Unlock()
+
+	if err != nil {
+		log.Debugf("Could not reload %s: %s", watcher.path, err)
+		select {
+		case watcher.errors <- fmt.Errorf("Could not reload %s: %s", watcher.path, err):
+		default:
+		}
+		return
+	}
+
+	log.Debugf("Reloaded %s", watcher.path)
+	// replace the pending version, if nobody received it yet
+	select {
+	case <-watcher.updates:
+	default:
+	}
+	watcher.updates <- config
+}
+
+// load the file, and make sure its secrets decrypt
+func (watcher *Watcher) load() (*ConfigFile, error) {
+	config, err := Load(watcher.path, watcher.options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = config.GetAll(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
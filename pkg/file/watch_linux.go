@@ -0,0 +1,47 @@
+// +build linux
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// the changes to a directory that may replace or modify a file in it
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM
+
+// notifications sends on `events` whenever something changes in the directory holding `path`, until `stop` is called.
+// The directory is watched instead of the file since editors and Save replace files instead of writing to them, and
+// mounted configs are often swapped through symlinks
+func notifications(path string) (events <-chan struct{}, stop func() error, err error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err = syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask); err != nil {
+		syscall.Close(fd)
+		return nil, nil, err
+	}
+
+	// a non-blocking file is read through the runtime's poller, so closing it stops pending reads
+	inotify := os.NewFile(uintptr(fd), "inotify")
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			if _, err := inotify.Read(buffer); err != nil {
+				return
+			}
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes, inotify.Close, nil
+}
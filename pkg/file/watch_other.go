@@ -0,0 +1,10 @@
+// +build !linux
+
+package file
+
+import "errors"
+
+// notifications are only available on Linux, other systems poll for changes
+func notifications(path string) (events <-chan struct{}, stop func() error, err error) {
+	return nil, nil, errors.New("change notifications are not supported on this system")
+}
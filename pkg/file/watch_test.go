@@ -0,0 +1,141 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fx "github.com/blinkhealth/go-config-yourself/internal/fixtures"
+	"github.com/blinkhealth/go-config-yourself/pkg/file"
+)
+
+func TestWatch(t *testing.T) {
+	fx.MockAWS()
+	dir, err := ioutil.TempDir("", "gcy-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.kms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := file.Watch(path, file.WithDebounce(10*time.Millisecond), file.WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	if value, _ := watcher.Current().Get("secret"); value != "asdf" {
+		t.Fatalf("Did not load the file: %v", value)
+	}
+
+	// save it the way gcy does, replacing the file
+	config := watcher.Current()
+	if err = config.VeryInsecurelySetPlaintext("added", []byte(`"new"`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = config.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case updated := <-watcher.Updates():
+		if value, _ := updated.Get("added"); value != "new" {
+			t.Fatalf("Sent an old version: %v", value)
+		}
+		if watcher.Current() != updated {
+			t.Fatal("Current did not return the new version")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Did not notice the file changed")
+	}
+
+	// secrets that don't decrypt are just as broken as bad yaml
+	broken := append(contents, []byte("broken:\n  encrypted: true\n  ciphertext: bm90IGNpcGhlcnRleHQ=\n")...)
+	for _, change := range [][]byte{[]byte("crypto: ["), broken} {
+		if err = ioutil.WriteFile(path, change, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err = <-watcher.Errors():
+			if !strings.HasPrefix(err.Error(), "Could not reload") {
+				t.Fatalf("Sent the wrong error: %s", err)
+			}
+		case <-watcher.Updates():
+			t.Fatal("Sent a broken version")
+		case <-time.After(5 * time.Second):
+			t.Fatal("Did not notice the file changed")
+		}
+
+		if value, _ := watcher.Current().Get("added"); value != "new" {
+			t.Fatalf("Did not keep the last good version: %v", value)
+		}
+	}
+
+	watcher.Close()
+	if _, open := <-watcher.Updates(); open {
+		t.Fatal("Did not close Updates")
+	}
+}
+
+func TestWatchWithLock(t *testing.T) {
+	if _, err := file.Watch(fx.Path("encrypted.kms"), file.WithLock()); err == nil {
+		t.Fatal("Watched a locked file")
+	}
+}
+
+func TestWatchPasswordFD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcy-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	contents, err := ioutil.ReadFile(fx.Path("encrypted.password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = writer.WriteString("password\n")
+	writer.Close()
+
+	watcher, err := file.Watch(path, file.WithPasswordFD(int(reader.Fd())), file.WithDebounce(10*time.Millisecond), file.WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	if err = ioutil.WriteFile(path, append(contents, []byte("added: true\n")...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case updated := <-watcher.Updates():
+		if value, err := updated.Get("secret"); err != nil || value != "asdf" {
+			t.Fatalf("Could not decrypt the new version: %v %v", value, err)
+		}
+	case err = <-watcher.Errors():
+		t.Fatalf("Did not reuse the password: %s", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Did not notice the file changed")
+	}
+}
@@ -8,7 +8,7 @@
 
 // Package provider represents an abstract provider. Providers must implement the Crypto interface, and call RegisterProvider during their init method
 //
-// Provider packages must also be imported by pkg/file
+// Provider packages must also be imported by pkg/file, unless they're plugins discovered by pkg/crypto/plugin
 package provider
 
 // Argument represents values required for providers to initialize or rekey
@@ -35,6 +35,29 @@ type Crypto interface {
 	Decrypt([]byte) (string, error)
 }
 
+// ContextualCrypto is implemented by providers that bind each secret to the keypath it's stored at. When available,
+// these are used instead of Crypto's Encrypt and Decrypt
+type ContextualCrypto interface {
+	// EncryptAt takes a byte slice to be stored at a keypath and returns it encrypted
+	EncryptAt(keyPath string, plainText []byte) ([]byte, error)
+	// DecryptAt takes a byte slice stored at a keypath and returns plaintext for it
+	DecryptAt(keyPath string, cipherText []byte) (string, error)
+}
+
+// Reporter is implemented by providers that can tell where they decrypted the last secret, like the region or server
+// that answered. `gcy info` shows these details
+type Reporter interface {
+	// Report returns details about the last decryption, or nil if nothing was decrypted yet
+	Report() map[string]string
+}
+
+// Reusable is implemented by providers that can turn the choices made while replacing their key, like a key selected
+// from a list or a password typed in, back into arguments. Operations on many files use these to prompt only once
+type Reusable interface {
+	// Arguments returns the arguments that make Replace choose the same key again, named like their flags
+	Arguments() map[string]interface{}
+}
+
 // Constructor is the signature of the function to initialize providers
 type Constructor = func(map[string]interface{}) (Crypto, error)
 
@@ -52,8 +75,13 @@ var ProviderList = []string{}
 // Providers holds providers Registrations
 var Providers = map[string]Registration{}
 
-// RegisterProvider is what a provider calls in their `init` func to expose them to the config system
+// RegisterProvider is what a provider calls in their `init` func to expose them to the config system. Names can only
+// be registered once, later registrations are ignored
 func RegisterProvider(name string, constructor Constructor, flags []Argument) {
+	if _, exists := Providers[name]; exists {
+		return
+	}
+
 	ProviderList = append(ProviderList, name)
 	Providers[name] = Registration{
 		New:   constructor,
@@ -61,10 +89,17 @@ func RegisterProvider(name string, constructor Constructor, flags []Argument) {
 	}
 }
 
-// AvailableFlags enumerates all available provider flags
+// AvailableFlags enumerates all available provider flags, once per flag name
 func AvailableFlags() (flags []Argument) {
-	for _, pvd := range Providers {
-		flags = append(flags, pvd.Flags...)
+	seen := map[string]bool{}
+	for _, name := range ProviderList {
+		for _, flag := range Providers[name].Flags {
+			if seen[flag.Name] {
+				continue
+			}
+			seen[flag.Name] = true
+			flags = append(flags, flag)
+		}
 	}
 	return
 }
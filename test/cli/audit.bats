@@ -0,0 +1,35 @@
+#!/usr/bin/env bats
+load "conftest"
+
+@test "audit: passes secrets with owners that don't expire soon" {
+  file=$(fixture encrypted.kms)
+  bc set --owner team-db --description "The database password" --expires 100d $file db.password <<<'hunter2'
+
+  run $CMD audit --ignore-unowned $file
+  [[ $status == 0 ]]
+}
+
+@test "audit: reports expiring and unowned secrets" {
+  file=$(fixture encrypted.kms)
+  bc set --owner team-db --expires 2d $file db.password <<<'hunter2'
+
+  run $CMD audit $file
+  [[ $status == 1 ]]
+  [[ $output == *"$file: db.password expires in 2 day(s)"* ]]
+  [[ $output == *"$file: secret has no owner"* ]]
+}
+
+@test "audit: rekey keeps metadata" {
+  file=$(fixture encrypted.kms)
+  bc set --owner team-db --expires 2030-01-31 $file db.password <<<'hunter2'
+  bc rekey --key "$GOOD_KEY" $file
+
+  [[ $(grep -c "owner: team-db" $file) == 1 ]]
+  [[ $(grep -c "expires: \"\?2030-01-31" $file) == 1 ]]
+}
+
+@test "audit: set refuses metadata for plain text values" {
+  file=$(fixture encrypted.kms)
+  run $CMD set --plain-text --owner team-db $file plain <<<'value'
+  [[ $status == 99 ]]
+}
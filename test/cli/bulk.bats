@@ -0,0 +1,75 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  export DIR="$WORKDIR/configs"
+  mkdir -p "$DIR/nested"
+  cp test/fixtures/encrypted.kms.yaml "$DIR/one.yml"
+  cp test/fixtures/encrypted.kms.yaml "$DIR/nested/two.yaml"
+  cp test/fixtures/plaintext.yaml "$DIR/plain.yml"
+}
+
+@test "bulk: get reads a value from every file in a directory" {
+  run $CMD get "$DIR" secret
+  [[ $status == 99 ]]
+  [[ $output == *"Read 2 of 3 file(s), 1 failed"* ]]
+
+  run $CMD get --continue-on-error "$DIR" secret
+  [[ $status == 99 ]]
+  [[ $output == *"\"$DIR/nested/two.yaml\":\"asdf\""* ]]
+  [[ $output == *"\"$DIR/one.yml\":\"asdf\""* ]]
+}
+
+@test "bulk: get accepts globs" {
+  # quoted, so gcy expands the glob instead of bash
+  run $CMD get "$DIR/**/*.yaml" secret
+  [[ $status == 0 ]]
+  [[ $output == *"{\"$DIR/nested/two.yaml\":\"asdf\"}"* ]]
+}
+
+@test "bulk: rekey re-encrypts every file in a directory" {
+  bc rekey --key ${GOOD_KEY/us-east-1/us-west-2} "$DIR"
+  grep us-west-2 "$DIR/one.yml"
+  grep us-west-2 "$DIR/nested/two.yaml"
+  ! grep crypto "$DIR/plain.yml"
+
+  export CONFIG_PASSWORD="$GOOD_PASSWORD"
+  run $CMD rekey --provider password "$DIR/**/*.y*ml"
+  [[ $status == 0 ]]
+  grep "provider: password" "$DIR/one.yml"
+  grep "provider: password" "$DIR/nested/two.yaml"
+  [[ "$(bc get "$DIR/one.yml" secret)" == *"asdf"* ]]
+}
+
+@test "bulk: rekey writes nothing unless every file succeeds" {
+  sed -i 's/ciphertext: .*/ciphertext: AAAA/' "$DIR/one.yml"
+
+  run $CMD rekey --key ${GOOD_KEY/us-east-1/us-west-2} "$DIR"
+  [[ $status == 2 ]]
+  [[ $output == *"no files were written"* ]]
+  ! grep us-west-2 "$DIR/nested/two.yaml"
+
+  run $CMD rekey --continue-on-error --key ${GOOD_KEY/us-east-1/us-west-2} "$DIR"
+  [[ $status == 2 ]]
+  [[ $output == *"Re-keyed 1 of 2 file(s), 1 failed"* ]]
+  grep us-west-2 "$DIR/nested/two.yaml"
+  ! grep us-west-2 "$DIR/one.yml"
+}
+
+@test "bulk: reads a password file descriptor once for every file" {
+  rm "$DIR/plain.yml"
+  cp test/fixtures/encrypted.password.yaml "$DIR/one.yml"
+  cp test/fixtures/encrypted.password.yaml "$DIR/nested/two.yaml"
+
+  run $CMD get --password-fd 3 "$DIR" secret 3<<<"password"
+  [[ $status == 0 ]]
+  [[ $output == *"\"$DIR/nested/two.yaml\":\"asdf\""* ]]
+  [[ $output == *"\"$DIR/one.yml\":\"asdf\""* ]]
+}
+
+@test "bulk: rekey tunes the kdf once for every file" {
+  export CONFIG_PASSWORD="$GOOD_PASSWORD"
+  bc rekey --provider password --kdf scrypt --kdf-target 20ms "$DIR"
+  [[ "$(grep -A4 'kdf:' "$DIR/one.yml")" == "$(grep -A4 'kdf:' "$DIR/nested/two.yaml")" ]]
+}
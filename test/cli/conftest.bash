@@ -10,13 +10,42 @@ export GOOD_PASSWORD="correct horse battery staple"
 
 mkdir -p "$WORKDIR"
 
-function setup() {
+function mock_aws() {
   # use mock creds
   export AWS_ACCESS_KEY_ID="AGOODACCESSKEYID"
   export AWS_SECRET_ACCESS_KEY="AVERYSECRETACCESSKEYTHATSNOTBASE64ENC="
   export AWS_SHARED_CREDENTIALS_FILE="/dev/null"
+  export AWS_EC2_METADATA_DISABLED="true"
   unset AWS_SESSION_TOKEN
   unset AWS_PROFILE
+  unset AWS_KMS_ENDPOINT
+}
+
+function setup() {
+  mock_aws
+}
+
+# Runs the stand-in for AWS KMS in internal/fakekms, and points AWS_KMS_ENDPOINT to it
+function start_fake_kms() {
+  local output; output=$(mktemp)
+  $CMD fake-kms > "$output" 2>/dev/null &
+  export FAKE_KMS_PID=$!
+
+  for _ in $(seq 50); do
+    if [[ -s "$output" ]]; then break; fi
+    sleep 0.1
+  done
+
+  AWS_KMS_ENDPOINT=$(head -n 1 "$output")
+  export AWS_KMS_ENDPOINT
+  rm -f "$output"
+}
+
+function stop_fake_kms() {
+  # `go run` leaves its child behind otherwise
+  pkill -P "$FAKE_KMS_PID" 2>/dev/null
+  kill "$FAKE_KMS_PID" 2>/dev/null
+  wait "$FAKE_KMS_PID" 2>/dev/null || true
 }
 
 function fixture() {
@@ -18,3 +18,30 @@ load "conftest"
   bc set $file newSecret <<<"a new secret"
   [[ "$(bc get $file newSecret)" == *'a new secret'* ]]
 }
+
+@test "get: reads passwords from files and commands" {
+  file=$(fixture encrypted.password)
+  printf 'password\nnot the password\n' > "$WORKDIR/password"
+
+  run $CMD get --password-file "$WORKDIR/password" $file secret
+  [[ $status == 0 ]];
+  [[ $output == *"asdf"* ]]
+
+  run $CMD get --password-command "echo password" $file secret
+  [[ $status == 0 ]];
+  [[ $output == *"asdf"* ]]
+
+  run $CMD get --password-fd 3 $file secret 3<<<"password"
+  [[ $status == 0 ]];
+  [[ $output == *"asdf"* ]]
+}
+
+@test "get: ignores password sources set in the file" {
+  file=$(fixture encrypted.password)
+  sed -i.bak "s|^crypto:|crypto:\n  password-command: touch $WORKDIR/ran; echo password|" $file
+
+  run $CMD get --password-command "echo password" $file secret
+  [[ $status == 0 ]];
+  [[ $output == *"asdf"* ]]
+  [ ! -e "$WORKDIR/ran" ]
+}
@@ -0,0 +1,58 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  export REPO="$WORKDIR/repo"
+  mkdir -p "$REPO/config"
+  git -C "$REPO" init -q
+  git -C "$REPO" config user.email "gcy@example.com"
+  git -C "$REPO" config user.name "gcy"
+  cat > "$REPO/.gcy.yaml" <<YAML
+creation_rules:
+  - path: config/*.yml
+    provider: kms
+    key: $GOOD_KEY
+YAML
+}
+
+@test "git: textconv shows decrypted secrets" {
+  file=$(fixture encrypted.kms)
+
+  run $CMD git-textconv $file
+  [[ $status == 0 ]]
+  [[ $output == *"secret: asdf # encrypted"* ]]
+  [[ $output != *"ciphertext"* ]]
+
+  run $CMD git-textconv --hashes $file
+  [[ $status == 0 ]]
+  [[ $output == *"# encrypted, hash of the value"* ]]
+  [[ $output != *"asdf"* ]]
+}
+
+@test "git: setup adds attributes from creation rules" {
+  # $CMD runs from the project root, point git to the test repository instead
+  export GIT_DIR="$REPO/.git" GIT_WORK_TREE="$REPO"
+  bc git-setup
+  bc git-setup
+  [[ $(cat "$REPO/.gitattributes") == "config/*.yml diff=gcy merge=gcy" ]]
+  [[ $(git config --get merge.gcy.driver) == "gcy git-merge %O %A %B" ]]
+}
+
+@test "git: merge combines decrypted values" {
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/base.yml"
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/ours.yml"
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/theirs.yml"
+  bc set "$WORKDIR/ours.yml" ours <<<"ours"
+  bc set "$WORKDIR/theirs.yml" theirs <<<"theirs"
+
+  bc git-merge "$WORKDIR/base.yml" "$WORKDIR/ours.yml" "$WORKDIR/theirs.yml"
+  [[ "$(bc get "$WORKDIR/ours.yml" theirs)" == *"theirs"* ]]
+  [[ "$(bc get "$WORKDIR/ours.yml" ours)" == *"ours"* ]]
+
+  bc set "$WORKDIR/theirs.yml" secret <<<"theirs"
+  bc set "$WORKDIR/ours.yml" secret <<<"ours"
+  run $CMD git-merge "$WORKDIR/base.yml" "$WORKDIR/ours.yml" "$WORKDIR/theirs.yml"
+  [[ $status == 2 ]]
+  [[ $output == *"Conflicting changes at secret"* ]]
+}
@@ -0,0 +1,30 @@
+#!/usr/bin/env bats
+load "conftest"
+
+@test "info describes crypto settings and secrets" {
+  file=$(fixture encrypted.kms)
+  output="$(bc info $file)"
+
+  [[ $output == *"provider: kms"* ]]
+  [[ $output == *"key: $GOOD_KEY"* ]]
+  [[ $output == *"secrets: 1"* ]]
+  [[ $output == *"  - secret"* ]]
+  [[ $output == *"region: us-east-1"* ]]
+}
+
+@test "info describes files without crypto" {
+  file=$(fixture plaintext)
+  output="$(bc info $file)"
+
+  [[ $output == *"provider: none"* ]]
+  [[ $output == *"secrets: 0"* ]]
+}
+
+@test "info fails when secrets can't be decrypted" {
+  file=$(fixture encrypted.kms)
+  export AWS_ACCESS_KEY_ID="ABADACCESSKEYID"
+
+  run $CMD info $file
+  [[ $status == 2 ]]
+  [[ $output == *"Failed to decrypt secret"* ]]
+}
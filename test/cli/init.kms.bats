@@ -1,6 +1,17 @@
 #!/usr/bin/env bats
 load "conftest"
 
+# exercise the real AWS SDK against a stand-in for KMS
+function setup() {
+  mock_aws
+  start_fake_kms
+}
+
+function teardown() {
+  stop_fake_kms
+  rm -rf ${WORKDIR:?}/*
+}
+
 @test "kms: init fails when no aws credentials are set" {
   export AWS_ACCESS_KEY_ID=""
   export AWS_PROFILE=""
@@ -37,3 +48,43 @@ load "conftest"
   bc init $file $GOOD_KEY
   grep us-east-1 $file
 }
+
+@test "kms: set fails with a key aws denies access to" {
+  file=$(fixture encrypted.kms)
+  rm "$file"
+
+  bc init $file $BAD_KEY
+  [[ "$(bc set $file secret <<<"hunter2")" == *"AWS denied access"* ]]
+}
+
+@test "kms: values round-trip through the kms endpoint" {
+  file=$(fixture encrypted.kms)
+  rm "$file"
+
+  bc init $file $GOOD_KEY
+  bc set $file secret <<<"hunter2"
+  [[ "$(bc get $file secret)" == "hunter2" ]]
+}
+
+@test "kms: init does not store the endpoint from the environment" {
+  file=$(fixture encrypted.kms)
+  rm "$file"
+
+  bc init $file $GOOD_KEY
+  run grep endpoint $file
+  [[ $status -ne 0 ]]
+}
+
+@test "kms: decrypts with replicas when the key's region is unavailable" {
+  file=$(fixture encrypted.kms)
+  rm "$file"
+  primary="arn:aws:kms:us-east-1:000000000000:key/mrk-00000000000000000000000000000000"
+
+  bc init --key $primary --kms-replica ${primary/us-east-1/us-west-2} $file
+  bc set $file secret <<<"replicated"
+
+  # the file now lists a key in a region that never answers
+  sed -i.bak "s|key: $primary|key: ${primary/us-east-1/eu-west-3}|" $file
+  [[ "$(bc get $file secret)" == *"replicated"* ]]
+  [[ "$(bc info $file)" == *"region: us-west-2"* ]]
+}
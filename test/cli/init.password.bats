@@ -40,3 +40,16 @@ load "conftest"
   bc init --skip-password-validation --password "password" --provider password $file
   rm "$file"
 }
+
+@test "password: init stores tuned kdf parameters" {
+  file=$(fixture encrypted.password)
+  rm "$file"
+
+  bc init --password "$GOOD_PASSWORD" --provider password --kdf scrypt --kdf-target 20ms $file
+  run grep "name: scrypt" $file
+  [[ $status == 0 ]];
+
+  echo "secret" | CONFIG_PASSWORD="$GOOD_PASSWORD" bc set $file secret
+  run env CONFIG_PASSWORD="$GOOD_PASSWORD" $CMD get $file secret
+  [[ $output == *"secret"* ]]
+}
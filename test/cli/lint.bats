@@ -0,0 +1,41 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  mkdir -p "$WORKDIR/config"
+  printf 'port: 80\ndb:\n  password: null\n' > "$WORKDIR/config/defaults.yml"
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/config/production.yml"
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/config/staging.yml"
+}
+
+@test "lint: passes consistent environments" {
+  for env in production staging; do
+    file="$WORKDIR/config/$env.yml"
+    rm "$file"
+    bc init --key $GOOD_KEY --provider kms $file
+    bc set $file db.password <<<'hunter2'
+  done
+  bc set --plain-text "$WORKDIR/config/production.yml" port <<<'443'
+
+  bc lint "$WORKDIR/config"
+}
+
+@test "lint: reports missing, extra, mistyped and plaintext keys" {
+  config="$WORKDIR/config"
+  bc set $config/production.yml db.password <<<'hunter2'
+  bc set --plain-text $config/production.yml port <<<'"https"'
+  bc set --plain-text $config/staging.yml db.password <<<'hunter2'
+
+  run $CMD lint $config
+  [[ $status == 1 ]]
+  [[ $output == *"$config/production.yml: port is a string, but an integer in defaults.yml"* ]]
+  [[ $output == *"$config/staging.yml: db.password is plaintext, but encrypted in $config/production.yml"* ]]
+  [[ $output == *"$config/staging.yml: secret is not in defaults.yml"* ]]
+}
+
+@test "lint: requires a defaults file" {
+  rm "$WORKDIR/config/defaults.yml"
+  run $CMD lint "$WORKDIR/config"
+  [[ $status == 99 ]]
+}
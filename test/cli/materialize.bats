@@ -0,0 +1,46 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/config.yml"
+}
+
+@test "materialize: writes every value as a file" {
+  bc materialize "$WORKDIR/config.yml" "$WORKDIR/out"
+  [[ "$(cat "$WORKDIR/out/secret")" == "asdf" ]]
+  [[ "$(cat "$WORKDIR/out/object/key")" == "value" ]]
+  [[ "$(cat "$WORKDIR/out/list")" == '["a","b","c"]' ]]
+  [ ! -e "$WORKDIR/out/crypto" ]
+  [[ "$(stat -L -c %a "$WORKDIR/out/secret" 2>/dev/null || stat -L -f %Lp "$WORKDIR/out/secret")" == 400 ]]
+}
+
+@test "materialize: writes values below a keypath" {
+  bc materialize --keypath object "$WORKDIR/config.yml" "$WORKDIR/out"
+  [[ "$(ls "$WORKDIR/out")" == "key" ]]
+  [[ "$(cat "$WORKDIR/out/key")" == "value" ]]
+}
+
+@test "materialize: refuses keypaths to values that can't be file names" {
+  printf 'nested:\n  "a/b": value\n' >> "$WORKDIR/config.yml"
+  run $CMD materialize --keypath "nested.a/b" "$WORKDIR/config.yml" "$WORKDIR/out"
+  [[ $status == 99 ]]
+  [[ $output == *"Cannot write key <a/b> as a file"* ]]
+  [ ! -e "$WORKDIR/out/a" ]
+}
+
+@test "materialize: removes files for deleted keys" {
+  bc materialize "$WORKDIR/config.yml" "$WORKDIR/out"
+  sed -i.bak '/^boolean:/d' "$WORKDIR/config.yml"
+  bc materialize "$WORKDIR/config.yml" "$WORKDIR/out"
+  [ ! -e "$WORKDIR/out/boolean" ]
+  [[ "$(cat "$WORKDIR/out/secret")" == "asdf" ]]
+}
+
+@test "materialize: refuses to write to directories with other files" {
+  mkdir "$WORKDIR/out"
+  touch "$WORKDIR/out/important"
+  run $CMD materialize "$WORKDIR/config.yml" "$WORKDIR/out"
+  [[ $status == 99 ]]
+  [[ $output == *"it's not empty"* ]]
+}
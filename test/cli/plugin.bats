@@ -0,0 +1,24 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  mkdir -p "$WORKDIR/plugins"
+  cat > "$WORKDIR/plugins/gcy-provider-marker" <<PLUGIN
+#!/bin/sh
+touch "$WORKDIR/started"
+while read line; do echo '{"flags":[{"name":"marker-key","description":"A marker key"}]}'; done
+PLUGIN
+  chmod +x "$WORKDIR/plugins/gcy-provider-marker"
+  export PATH="$WORKDIR/plugins:$PATH"
+}
+
+@test "plugin: only started by commands that offer their flags" {
+  bc get test/fixtures/encrypted.kms.yaml secret
+  [ ! -e "$WORKDIR/started" ]
+
+  run $CMD init --help
+  [[ $status == 0 ]]
+  [[ $output == *"--marker-key"* ]]
+  [ -e "$WORKDIR/started" ]
+}
@@ -12,3 +12,13 @@ load "conftest"
   # hash didnt
   grep "hash:\s*$HASH" $file >/dev/null
 }
+
+@test "rekey: shares passwords read from a file descriptor" {
+  file=$(fixture encrypted.password)
+  old_ciphertext=$(grep ciphertext $file)
+
+  bc rekey --skip-password-validation --password-fd 3 $file 3<<<"password"
+
+  [[ $(grep ciphertext $file) != $old_ciphertext ]]
+  [[ "$(bc get --password-fd 3 $file secret 3<<<"password")" == "asdf" ]]
+}
@@ -0,0 +1,48 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/config.yml"
+  printf '{{ .secret }} {{ secret "secret" | b64enc }} {{ toJSON .list }} {{ .object.key }}\n' > "$WORKDIR/template"
+}
+
+@test "render: prints templates with decrypted values" {
+  run bc render "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ $status == 0 ]]
+  [[ $output == 'asdf YXNkZg== ["a","b","c"] value' ]]
+}
+
+@test "render: writes output only its owner can read" {
+  bc render -o "$WORKDIR/out" "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ "$(cat "$WORKDIR/out")" == 'asdf YXNkZg== ["a","b","c"] value' ]]
+  [[ "$(stat -c %a "$WORKDIR/out" 2>/dev/null || stat -f %Lp "$WORKDIR/out")" == 600 ]]
+
+  bc render --mode 0640 -o "$WORKDIR/out" "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ "$(stat -c %a "$WORKDIR/out" 2>/dev/null || stat -f %Lp "$WORKDIR/out")" == 640 ]]
+}
+
+@test "render: only decrypts referenced secrets" {
+  printf 'broken:\n  encrypted: true\n  ciphertext: bm90IGNpcGhlcnRleHQ=\n' >> "$WORKDIR/config.yml"
+  echo '{{ .object.key }}' > "$WORKDIR/template"
+  run bc render "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ $status == 0 ]]
+  [[ $output == "value" ]]
+
+  echo '{{ .broken }}' > "$WORKDIR/template"
+  run $CMD render -o "$WORKDIR/out" "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ $status == 99 ]]
+  [[ $output == *"Could not decrypt broken"* ]]
+  [ ! -f "$WORKDIR/out" ]
+}
+
+@test "render: fails on missing keys and required values" {
+  echo '{{ .missing }}' > "$WORKDIR/template"
+  run $CMD render "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ $status == 99 ]]
+
+  echo '{{ required "empty-list must be set" (index . "empty-list") }}' > "$WORKDIR/template"
+  run $CMD render "$WORKDIR/config.yml" "$WORKDIR/template"
+  [[ $status == 99 ]]
+  [[ $output == *"empty-list must be set"* ]]
+}
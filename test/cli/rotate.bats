@@ -0,0 +1,38 @@
+#!/usr/bin/env bats
+load "conftest"
+
+@test "rotate: set generates values" {
+  file=$(fixture encrypted.kms)
+  bc set --generate $file api.token
+
+  bc get $file api.token
+  [[ $output =~ ^[0-9A-Za-z]{32}$ ]]
+
+  bc set --generate --charset hex --length 8 --show $file hexKey
+  [[ $output =~ [0-9a-f]{8} ]]
+
+  run $CMD set --generate --plain-text $file plain
+  [[ $status == 99 ]]
+}
+
+@test "rotate: replaces secrets, keeping their owner" {
+  file=$(fixture encrypted.kms)
+  bc set --generate --owner team-api $file api.token
+  bc get $file api.token
+  before="$output"
+
+  bc rotate --charset words --length 4 --show $file api.token
+  [[ $output =~ api.token:\ [a-z]+(-[a-z]+){3} ]]
+
+  bc get $file api.token
+  [[ $output != "$before" ]]
+  [[ $output =~ ^[a-z]+(-[a-z]+){3}$ ]]
+  [[ $(grep -c "owner: team-api" $file) == 1 ]]
+}
+
+@test "rotate: refuses plaintext values" {
+  file=$(fixture encrypted.kms)
+  run $CMD rotate $file string
+  [[ $status == 99 ]]
+  [[ $output == *"it's not a secret"* ]]
+}
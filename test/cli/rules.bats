@@ -0,0 +1,66 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  export PROJECT="$WORKDIR/project"
+  mkdir -p "$PROJECT/config/dev"
+  cat > "$PROJECT/.gcy.yaml" <<YAML
+creation_rules:
+  - path: config/production.yml
+    provider: kms
+    key: $GOOD_KEY
+    kms-context:
+      - env=production
+  - path: config/**/*.yml
+    provider: kms
+    key: ${GOOD_KEY/us-east-1/us-west-2}
+  - path: passwords/*
+    provider: password
+YAML
+}
+
+@test "rules: init uses the matching creation rule" {
+  bc init "$PROJECT/config/production.yml"
+  grep "key: $GOOD_KEY" "$PROJECT/config/production.yml"
+  grep "env: production" "$PROJECT/config/production.yml"
+
+  bc init "$PROJECT/config/dev/app.yml"
+  grep "us-west-2" "$PROJECT/config/dev/app.yml"
+}
+
+@test "rules: flags take precedence over creation rules" {
+  bc init --key ${GOOD_KEY/us-east-1/eu-west-1} "$PROJECT/config/production.yml"
+  grep "eu-west-1" "$PROJECT/config/production.yml"
+  grep "env: production" "$PROJECT/config/production.yml"
+}
+
+@test "rules: rekey re-applies creation rules" {
+  bc init --key ${GOOD_KEY/us-east-1/eu-west-1} "$PROJECT/config/dev/app.yml"
+  bc set "$PROJECT/config/dev/app.yml" secret <<<"ruled"
+
+  bc rekey "$PROJECT/config/dev/app.yml"
+  grep "us-west-2" "$PROJECT/config/dev/app.yml"
+
+  export CONFIG_PASSWORD="$GOOD_PASSWORD"
+  bc rekey --provider password "$PROJECT/config/dev/app.yml"
+  bc rekey --apply-rules "$PROJECT/config/dev/app.yml"
+  grep "provider: kms" "$PROJECT/config/dev/app.yml"
+  [[ "$(bc get "$PROJECT/config/dev/app.yml" secret)" == *"ruled"* ]]
+}
+
+@test "rules: rekey --apply-rules fails without a matching rule" {
+  cp test/fixtures/encrypted.kms.yaml "$PROJECT/unruled.yml"
+
+  run $CMD rekey --apply-rules "$PROJECT/unruled.yml"
+  [[ $status == 99 ]]
+  [[ $output == *"No creation rule matches"* ]]
+}
+
+@test "rules: init rejects invalid creation rules" {
+  echo "creation_rules: [{path: '**', provider: kms, colour: red}]" > "$PROJECT/.gcy.yaml"
+
+  run $CMD init "$PROJECT/config/app.yml"
+  [[ $status == 99 ]]
+  [[ $output == *"Unknown argument <colour>"* ]]
+}
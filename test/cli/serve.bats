@@ -0,0 +1,30 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  cp test/fixtures/encrypted.kms.yaml "$WORKDIR/config.yml"
+}
+
+@test "serve: requires a socket or an address" {
+  run $CMD serve "$WORKDIR/config.yml"
+  [[ $status == 99 ]]
+  [[ $output == *"Pass either --socket or --listen"* ]]
+
+  run $CMD serve --socket "$WORKDIR/gcy.sock" --listen 127.0.0.1:0 "$WORKDIR/config.yml"
+  [[ $status == 99 ]]
+}
+
+@test "serve: requires a token to listen on TCP" {
+  unset GCY_SERVE_TOKEN
+  run $CMD serve --listen 127.0.0.1:0 "$WORKDIR/config.yml"
+  [[ $status == 99 ]]
+  [[ $output == *"A bearer token is required with --listen"* ]]
+}
+
+@test "serve: fails on config files that don't load" {
+  echo 'crypto: [' > "$WORKDIR/config.yml"
+  run $CMD serve --socket "$WORKDIR/gcy.sock" "$WORKDIR/config.yml"
+  [[ $status == 99 ]]
+  [ ! -e "$WORKDIR/gcy.sock" ]
+}
@@ -89,3 +89,25 @@ function testDefaultFile() {
   testDefaultFile defaults.yml
 }
 
+
+@test "set keeps permissions and optional backups" {
+  file=$(fixture encrypted.kms)
+  chmod 600 $file
+  original=$(cat $file)
+
+  bc --backup set $file newSecret <<<"backed up"
+  [[ $(stat -c %a $file 2>/dev/null || stat -f %Lp $file) == 600 ]]
+  [[ "$(cat $file.bak)" == "$original" ]]
+  grep newSecret $file
+}
+
+@test "set does not lose concurrent updates" {
+  file=$(fixture encrypted.kms)
+
+  for i in $(seq 10); do
+    $CMD set $file "concurrent$i" <<<"value $i" 2>/dev/null &
+  done
+  wait
+
+  [[ $(grep -c "^concurrent" $file) == 10 ]]
+}
@@ -0,0 +1,51 @@
+#!/usr/bin/env bats
+load "conftest"
+
+function setup() {
+  mock_aws
+  cat > "$WORKDIR/schema.yaml" <<YAML
+type: object
+required: [number, port]
+properties:
+  number: {type: integer}
+  port: {type: integer, minimum: 1}
+  secret: {type: string, minLength: 4}
+YAML
+}
+
+@test "validate: reports values that break the schema" {
+  file=$(fixture encrypted.kms)
+
+  run $CMD validate --schema "$WORKDIR/schema.yaml" $file
+  [[ $status == 1 ]]
+  [[ $output == *"$file: port is missing, but required"* ]]
+
+  bc set --plain-text $file port <<<'8080'
+  bc validate --schema "$WORKDIR/schema.yaml" $file
+}
+
+@test "validate: reads the schema from the file" {
+  file=$(fixture encrypted.kms)
+  bc set --plain-text $file '$schema' <<<"$WORKDIR/schema.yaml"
+
+  run $CMD validate $file
+  [[ $status == 1 ]]
+  [[ $output == *"port is missing, but required"* ]]
+}
+
+@test "validate: set refuses values that break the schema" {
+  file=$(fixture encrypted.kms)
+  bc set --plain-text $file '$schema' <<<"$WORKDIR/schema.yaml"
+
+  run $CMD set --plain-text $file port <<<'abc'
+  [[ $status == 99 ]]
+  [[ $output == *"port must be an integer, not a string"* ]]
+
+  # secrets are checked with the type the schema expects
+  bc set $file port <<<'443'
+  run $CMD set $file port <<<'-1'
+  [[ $status == 99 ]]
+  [[ $output == *"port must be at least 1"* ]]
+
+  bc validate $file
+}